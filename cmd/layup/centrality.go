@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	layupv1 "github.com/picatz/layup/pkg/layup/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// metrics are the available centrality metrics for the centrality command.
+var metrics = map[string]func(g *layupv1.Graph, opts ...layupv1.TraversalOption) (layupv1.Scores, error){
+	"in_degree": func(g *layupv1.Graph, opts ...layupv1.TraversalOption) (layupv1.Scores, error) {
+		return g.DegreeCentrality(append(opts, layupv1.WithDirection(layupv1.Incoming))...)
+	},
+	"out_degree": func(g *layupv1.Graph, opts ...layupv1.TraversalOption) (layupv1.Scores, error) {
+		return g.DegreeCentrality(append(opts, layupv1.WithDirection(layupv1.Outgoing))...)
+	},
+	"degree": func(g *layupv1.Graph, opts ...layupv1.TraversalOption) (layupv1.Scores, error) {
+		return g.DegreeCentrality(append(opts, layupv1.WithDirection(layupv1.Both))...)
+	},
+	"pagerank":    (*layupv1.Graph).PageRank,
+	"betweenness": (*layupv1.Graph).BetweennessCentrality,
+	"closeness":   (*layupv1.Graph).ClosenessCentrality,
+}
+
+func metricNames() string {
+	names := make([]string, 0, len(metrics))
+	for name := range metrics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+func runCentrality(args []string) error {
+	flagSet := newFlagSet("centrality", "<path/to/layup.hcl>")
+
+	var (
+		metricList string
+		weight     string
+		limit      int
+		asJSON     bool
+		attributes bool
+		traversal  traversalFlags
+	)
+
+	flagSet.StringVar(&metricList, "metrics", "in_degree,out_degree,pagerank,betweenness,closeness", "Comma separated metrics to compute ("+metricNames()+"), sorted by the first")
+	flagSet.StringVar(&weight, "weight", "", "Link attribute to use as the weight of each link (e.g. latency)")
+	flagSet.IntVar(&limit, "limit", 0, "Maximum number of nodes to list (defaults to all)")
+	flagSet.BoolVar(&asJSON, "json", false, "Write the scores as JSON")
+	flagSet.BoolVar(&attributes, "attributes", false, "Write the model as JSON, with the scores as node attributes")
+	traversal.register(flagSet, "outgoing")
+	flagSet.Parse(args)
+
+	if flagSet.NArg() != 1 {
+		flagSet.Usage()
+		os.Exit(1)
+	}
+
+	opts, err := traversal.options()
+	if err != nil {
+		return err
+	}
+
+	if weight != "" {
+		opts = append(opts, layupv1.WithWeight(weight))
+	}
+
+	g, err := loadGraph(flagSet.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	var scores []layupv1.Scores
+
+	for _, name := range strings.Split(metricList, ",") {
+		metric, ok := metrics[name]
+		if !ok {
+			return fmt.Errorf("unknown metric %q, must be one of: %s", name, metricNames())
+		}
+
+		s, err := metric(g, opts...)
+		if err != nil {
+			return fmt.Errorf("failed to compute %s: %w", name, err)
+		}
+
+		scores = append(scores, s)
+	}
+
+	if attributes {
+		for _, s := range scores {
+			g.SetNodeScores(s)
+		}
+
+		b, err := protojson.Marshal(g.Model())
+		if err != nil {
+			return err
+		}
+
+		fmt.Println(string(b))
+
+		return nil
+	}
+
+	// Only keep the top nodes by the first metric.
+	if limit > 0 {
+		top := scores[0].Sorted()
+		if len(top) > limit {
+			for _, s := range top[limit:] {
+				for _, other := range scores {
+					delete(other.Values, s.Node)
+				}
+			}
+		}
+	}
+
+	if asJSON {
+		rows := []map[string]any{}
+		for _, row := range scores[0].Sorted() {
+			r := map[string]any{"node": row.Node}
+			for _, s := range scores {
+				r[s.Name] = s.Values[row.Node]
+			}
+			rows = append(rows, r)
+		}
+
+		return writeJSON(os.Stdout, rows)
+	}
+
+	return layupv1.WriteScoreTable(os.Stdout, scores...)
+}
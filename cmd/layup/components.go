@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	layupv1 "github.com/picatz/layup/pkg/layup/v1"
+)
+
+func runComponents(args []string) error {
+	flagSet := newFlagSet("components", "<path/to/layup.hcl>")
+
+	var (
+		format    string
+		output    string
+		strong    bool
+		traversal traversalFlags
+	)
+
+	flagSet.StringVar(&format, "format", "text", "Output format (text, json, or a render format to color nodes by component: d2, dot, mermaid, svg)")
+	flagSet.StringVar(&output, "output", "", "Output file (defaults to standard output)")
+	flagSet.BoolVar(&strong, "strong", false, "Color nodes by strongly connected component, instead of weakly")
+	traversal.register(flagSet, "outgoing")
+	flagSet.Parse(args)
+
+	if flagSet.NArg() != 1 {
+		flagSet.Usage()
+		os.Exit(1)
+	}
+
+	opts, err := traversal.options()
+	if err != nil {
+		return err
+	}
+
+	m, err := loadModel(flagSet.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	g, err := layupv1.NewGraph(m)
+	if err != nil {
+		return err
+	}
+
+	components := g.Components(opts...)
+
+	w, err := createOutput(output)
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+
+	switch format {
+	case "json":
+		return writeJSON(w, components)
+	case "text":
+		writeGroups := func(title string, groups [][]string) {
+			fmt.Fprintf(w, "%s (%d):\n", title, len(groups))
+			for i, group := range groups {
+				fmt.Fprintf(w, "  %d:\n", i+1)
+				for _, uri := range group {
+					fmt.Fprintf(w, "    %s\n", uri)
+				}
+			}
+		}
+
+		writeGroups("Weakly connected components", components.Weak)
+		writeGroups("Strongly connected components", components.Strong)
+
+		fmt.Fprintf(w, "Isolated nodes (%d):\n", len(components.Isolated))
+		for _, uri := range components.Isolated {
+			fmt.Fprintf(w, "  %s\n", uri)
+		}
+
+		fmt.Fprintf(w, "Unreachable layers (%d):\n", len(components.UnreachableLayers))
+		for _, id := range components.UnreachableLayers {
+			fmt.Fprintf(w, "  %s\n", id)
+		}
+
+		return nil
+	}
+
+	render, ok := renderers[format]
+	if !ok {
+		return fmt.Errorf("unknown format %q, must be text, json, or one of: %s", format, renderFormats())
+	}
+
+	groups := components.Weak
+	if strong {
+		groups = components.Strong
+	}
+
+	return render(w, m, layupv1.WithNodeColors(layupv1.GroupColors(groups)))
+}
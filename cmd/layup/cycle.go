@@ -0,0 +1,115 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	layupv1 "github.com/picatz/layup/pkg/layup/v1"
+)
+
+func runTopoSort(args []string) error {
+	flagSet := newFlagSet("toposort", "<path/to/layup.hcl>")
+
+	var (
+		asJSON    bool
+		traversal traversalFlags
+	)
+
+	flagSet.BoolVar(&asJSON, "json", false, "Write the sorted nodes as JSON")
+	traversal.register(flagSet, "outgoing")
+	flagSet.Parse(args)
+
+	if flagSet.NArg() != 1 {
+		flagSet.Usage()
+		os.Exit(1)
+	}
+
+	opts, err := traversal.options()
+	if err != nil {
+		return err
+	}
+
+	g, err := loadGraph(flagSet.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	sorted, err := g.TopologicalSort(opts...)
+
+	var cycleErr *layupv1.CycleError
+	if errors.As(err, &cycleErr) {
+		for _, cycle := range cycleErr.Cycles {
+			fmt.Fprintf(os.Stderr, "cycle: %s\n", cycle)
+		}
+		return errors.New("can't sort a graph containing cycles")
+	}
+	if err != nil {
+		return err
+	}
+
+	if asJSON {
+		return writeJSON(os.Stdout, sorted)
+	}
+
+	for _, uri := range sorted {
+		fmt.Println(uri)
+	}
+
+	return nil
+}
+
+func runCycles(args []string) error {
+	flagSet := newFlagSet("cycles", "<path/to/layup.hcl>")
+
+	var (
+		maxDepth  int
+		maxPaths  int
+		asJSON    bool
+		traversal traversalFlags
+	)
+
+	flagSet.IntVar(&maxDepth, "max-depth", -1, "Maximum number of links in a cycle")
+	flagSet.IntVar(&maxPaths, "max-cycles", -1, "Maximum number of cycles to find")
+	flagSet.BoolVar(&asJSON, "json", false, "Write the cycles as JSON")
+	traversal.register(flagSet, "outgoing")
+	flagSet.Parse(args)
+
+	if flagSet.NArg() != 1 {
+		flagSet.Usage()
+		os.Exit(1)
+	}
+
+	opts, err := traversal.options()
+	if err != nil {
+		return err
+	}
+
+	g, err := loadGraph(flagSet.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	cycles := g.Cycles(append(opts, layupv1.WithMaxDepth(maxDepth), layupv1.WithMaxPaths(maxPaths))...)
+
+	if asJSON {
+		if cycles == nil {
+			cycles = []layupv1.Cycle{}
+		}
+		if err := writeJSON(os.Stdout, cycles); err != nil {
+			return err
+		}
+	} else {
+		for _, cycle := range cycles {
+			fmt.Println(cycle)
+		}
+	}
+
+	// Exit with a non-zero status if any cycles were found,
+	// so the command can be used to check models in CI.
+	if len(cycles) > 0 {
+		os.Exit(1)
+	}
+
+	return nil
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	layupv1 "github.com/picatz/layup/pkg/layup/v1"
+)
+
+// runDiff exits with status 1 if the models are different, and 2 if there
+// is an error, like diff(1), so the command can be used to check models in
+// CI without mistaking a failure for changes.
+func runDiff(args []string) error {
+	different, err := diff(args)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(2)
+	}
+
+	if different {
+		os.Exit(1)
+	}
+
+	return nil
+}
+
+// diff writes the changes between the two models given in args, and
+// reports whether there are any.
+func diff(args []string) (bool, error) {
+	flagSet := newFlagSet("diff", "<path/to/old.hcl> <path/to/new.hcl>")
+
+	var (
+		format string
+		output string
+	)
+
+	flagSet.StringVar(&format, "format", "text", "Output format (text, json, unified, patch, or a render format to highlight the changes: "+renderFormats()+")")
+	flagSet.StringVar(&output, "output", "", "Output file (defaults to standard output)")
+	flagSet.Parse(args)
+
+	if flagSet.NArg() != 2 {
+		flagSet.Usage()
+		os.Exit(2)
+	}
+
+	a, err := loadModel(flagSet.Arg(0))
+	if err != nil {
+		return false, err
+	}
+
+	b, err := loadModel(flagSet.Arg(1))
+	if err != nil {
+		return false, err
+	}
+
+	d, err := layupv1.Diff(a, b)
+	if err != nil {
+		return false, err
+	}
+
+	w, err := createOutput(output)
+	if err != nil {
+		return false, err
+	}
+	defer w.Close()
+
+	switch format {
+	case "text":
+		err = layupv1.WriteDiff(w, d)
+	case "json":
+		err = writeJSON(w, d)
+	case "unified":
+		err = layupv1.WriteUnifiedDiff(w, d)
+	case "patch":
+		err = writeJSON(w, d.Patch())
+	default:
+		render, ok := renderers[format]
+		if !ok {
+			return false, fmt.Errorf("unknown format %q, must be text, json, unified, patch, or one of: %s", format, renderFormats())
+		}
+
+		var union *layupv1.Model
+		union, err = layupv1.DiffUnion(a, b)
+		if err != nil {
+			return false, err
+		}
+
+		err = render(w, union, d.RenderOptions()...)
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return !d.Empty(), nil
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	layupv1 "github.com/picatz/layup/pkg/layup/v1"
+)
+
+func runHash(args []string) error {
+	flagSet := newFlagSet("hash", "<path/to/layup.hcl>")
+
+	var (
+		asJSON    bool
+		elements  bool
+		canonical bool
+	)
+
+	flagSet.BoolVar(&asJSON, "json", false, "Write the hashes as JSON")
+	flagSet.BoolVar(&elements, "elements", false, "Also write the hash of each layer, node and link")
+	flagSet.BoolVar(&canonical, "canonical", false, "Write the canonical serialization of the model which is hashed, instead of its hash")
+	flagSet.Parse(args)
+
+	if flagSet.NArg() != 1 {
+		flagSet.Usage()
+		os.Exit(1)
+	}
+
+	m, err := loadModel(flagSet.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	if canonical {
+		b, err := layupv1.CanonicalJSON(m)
+		if err != nil {
+			return err
+		}
+
+		_, err = fmt.Println(string(b))
+		return err
+	}
+
+	hash, err := layupv1.Hash(m)
+	if err != nil {
+		return err
+	}
+
+	hashes := []layupv1.ElementHash{{Element: layupv1.ElementModel, URI: m.GetUri(), Hash: hash}}
+
+	if elements {
+		elementHashes, err := layupv1.ElementHashes(m)
+		if err != nil {
+			return err
+		}
+		hashes = append(hashes, elementHashes...)
+	}
+
+	if asJSON {
+		return writeJSON(os.Stdout, hashes)
+	}
+
+	// Hashes are written like sha256sum(1), followed by the element.
+	for _, h := range hashes {
+		fmt.Printf("%s  %s %s\n", h.Hash, h.Element, h.URI)
+	}
+
+	return nil
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	layupv1 "github.com/picatz/layup/pkg/layup/v1"
+)
+
+func runImpact(args []string) error {
+	flagSet := newFlagSet("impact", "<path/to/layup.hcl> <node>")
+
+	var (
+		format    string
+		output    string
+		maxDepth  int
+		traversal traversalFlags
+	)
+
+	flagSet.StringVar(&format, "format", "text", "Output format (text, json, or a render format to highlight the affected nodes: d2, dot, mermaid, svg)")
+	flagSet.StringVar(&output, "output", "", "Output file (defaults to standard output)")
+	flagSet.IntVar(&maxDepth, "max-depth", -1, "Maximum number of links between the node and the affected nodes")
+	traversal.register(flagSet, "incoming")
+	flagSet.Parse(args)
+
+	if flagSet.NArg() != 2 {
+		flagSet.Usage()
+		os.Exit(1)
+	}
+
+	opts, err := traversal.options()
+	if err != nil {
+		return err
+	}
+
+	g, err := loadGraph(flagSet.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	node, err := g.FindNode(flagSet.Arg(1))
+	if err != nil {
+		return err
+	}
+
+	impact, err := g.Impact(node, append(opts, layupv1.WithMaxDepth(maxDepth))...)
+	if err != nil {
+		return err
+	}
+
+	w, err := createOutput(output)
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+
+	switch format {
+	case "json":
+		return writeJSON(w, impact)
+	case "text":
+		fmt.Fprintf(w, "Changing %s affects %d node(s) in %d layer(s)\n", impact.Node, len(impact.Affected), len(impact.Layers))
+
+		depths := map[string]layupv1.AffectedNode{}
+		for _, n := range impact.Affected {
+			depths[n.Node] = n
+		}
+
+		for _, layer := range impact.Layers {
+			fmt.Fprintf(w, "\n%s (%d):\n", layer.Layer, len(layer.Nodes))
+			for _, uri := range layer.Nodes {
+				fmt.Fprintf(w, "  %s (depth %d, via %s)\n", uri, depths[uri].Depth, depths[uri].Via)
+			}
+		}
+
+		var external []layupv1.AffectedNode
+		for _, n := range impact.Affected {
+			if n.External {
+				external = append(external, n)
+			}
+		}
+
+		if len(external) > 0 {
+			fmt.Fprintf(w, "\nexternal (%d):\n", len(external))
+			for _, n := range external {
+				fmt.Fprintf(w, "  %s (depth %d, via %s)\n", n.Node, n.Depth, n.Via)
+			}
+		}
+
+		return nil
+	}
+
+	render, ok := renderers[format]
+	if !ok {
+		return fmt.Errorf("unknown format %q, must be text, json, or one of: %s", format, renderFormats())
+	}
+
+	return render(w, g.Model(), impact.RenderOptions()...)
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	layupv1 "github.com/picatz/layup/pkg/layup/v1"
+)
+
+func runLayers(args []string) error {
+	flagSet := newFlagSet("layers", "<path/to/layup.hcl>")
+
+	var (
+		format  string
+		output  string
+		flatten bool
+		project string
+		links   string
+	)
+
+	flagSet.StringVar(&format, "format", "json", "Output format (json, or a render format: "+renderFormats()+")")
+	flagSet.StringVar(&output, "output", "", "Output file (defaults to standard output)")
+	flagSet.BoolVar(&flatten, "flatten", false, "Flatten all layers into one, instead of collapsing each layer into a node")
+	flagSet.StringVar(&project, "project", "", "Project the links of one layer onto the nodes of another (from:onto)")
+	flagSet.StringVar(&links, "links", "", "Comma separated IDs of the links to collapse or project (defaults to all)")
+	flagSet.Parse(args)
+
+	if flagSet.NArg() != 1 {
+		flagSet.Usage()
+		os.Exit(1)
+	}
+
+	if flatten && project != "" {
+		return fmt.Errorf("only one of --flatten or --project can be used")
+	}
+
+	var opts []layupv1.TraversalOption
+	if links != "" {
+		opts = append(opts, layupv1.WithLinkIDs(strings.Split(links, ",")...))
+	}
+
+	g, err := loadGraph(flagSet.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	var m *layupv1.Model
+
+	switch {
+	case flatten:
+		m, err = g.Flatten()
+	case project != "":
+		from, onto, ok := strings.Cut(project, ":")
+		if !ok {
+			return fmt.Errorf("invalid --project %q, must be from:onto", project)
+		}
+
+		m, err = g.Project(from, onto, opts...)
+	default:
+		m, err = g.CollapseLayers(opts...)
+	}
+	if err != nil {
+		return err
+	}
+
+	w, err := createOutput(output)
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+
+	return writeModel(w, m, format)
+}
@@ -4,58 +4,196 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"sort"
+	"strings"
 
-	layupv1 "github.com/picatz/layup/pkg/layup/v1"
 	"google.golang.org/protobuf/encoding/protojson"
 )
 
-var usage = `Layup enables anyone to model relationships between data in a graph using 
-"layers" containing "nodes" and "links" to represent relationships. It is 
-designed to be a simple, flexible, and extensible way to model anything. 
+var usage = `Layup enables anyone to model relationships between data in a graph using
+"layers" containing "nodes" and "links" to represent relationships. It is
+designed to be a simple, flexible, and extensible way to model anything.
 
 Because everything is a graph.
 
-Usage: layup <path/to/layup.hcl>`
+Usage: layup <path/to/layup.hcl>
+       layup <command> [options] <path/to/layup.hcl>
 
-func getArgOrExit() string {
-	if len(os.Args) != 2 {
-		fmt.Println(usage)
+Commands:
+%s
+Use "layup <command> -help" for more information about a command.`
+
+// command is a layup subcommand, which is given the arguments after its name.
+type command struct {
+	description string
+	run         func(args []string) error
+}
+
+// commands are all the available subcommands, by name.
+var commands map[string]command
+
+// Commands are registered in init, since they refer back to commands
+// when printing their own usage.
+func init() {
+	commands = map[string]command{
+		"centrality": {
+			description: "Compute centrality metrics (degree, PageRank, betweenness, closeness) for each node",
+			run:         runCentrality,
+		},
+		"components": {
+			description: "Find the connected components, isolated nodes and unreachable layers",
+			run:         runComponents,
+		},
+		"cycles": {
+			description: "List the cycles in the model, exiting non-zero if there are any",
+			run:         runCycles,
+		},
+		"diff": {
+			description: "Show the structural changes between two models, exiting non-zero if there are any",
+			run:         runDiff,
+		},
+		"hash": {
+			description: "Compute content hashes of the model and its elements, from its canonical serialization",
+			run:         runHash,
+		},
+		"impact": {
+			description: "Find the nodes affected if a node changes or fails (its blast radius)",
+			run:         runImpact,
+		},
+		"json": {
+			description: "Convert the model to JSON (the default)",
+			run:         runJSON,
+		},
+		"keygen": {
+			description: "Generate an Ed25519 key pair to sign and verify models",
+			run:         runKeygen,
+		},
+		"layers": {
+			description: "Show the graph between layers, flatten the layers, or project one onto another",
+			run:         runLayers,
+		},
+		"match": {
+			description: "Find the matches of a node-link-node pattern, like a Cypher MATCH",
+			run:         runMatch,
+		},
+		"merge": {
+			description: "Merge models describing parts of the same system into a single model",
+			run:         runMerge,
+		},
+		"patch": {
+			description: "Apply a patch (e.g. from diff --format patch) to the model",
+			run:         runPatch,
+		},
+		"path": {
+			description: "Find the shortest (or cheapest) paths between two nodes",
+			run:         runPath,
+		},
+		"sign": {
+			description: "Sign the model with an Ed25519 private key",
+			run:         runSign,
+		},
+		"subgraph": {
+			description: "Extract part of the model by nodes, neighborhood, layers or attributes",
+			run:         runSubgraph,
+		},
+		"toposort": {
+			description: "Sort the nodes of the model topologically (in dependency order)",
+			run:         runTopoSort,
+		},
+		"query": {
+			description: "Find the nodes or links matching a CEL expression",
+			run:         runQuery,
+		},
+		"render": {
+			description: "Render the model in another format (e.g. DOT, Mermaid, SVG)",
+			run:         runRender,
+		},
+		"verify": {
+			description: "Verify the signature of the model with an Ed25519 public key",
+			run:         runVerify,
+		},
+	}
+}
+
+func printUsage() {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var sb strings.Builder
+	for _, name := range names {
+		fmt.Fprintf(&sb, "  %-10s %s\n", name, commands[name].description)
+	}
+
+	fmt.Printf(usage+"\n", sb.String())
+}
+
+// newFlagSet returns a flag set for the given command, which prints the
+// command's usage (and flags) when -help is given.
+func newFlagSet(name, args string) *flag.FlagSet {
+	flagSet := flag.NewFlagSet("layup "+name, flag.ExitOnError)
+	flagSet.Usage = func() {
+		fmt.Fprintf(flagSet.Output(), "%s\n\nUsage: layup %s [options] %s\n\nOptions:\n", commands[name].description, name, args)
+		flagSet.PrintDefaults()
+	}
+	return flagSet
+}
+
+// runJSON converts the model at the given path to JSON, which is the
+// original (and default) behavior of the CLI.
+func runJSON(args []string) error {
+	flagSet := newFlagSet("json", "<path/to/layup.hcl>")
+	flagSet.Parse(args)
+
+	if flagSet.NArg() != 1 {
+		flagSet.Usage()
 		os.Exit(1)
 	}
 
-	var help bool
-	flagSet := flag.NewFlagSet("layup", flag.ExitOnError)
-	flagSet.BoolVar(&help, "help", false, "Show this help message")
-	flagSet.Parse(os.Args[1:])
+	m, err := loadModel(flagSet.Arg(0))
+	if err != nil {
+		return err
+	}
 
-	if help {
-		fmt.Println(usage)
-		os.Exit(0)
+	b, err := protojson.Marshal(m)
+	if err != nil {
+		return err
 	}
 
-	return flagSet.Arg(0)
+	fmt.Println(string(b))
+
+	return nil
 }
 
 func main() {
 	// TODO: use cobra to parse flags and args.
-	fh, err := os.Open(getArgOrExit())
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+	if len(os.Args) < 2 {
+		printUsage()
 		os.Exit(1)
 	}
-	defer fh.Close()
 
-	m, err := layupv1.ParseHCL(fh)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "error: %v\n", err)
-		os.Exit(1)
+	var err error
+
+	switch name := os.Args[1]; name {
+	case "-h", "-help", "--help", "help":
+		printUsage()
+		os.Exit(0)
+	default:
+		cmd, ok := commands[name]
+		if !ok {
+			// Not a command, so treat it as a path to a model to
+			// convert to JSON like previous versions of the CLI.
+			err = runJSON(os.Args[1:])
+			break
+		}
+
+		err = cmd.run(os.Args[2:])
 	}
 
-	b, err := protojson.Marshal(m)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "error: %v\n", err)
 		os.Exit(1)
 	}
-
-	fmt.Println(string(b))
 }
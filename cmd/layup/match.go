@@ -0,0 +1,66 @@
+package main
+
+import (
+	"os"
+	"strings"
+
+	layupv1 "github.com/picatz/layup/pkg/layup/v1"
+)
+
+func runMatch(args []string) error {
+	flagSet := newFlagSet("match", "<path/to/layup.hcl> <pattern>")
+
+	var (
+		output     string
+		asJSON     bool
+		maxMatches int
+		layers     string
+		links      string
+	)
+
+	flagSet.StringVar(&output, "output", "", "Output file (defaults to standard output)")
+	flagSet.BoolVar(&asJSON, "json", false, "Write the matches as JSON")
+	flagSet.IntVar(&maxMatches, "max-matches", -1, "Maximum number of matches to find (defaults to all)")
+	flagSet.StringVar(&layers, "layers", "", "Comma separated IDs of the layers to match nodes in (defaults to all)")
+	flagSet.StringVar(&links, "links", "", "Comma separated IDs of the links to match (defaults to all)")
+	flagSet.Parse(args)
+
+	if flagSet.NArg() != 2 {
+		flagSet.Usage()
+		os.Exit(1)
+	}
+
+	// The direction of each link is part of the pattern, so unlike other
+	// commands, there is no direction flag.
+	opts := []layupv1.TraversalOption{layupv1.WithMaxPaths(maxMatches)}
+
+	if layers != "" {
+		opts = append(opts, layupv1.WithLayers(strings.Split(layers, ",")...))
+	}
+
+	if links != "" {
+		opts = append(opts, layupv1.WithLinkIDs(strings.Split(links, ",")...))
+	}
+
+	g, err := loadGraph(flagSet.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	result, err := g.Match(flagSet.Arg(1), opts...)
+	if err != nil {
+		return err
+	}
+
+	w, err := createOutput(output)
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+
+	if asJSON {
+		return writeJSON(w, result)
+	}
+
+	return layupv1.WriteMatchTable(w, result)
+}
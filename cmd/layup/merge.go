@@ -0,0 +1,78 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	layupv1 "github.com/picatz/layup/pkg/layup/v1"
+)
+
+func runMerge(args []string) error {
+	flagSet := newFlagSet("merge", "<path/to/a.hcl> <path/to/b.hcl> [...]")
+
+	var (
+		strategy string
+		format   string
+		output   string
+	)
+
+	flagSet.StringVar(&strategy, "strategy", "error", "How to resolve conflicting fields (error, last-wins, deep)")
+	flagSet.StringVar(&format, "format", "json", "Output format (json, or a render format: "+renderFormats()+")")
+	flagSet.StringVar(&output, "output", "", "Output file (defaults to standard output)")
+	flagSet.Parse(args)
+
+	if flagSet.NArg() < 2 {
+		flagSet.Usage()
+		os.Exit(1)
+	}
+
+	s, err := layupv1.ParseMergeStrategy(strategy)
+	if err != nil {
+		return err
+	}
+
+	mg := &layupv1.Merger{
+		Strategy: s,
+		Sources:  flagSet.Args(),
+	}
+
+	models := make([]*layupv1.Model, 0, flagSet.NArg())
+	for _, path := range flagSet.Args() {
+		m, err := loadModel(path)
+		if err != nil {
+			return fmt.Errorf("failed to load %s: %w", path, err)
+		}
+		models = append(models, m)
+	}
+
+	result, err := mg.Merge(models...)
+	if err != nil {
+		// Every conflict is listed, so they can all be fixed at once.
+		var conflictErr *layupv1.MergeConflictError
+		if errors.As(err, &conflictErr) {
+			for _, c := range conflictErr.Conflicts {
+				fmt.Fprintf(os.Stderr, "conflict: %s\n", c)
+			}
+			noun := "conflicts"
+			if len(conflictErr.Conflicts) == 1 {
+				noun = "conflict"
+			}
+			fmt.Fprintf(os.Stderr, "%d %s, use -strategy last-wins or deep to resolve them\n", len(conflictErr.Conflicts), noun)
+			os.Exit(1)
+		}
+		return err
+	}
+
+	for _, c := range result.Conflicts {
+		fmt.Fprintf(os.Stderr, "resolved (%s): %s\n", s, c)
+	}
+
+	w, err := createOutput(output)
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+
+	return writeModel(w, result.Model, format)
+}
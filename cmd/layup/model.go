@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	layupv1 "github.com/picatz/layup/pkg/layup/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// loadModel reads the model at the given path, which is either an HCL file,
+// or a JSON file (e.g. the output of "layup json"). A path of "-" reads HCL
+// from standard input.
+func loadModel(path string) (*layupv1.Model, error) {
+	var r io.Reader = os.Stdin
+
+	if path != "-" {
+		fh, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer fh.Close()
+
+		r = fh
+	}
+
+	if filepath.Ext(path) != ".json" {
+		return layupv1.ParseHCL(r)
+	}
+
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &layupv1.Model{}
+	if err := protojson.Unmarshal(b, m); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON model: %w", err)
+	}
+
+	if err := layupv1.Validate(m); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// loadGraph reads the model at the given path, like loadModel, and returns
+// a graph indexing it.
+func loadGraph(path string) (*layupv1.Graph, error) {
+	m, err := loadModel(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return layupv1.NewGraph(m)
+}
+
+// createOutput returns the writer for the given output path, which is
+// standard output if the path is empty or "-".
+func createOutput(path string) (io.WriteCloser, error) {
+	if path == "" || path == "-" {
+		return nopCloser{os.Stdout}, nil
+	}
+
+	return os.Create(path)
+}
+
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error { return nil }
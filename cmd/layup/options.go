@@ -0,0 +1,53 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"io"
+	"strings"
+
+	layupv1 "github.com/picatz/layup/pkg/layup/v1"
+)
+
+// traversalFlags are the flags shared by the commands which traverse the
+// graph, to choose which nodes and links are followed.
+type traversalFlags struct {
+	direction string
+	layers    string
+	links     string
+}
+
+// register adds the traversal flags to the given flag set, with the given
+// default direction.
+func (f *traversalFlags) register(flagSet *flag.FlagSet, direction string) {
+	flagSet.StringVar(&f.direction, "direction", direction, "Direction to follow links in (outgoing, incoming, both)")
+	flagSet.StringVar(&f.layers, "layers", "", "Comma separated IDs of the layers to include (defaults to all)")
+	flagSet.StringVar(&f.links, "links", "", "Comma separated IDs of the links to follow (defaults to all)")
+}
+
+// options returns the traversal options for the parsed flags.
+func (f *traversalFlags) options() ([]layupv1.TraversalOption, error) {
+	dir, err := layupv1.ParseDirection(f.direction)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := []layupv1.TraversalOption{layupv1.WithDirection(dir)}
+
+	if f.layers != "" {
+		opts = append(opts, layupv1.WithLayers(strings.Split(f.layers, ",")...))
+	}
+
+	if f.links != "" {
+		opts = append(opts, layupv1.WithLinkIDs(strings.Split(f.links, ",")...))
+	}
+
+	return opts, nil
+}
+
+// writeJSON writes the given value to the given writer as indented JSON.
+func writeJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"io"
+	"os"
+
+	layupv1 "github.com/picatz/layup/pkg/layup/v1"
+)
+
+func runPatch(args []string) error {
+	flagSet := newFlagSet("patch", "<path/to/layup.hcl> <path/to/patch.json>")
+
+	var (
+		format string
+		output string
+	)
+
+	flagSet.StringVar(&format, "format", "json", "Output format (json, or a render format: "+renderFormats()+")")
+	flagSet.StringVar(&output, "output", "", "Output file (defaults to standard output)")
+	flagSet.Parse(args)
+
+	if flagSet.NArg() != 2 {
+		flagSet.Usage()
+		os.Exit(1)
+	}
+
+	m, err := loadModel(flagSet.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	var r io.Reader = os.Stdin
+	if path := flagSet.Arg(1); path != "-" {
+		fh, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer fh.Close()
+
+		r = fh
+	}
+
+	p, err := layupv1.ParsePatch(r)
+	if err != nil {
+		return err
+	}
+
+	patched, err := layupv1.ApplyPatch(m, p)
+	if err != nil {
+		return err
+	}
+
+	w, err := createOutput(output)
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+
+	return writeModel(w, patched, format)
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	layupv1 "github.com/picatz/layup/pkg/layup/v1"
+)
+
+func runPath(args []string) error {
+	flagSet := newFlagSet("path", "<path/to/layup.hcl> <from> <to>")
+
+	var (
+		weight    string
+		k         int
+		all       bool
+		maxDepth  int
+		maxPaths  int
+		asJSON    bool
+		traversal traversalFlags
+	)
+
+	flagSet.StringVar(&weight, "weight", "", "Link attribute to use as the weight of each link (e.g. latency)")
+	flagSet.IntVar(&k, "k", 1, "Number of cheapest paths to find")
+	flagSet.BoolVar(&all, "all", false, "Find all simple paths, instead of the cheapest")
+	flagSet.IntVar(&maxDepth, "max-depth", -1, "Maximum number of links in a path, with -all")
+	flagSet.IntVar(&maxPaths, "max-paths", -1, "Maximum number of paths to find, with -all")
+	flagSet.BoolVar(&asJSON, "json", false, "Write the paths as JSON")
+	traversal.register(flagSet, "outgoing")
+	flagSet.Parse(args)
+
+	if flagSet.NArg() != 3 {
+		flagSet.Usage()
+		os.Exit(1)
+	}
+
+	opts, err := traversal.options()
+	if err != nil {
+		return err
+	}
+
+	g, err := loadGraph(flagSet.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	from, err := g.FindNode(flagSet.Arg(1))
+	if err != nil {
+		return err
+	}
+
+	to, err := g.FindNode(flagSet.Arg(2))
+	if err != nil {
+		return err
+	}
+
+	if weight != "" {
+		opts = append(opts, layupv1.WithWeight(weight))
+	}
+
+	var paths []layupv1.Path
+
+	switch {
+	case all:
+		opts = append(opts, layupv1.WithMaxDepth(maxDepth), layupv1.WithMaxPaths(maxPaths))
+		paths, err = g.AllSimplePaths(from, to, opts...)
+	case k > 1:
+		paths, err = g.KShortestPaths(from, to, k, opts...)
+	case weight != "":
+		var p layupv1.Path
+		p, err = g.Dijkstra(from, to, opts...)
+		paths = append(paths, p)
+	default:
+		var p layupv1.Path
+		p, err = g.ShortestPath(from, to, opts...)
+		paths = append(paths, p)
+	}
+	if err != nil {
+		return err
+	}
+
+	if asJSON {
+		return writeJSON(os.Stdout, paths)
+	}
+
+	for i, p := range paths {
+		if i > 0 {
+			fmt.Println()
+		}
+
+		fmt.Printf("cost: %s\n", strconv.FormatFloat(p.Cost, 'f', -1, 64))
+		for j, node := range p.Nodes {
+			if j > 0 {
+				fmt.Printf("  via %s\n", p.Links[j-1])
+			}
+			fmt.Printf("%s\n", node)
+		}
+	}
+
+	return nil
+}
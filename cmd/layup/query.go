@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	layupv1 "github.com/picatz/layup/pkg/layup/v1"
+)
+
+func runQuery(args []string) error {
+	flagSet := newFlagSet("query", "<path/to/layup.hcl> <expression>")
+
+	var (
+		format    string
+		output    string
+		selection string
+		dangling  string
+	)
+
+	flagSet.StringVar(&format, "format", "json", "Output format (json for the matches, text for their URIs, model for the matches as a model in JSON, or a render format: "+renderFormats()+")")
+	flagSet.StringVar(&output, "output", "", "Output file (defaults to standard output)")
+	flagSet.StringVar(&selection, "select", "", "CEL expression to select a value from each match (e.g. node.attributes.url)")
+	flagSet.StringVar(&dangling, "dangling", "drop", "What to do with links to nodes outside of the matches, for models (drop, keep, stub)")
+	flagSet.Parse(args)
+
+	if flagSet.NArg() != 2 {
+		flagSet.Usage()
+		os.Exit(1)
+	}
+
+	policy, err := layupv1.ParseDanglingPolicy(dangling)
+	if err != nil {
+		return err
+	}
+
+	g, err := loadGraph(flagSet.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	var opts []layupv1.QueryOption
+	if selection != "" {
+		opts = append(opts, layupv1.WithSelect(selection))
+	}
+
+	result, err := g.Query(flagSet.Arg(1), opts...)
+	if err != nil {
+		return err
+	}
+
+	w, err := createOutput(output)
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+
+	switch format {
+	case "json":
+		return writeJSON(w, result)
+	case "text":
+		for _, uri := range result.URIs() {
+			fmt.Fprintln(w, uri)
+		}
+		return nil
+	case "model":
+		format = "json"
+	}
+
+	m, err := result.Model(layupv1.WithDangling(policy))
+	if err != nil {
+		return err
+	}
+
+	return writeModel(w, m, format)
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	layupv1 "github.com/picatz/layup/pkg/layup/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// renderer writes the model in an output format, using the given options
+// if the format supports them.
+type renderer func(w io.Writer, m *layupv1.Model, opts ...layupv1.RenderOption) error
+
+// renderers are the available output formats for the render command.
+var renderers = map[string]renderer{
+	"cytoscape": func(w io.Writer, m *layupv1.Model, _ ...layupv1.RenderOption) error {
+		return layupv1.WriteCytoscape(w, m)
+	},
+	"d2":  layupv1.WriteD2,
+	"dot": layupv1.WriteDOT,
+	"jgf": func(w io.Writer, m *layupv1.Model, _ ...layupv1.RenderOption) error {
+		return layupv1.WriteJGF(w, m)
+	},
+	"mermaid": layupv1.WriteMermiad,
+	"svg":     layupv1.WriteSVG,
+	"text": func(w io.Writer, m *layupv1.Model, _ ...layupv1.RenderOption) error {
+		return layupv1.WriteText(w, m)
+	},
+	"jsonld": func(w io.Writer, m *layupv1.Model, _ ...layupv1.RenderOption) error {
+		return layupv1.WriteJSONLD(w, m)
+	},
+	"ntriples": func(w io.Writer, m *layupv1.Model, _ ...layupv1.RenderOption) error {
+		return layupv1.WriteNTriples(w, m)
+	},
+	"turtle": func(w io.Writer, m *layupv1.Model, _ ...layupv1.RenderOption) error {
+		return layupv1.WriteTurtle(w, m)
+	},
+}
+
+func renderFormats() string {
+	formats := make([]string, 0, len(renderers))
+	for format := range renderers {
+		formats = append(formats, format)
+	}
+	sort.Strings(formats)
+	return strings.Join(formats, ", ")
+}
+
+// writeModel writes the model as JSON, or in the given render format.
+func writeModel(w io.Writer, m *layupv1.Model, format string) error {
+	if format == "json" {
+		b, err := protojson.Marshal(m)
+		if err != nil {
+			return err
+		}
+
+		_, err = fmt.Fprintln(w, string(b))
+		return err
+	}
+
+	render, ok := renderers[format]
+	if !ok {
+		return fmt.Errorf("unknown format %q, must be json, or one of: %s", format, renderFormats())
+	}
+
+	return render(w, m)
+}
+
+func runRender(args []string) error {
+	flagSet := newFlagSet("render", "<path/to/layup.hcl>")
+
+	var (
+		format string
+		output string
+		root   string
+		width  int
+		ascii  bool
+	)
+
+	flagSet.StringVar(&format, "format", "dot", "Output format ("+renderFormats()+")")
+	flagSet.StringVar(&output, "output", "", "Output file (defaults to standard output)")
+	flagSet.StringVar(&root, "root", "", "Node URI (or layer/node path) to render a tree from, for the text format")
+	flagSet.IntVar(&width, "width", layupv1.DefaultTextWidth, "Maximum line width, for the text format")
+	flagSet.BoolVar(&ascii, "ascii", false, "Only use ASCII characters, for the text format")
+	flagSet.Parse(args)
+
+	if flagSet.NArg() != 1 {
+		flagSet.Usage()
+		os.Exit(1)
+	}
+
+	render, ok := renderers[format]
+	if !ok {
+		return fmt.Errorf("unknown format %q, must be one of: %s", format, renderFormats())
+	}
+
+	if format == "text" {
+		opts := []layupv1.TextOption{layupv1.WithTextWidth(width)}
+		if ascii {
+			opts = append(opts, layupv1.WithASCII())
+		}
+
+		render = func(w io.Writer, m *layupv1.Model, _ ...layupv1.RenderOption) error {
+			if root != "" {
+				return layupv1.WriteTree(w, m, root, opts...)
+			}
+			return layupv1.WriteText(w, m, opts...)
+		}
+	}
+
+	m, err := loadModel(flagSet.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	w, err := createOutput(output)
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+
+	return render(w, m)
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+
+	layupv1 "github.com/picatz/layup/pkg/layup/v1"
+)
+
+func runKeygen(args []string) error {
+	flagSet := newFlagSet("keygen", "<path/to/key.pem>")
+	flagSet.Parse(args)
+
+	if flagSet.NArg() != 1 {
+		flagSet.Usage()
+		os.Exit(1)
+	}
+
+	path := flagSet.Arg(0)
+
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return err
+	}
+
+	privDER, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		return err
+	}
+
+	pubDER, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return err
+	}
+
+	// The private key is only readable by its owner, and is never
+	// overwritten, since it may be the only copy.
+	fh, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return err
+	}
+	defer fh.Close()
+
+	if err := pem.Encode(fh, &pem.Block{Type: "PRIVATE KEY", Bytes: privDER}); err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(path+".pub", pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDER}), 0o644); err != nil {
+		return err
+	}
+
+	fmt.Printf("%s  %s.pub\n", layupv1.KeyID(pub), path)
+
+	return fh.Close()
+}
+
+func runSign(args []string) error {
+	flagSet := newFlagSet("sign", "<path/to/layup.hcl>")
+
+	var (
+		key    string
+		embed  bool
+		output string
+	)
+
+	flagSet.StringVar(&key, "key", "", "Ed25519 private key file (PEM encoded PKCS #8, e.g. from layup keygen)")
+	flagSet.BoolVar(&embed, "embed", false, "Write the model as JSON with the signature embedded in its attributes, instead of a detached signature")
+	flagSet.StringVar(&output, "output", "", "Output file (defaults to standard output)")
+	flagSet.Parse(args)
+
+	if flagSet.NArg() != 1 || key == "" {
+		flagSet.Usage()
+		os.Exit(1)
+	}
+
+	priv, err := loadPrivateKey(key)
+	if err != nil {
+		return err
+	}
+
+	m, err := loadModel(flagSet.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	sig, err := layupv1.Sign(m, priv)
+	if err != nil {
+		return err
+	}
+
+	w, err := createOutput(output)
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+
+	if !embed {
+		return writeJSON(w, sig)
+	}
+
+	signed, err := layupv1.EmbedSignature(m, sig)
+	if err != nil {
+		return err
+	}
+
+	return writeModel(w, signed, "json")
+}
+
+func runVerify(args []string) error {
+	flagSet := newFlagSet("verify", "<path/to/layup.hcl>")
+
+	var (
+		key       string
+		signature string
+	)
+
+	flagSet.StringVar(&key, "key", "", "Ed25519 public key file (PEM encoded PKIX, e.g. from layup keygen)")
+	flagSet.StringVar(&signature, "signature", "", "Detached signature file (defaults to the signature embedded in the model)")
+	flagSet.Parse(args)
+
+	if flagSet.NArg() != 1 || key == "" {
+		flagSet.Usage()
+		os.Exit(1)
+	}
+
+	pub, err := loadPublicKey(key)
+	if err != nil {
+		return err
+	}
+
+	m, err := loadModel(flagSet.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	var sig *layupv1.Signature
+
+	if signature != "" {
+		b, err := os.ReadFile(signature)
+		if err != nil {
+			return err
+		}
+
+		sig = &layupv1.Signature{}
+		if err := json.Unmarshal(b, sig); err != nil {
+			return fmt.Errorf("failed to parse signature: %w", err)
+		}
+	} else {
+		var ok bool
+
+		sig, ok, err = layupv1.EmbeddedSignature(m)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return errors.New("model has no embedded signature, use -signature to verify a detached signature")
+		}
+	}
+
+	if err := layupv1.Verify(m, sig, pub); err != nil {
+		return err
+	}
+
+	fmt.Printf("verified %s signed by %s\n", sig.Hash, sig.KeyID)
+
+	return nil
+}
+
+// loadPrivateKey loads a PEM encoded Ed25519 private key.
+func loadPrivateKey(path string) (ed25519.PrivateKey, error) {
+	der, err := readPEM(path, "PRIVATE KEY")
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key: %w", err)
+	}
+
+	priv, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("private key is a %T, not an Ed25519 key", key)
+	}
+
+	return priv, nil
+}
+
+// loadPublicKey loads a PEM encoded Ed25519 public key.
+func loadPublicKey(path string) (ed25519.PublicKey, error) {
+	der, err := readPEM(path, "PUBLIC KEY")
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse public key: %w", err)
+	}
+
+	pub, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("public key is a %T, not an Ed25519 key", key)
+	}
+
+	return pub, nil
+}
+
+// readPEM reads the contents of the PEM block of the given type from the
+// file at the given path.
+func readPEM(path, blockType string) ([]byte, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(b)
+	if block == nil || block.Type != blockType {
+		return nil, fmt.Errorf("%s does not contain a PEM encoded %s", path, blockType)
+	}
+
+	return block.Bytes, nil
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	layupv1 "github.com/picatz/layup/pkg/layup/v1"
+)
+
+func runSubgraph(args []string) error {
+	flagSet := newFlagSet("subgraph", "<path/to/layup.hcl>")
+
+	var (
+		format   string
+		output   string
+		nodes    string
+		layers   string
+		around   string
+		hops     int
+		where    string
+		dangling string
+		links    string
+	)
+
+	flagSet.StringVar(&format, "format", "json", "Output format (json, or a render format: "+renderFormats()+")")
+	flagSet.StringVar(&output, "output", "", "Output file (defaults to standard output)")
+	flagSet.StringVar(&nodes, "nodes", "", "Comma separated nodes to select (canonical URIs, or layer/node paths)")
+	flagSet.StringVar(&layers, "layers", "", "Comma separated IDs of the layers to select")
+	flagSet.StringVar(&around, "around", "", "Select the neighborhood of this node, within --hops links")
+	flagSet.IntVar(&hops, "hops", 1, "Maximum number of links from the --around node")
+	flagSet.StringVar(&where, "where", "", "Select the nodes with an attribute set to a value (key=value)")
+	flagSet.StringVar(&dangling, "dangling", "drop", "What to do with links to nodes outside of the subgraph (drop, keep, stub)")
+	flagSet.StringVar(&links, "links", "", "Comma separated IDs of the links to follow from the --around node (defaults to all)")
+	flagSet.Parse(args)
+
+	if flagSet.NArg() != 1 {
+		flagSet.Usage()
+		os.Exit(1)
+	}
+
+	selectors := 0
+	for _, s := range []string{nodes, layers, around, where} {
+		if s != "" {
+			selectors++
+		}
+	}
+
+	if selectors != 1 {
+		return fmt.Errorf("exactly one of --nodes, --layers, --around or --where is required")
+	}
+
+	policy, err := layupv1.ParseDanglingPolicy(dangling)
+	if err != nil {
+		return err
+	}
+
+	opts := []layupv1.SubgraphOption{layupv1.WithDangling(policy)}
+
+	if links != "" {
+		opts = append(opts, layupv1.WithTraversal(layupv1.WithLinkIDs(strings.Split(links, ",")...)))
+	}
+
+	g, err := loadGraph(flagSet.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	var m *layupv1.Model
+
+	switch {
+	case nodes != "":
+		var uris []string
+		for _, ref := range strings.Split(nodes, ",") {
+			uri, err := g.FindNode(ref)
+			if err != nil {
+				return err
+			}
+			uris = append(uris, uri)
+		}
+
+		m, err = g.Subgraph(uris, opts...)
+	case layers != "":
+		m, err = g.LayerSubgraph(strings.Split(layers, ","), opts...)
+	case around != "":
+		uri, err := g.FindNode(around)
+		if err != nil {
+			return err
+		}
+
+		m, err = g.Neighborhood(uri, hops, opts...)
+		if err != nil {
+			return err
+		}
+	case where != "":
+		key, value, ok := strings.Cut(where, "=")
+		if !ok {
+			return fmt.Errorf("invalid --where %q, must be key=value", where)
+		}
+
+		// Compare the formatted attribute values, since the type of the
+		// value can't be known from the flag.
+		m, err = g.FilterSubgraph(func(_ string, n *layupv1.Node) bool {
+			v, ok := n.GetAttributes()[key]
+			return ok && fmt.Sprint(v.AsInterface()) == value
+		}, opts...)
+	}
+	if err != nil {
+		return err
+	}
+
+	w, err := createOutput(output)
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+
+	return writeModel(w, m, format)
+}
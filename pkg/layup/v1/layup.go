@@ -0,0 +1,110 @@
+package layupv1
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/bufbuild/protovalidate-go"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// validator returns the shared protovalidate validator for models, which
+// is created once since compiling the validation rules is expensive.
+var validator = sync.OnceValues(func() (*protovalidate.Validator, error) {
+	return protovalidate.New(
+		protovalidate.WithMessages(&Model{}),
+		protovalidate.WithDisableLazy(true),
+	)
+})
+
+// Validate checks the given model against the validation rules defined
+// in Layup's protobuf schema, such as unique IDs and valid link references,
+// and that the model and layers marked with the AcyclicAttribute don't
+// contain any cycles.
+func Validate(m *Model) error {
+	v, err := validator()
+	if err != nil {
+		return err
+	}
+
+	if err := v.Validate(m); err != nil {
+		return err
+	}
+
+	return validateAcyclic(m)
+}
+
+// layerURI returns the canonical URI of the layer with the given ID,
+// rooted at the model's URI.
+func layerURI(m *Model, layerID string) string {
+	return NewLayerURI(m.GetUri(), layerID).String()
+}
+
+// nodeURI returns the canonical URI of the node with the given ID
+// within the given layer, rooted at the model's URI.
+func nodeURI(m *Model, layerID, nodeID string) string {
+	return NewNodeURI(m.GetUri(), layerID, nodeID).String()
+}
+
+// linkURI returns the canonical URI of the link with the given ID
+// within the given layer, rooted at the model's URI.
+func linkURI(m *Model, layerID, linkID string) string {
+	return NewLinkURI(m.GetUri(), layerID, linkID).String()
+}
+
+// linkTargetURI returns the canonical URI of the node the given link
+// is going to. Links within a layer use the local node ID, which is
+// resolved relative to the node the link is from, while links across
+// layers (or to other systems entirely) already use a URI.
+func linkTargetURI(m *Model, layer *Layer, link *Link) string {
+	to, err := NewNodeURI(m.GetUri(), layer.GetId(), link.GetFrom()).Resolve(link.GetTo())
+	if err != nil {
+		return link.GetTo()
+	}
+
+	return to.String()
+}
+
+// linkTargetID returns the ID that the DOT, Mermaid and D2 renderers use for
+// the node the given link is going to, which is the node's layer and node
+// IDs joined with the given separator, matching the IDs they give nodes in
+// each layer's subgraph, so links to other layers (by URI) line up.
+//
+// Links going to a node outside of the model return its URI as-is, and
+// external is true, since the URI is not a valid ID in any of the formats
+// and must be quoted (or escaped) by the renderer.
+func linkTargetID(m *Model, layer *Layer, link *Link, sep string) (id string, external bool) {
+	to, err := ParseURI(linkTargetURI(m, layer, link))
+	if err != nil || !to.IsNode() || to.Model != m.GetUri() {
+		return link.GetTo(), strings.Contains(link.GetTo(), "://")
+	}
+
+	return to.Layer + sep + to.Node, false
+}
+
+// attributesToMap converts the given attributes into native Go values,
+// which is useful for encoders that don't understand protobuf values.
+func attributesToMap(attrs map[string]*structpb.Value) map[string]any {
+	if len(attrs) == 0 {
+		return nil
+	}
+
+	m := make(map[string]any, len(attrs))
+	for k, v := range attrs {
+		m[k] = v.AsInterface()
+	}
+
+	return m
+}
+
+// sortedKeys returns the keys of the given map in sorted order, which is
+// used to write attributes deterministically.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
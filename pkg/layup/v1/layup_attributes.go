@@ -0,0 +1,483 @@
+package layupv1
+
+import (
+	"encoding"
+	"encoding/base64"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// AttributeError is returned when an attribute can't be decoded into (or
+// encoded from) a Go value, such as when it has the wrong type.
+type AttributeError struct {
+	// Attribute is the path of the attribute, with the fields of objects
+	// separated by dots, and list indexes in brackets, like "size.litres"
+	// or "tags[1]".
+	Attribute string
+	// Err is the reason the attribute couldn't be decoded or encoded.
+	Err error
+}
+
+// Error returns the path of the attribute and the reason.
+func (e *AttributeError) Error() string {
+	return fmt.Sprintf("attribute %q: %v", e.Attribute, e.Err)
+}
+
+// Unwrap returns the reason the attribute couldn't be decoded or encoded.
+func (e *AttributeError) Unwrap() error {
+	return e.Err
+}
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	durationType = reflect.TypeOf(time.Duration(0))
+	valueType    = reflect.TypeOf((*structpb.Value)(nil))
+)
+
+// DecodeAttributes decodes the attributes of a model, layer, node or link
+// into the struct pointed to by v, instead of type switching on the kind
+// of each structpb value:
+//
+//	var bowl struct {
+//		Material string        `layup:"material"`
+//		Size     struct {
+//			Litres float64 `layup:"litres"`
+//		} `layup:"size"`
+//		Tags     []string      `layup:"tags"`
+//		Cleaned  time.Time     `layup:"cleaned"`
+//		Timeout  time.Duration `layup:"timeout"`
+//	}
+//
+//	err := layupv1.DecodeAttributes(node.GetAttributes(), &bowl)
+//
+// Attributes are decoded into the exported fields named by their "layup"
+// tag (or the field name, without a tag). Fields tagged "-" are skipped, as
+// are attributes without a field, and fields without an attribute (or with
+// a null value) are unchanged.
+//
+// Objects are decoded into structs, or maps with string keys, lists into
+// slices, or arrays of the same length, and numbers into any numeric type
+// which can represent them exactly. Strings are decoded into time.Time (as
+// RFC 3339), time.Duration (see time.ParseDuration), []byte (as base64),
+// and types implementing encoding.TextUnmarshaler. Values of other types return an AttributeError.
+func DecodeAttributes(attrs map[string]*structpb.Value, v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("failed to decode attributes: %T is not a pointer to a struct", v)
+	}
+
+	return decodeStruct("", &structpb.Struct{Fields: attrs}, rv.Elem())
+}
+
+// EncodeAttributes encodes the struct v (or a pointer to it) as attributes,
+// which can be decoded back into the same struct with DecodeAttributes.
+//
+// Fields tagged with the "omitempty" option (like `layup:"tags,omitempty"`)
+// are omitted if they have their zero value. Times are encoded as RFC 3339
+// strings, durations as strings (see time.Duration.String), and types
+// implementing encoding.TextMarshaler as strings.
+func EncodeAttributes(v any) (map[string]*structpb.Value, error) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+
+	if rv.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("failed to encode attributes: %T is not a struct", v)
+	}
+
+	s, err := encodeStruct("", rv)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetFields(), nil
+}
+
+// attributeField is an exported field of a struct, with its attribute
+// name and options from its tag.
+type attributeField struct {
+	index     int
+	name      string
+	omitEmpty bool
+}
+
+// structAttributeFields returns the fields of the given struct type which
+// are decoded from (and encoded to) attributes.
+func structAttributeFields(t reflect.Type) []attributeField {
+	var fields []attributeField
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+
+		tag, ok := f.Tag.Lookup("layup")
+		if tag == "-" {
+			continue
+		}
+
+		name, opts, _ := strings.Cut(tag, ",")
+		if !ok || name == "" {
+			name = f.Name
+		}
+
+		fields = append(fields, attributeField{
+			index:     i,
+			name:      name,
+			omitEmpty: opts == "omitempty",
+		})
+	}
+
+	return fields
+}
+
+// attributePath returns the path of a field within the given path.
+func attributePath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// kindName returns the name of the kind of a structpb value, used in
+// errors.
+func kindName(v *structpb.Value) string {
+	switch v.GetKind().(type) {
+	case *structpb.Value_NullValue:
+		return "null"
+	case *structpb.Value_BoolValue:
+		return "bool"
+	case *structpb.Value_NumberValue:
+		return "number"
+	case *structpb.Value_StringValue:
+		return "string"
+	case *structpb.Value_ListValue:
+		return "list"
+	case *structpb.Value_StructValue:
+		return "object"
+	default:
+		return "unknown value"
+	}
+}
+
+func decodeStruct(path string, s *structpb.Struct, rv reflect.Value) error {
+	for _, f := range structAttributeFields(rv.Type()) {
+		v, ok := s.GetFields()[f.name]
+		if !ok {
+			continue
+		}
+
+		if err := decodeValue(attributePath(path, f.name), v, rv.Field(f.index)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func decodeValue(path string, v *structpb.Value, rv reflect.Value) error {
+	if _, ok := v.GetKind().(*structpb.Value_NullValue); ok {
+		return nil
+	}
+
+	mismatch := func() error {
+		return &AttributeError{Attribute: path, Err: fmt.Errorf("cannot decode %s into %s", kindName(v), rv.Type())}
+	}
+
+	// Types with their own representation are checked before their kind,
+	// since a time.Duration is also an int64.
+	switch {
+	case rv.Type() == valueType:
+		rv.Set(reflect.ValueOf(proto.Clone(v)))
+		return nil
+	case rv.Type() == timeType, rv.Type() == durationType:
+		s, ok := v.GetKind().(*structpb.Value_StringValue)
+		if !ok {
+			return mismatch()
+		}
+
+		if rv.Type() == durationType {
+			d, err := time.ParseDuration(s.StringValue)
+			if err != nil {
+				return &AttributeError{Attribute: path, Err: err}
+			}
+			rv.SetInt(int64(d))
+			return nil
+		}
+
+		t, err := time.Parse(time.RFC3339, s.StringValue)
+		if err != nil {
+			return &AttributeError{Attribute: path, Err: err}
+		}
+		rv.Set(reflect.ValueOf(t))
+		return nil
+	case rv.Kind() != reflect.Pointer && rv.CanAddr() && rv.Addr().Type().Implements(reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()):
+		s, ok := v.GetKind().(*structpb.Value_StringValue)
+		if !ok {
+			return mismatch()
+		}
+
+		if err := rv.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s.StringValue)); err != nil {
+			return &AttributeError{Attribute: path, Err: err}
+		}
+		return nil
+	}
+
+	switch rv.Kind() {
+	case reflect.Pointer:
+		if rv.IsNil() {
+			rv.Set(reflect.New(rv.Type().Elem()))
+		}
+		return decodeValue(path, v, rv.Elem())
+	case reflect.Interface:
+		if rv.NumMethod() != 0 {
+			return mismatch()
+		}
+		rv.Set(reflect.ValueOf(v.AsInterface()))
+	case reflect.Bool:
+		b, ok := v.GetKind().(*structpb.Value_BoolValue)
+		if !ok {
+			return mismatch()
+		}
+		rv.SetBool(b.BoolValue)
+	case reflect.String:
+		s, ok := v.GetKind().(*structpb.Value_StringValue)
+		if !ok {
+			return mismatch()
+		}
+		rv.SetString(s.StringValue)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, ok := v.GetKind().(*structpb.Value_NumberValue)
+		if !ok {
+			return mismatch()
+		}
+
+		if n.NumberValue != math.Trunc(n.NumberValue) {
+			return &AttributeError{Attribute: path, Err: fmt.Errorf("number %v is not an integer", n.NumberValue)}
+		}
+
+		// Numbers outside of the range of an int64 can't be converted
+		// to one, so they're checked first.
+		i := int64(n.NumberValue)
+		if n.NumberValue < math.MinInt64 || n.NumberValue >= math.MaxInt64 || rv.OverflowInt(i) {
+			return &AttributeError{Attribute: path, Err: fmt.Errorf("number %v overflows %s", n.NumberValue, rv.Type())}
+		}
+		rv.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n, ok := v.GetKind().(*structpb.Value_NumberValue)
+		if !ok {
+			return mismatch()
+		}
+
+		if n.NumberValue != math.Trunc(n.NumberValue) {
+			return &AttributeError{Attribute: path, Err: fmt.Errorf("number %v is not an integer", n.NumberValue)}
+		}
+
+		u := uint64(n.NumberValue)
+		if n.NumberValue < 0 || n.NumberValue >= math.MaxUint64 || rv.OverflowUint(u) {
+			return &AttributeError{Attribute: path, Err: fmt.Errorf("number %v overflows %s", n.NumberValue, rv.Type())}
+		}
+		rv.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		n, ok := v.GetKind().(*structpb.Value_NumberValue)
+		if !ok {
+			return mismatch()
+		}
+
+		if rv.OverflowFloat(n.NumberValue) {
+			return &AttributeError{Attribute: path, Err: fmt.Errorf("number %v overflows %s", n.NumberValue, rv.Type())}
+		}
+		rv.SetFloat(n.NumberValue)
+	case reflect.Slice:
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			// Bytes are base64 encoded, like structpb.NewValue.
+			s, ok := v.GetKind().(*structpb.Value_StringValue)
+			if !ok {
+				return mismatch()
+			}
+
+			b, err := base64.StdEncoding.DecodeString(s.StringValue)
+			if err != nil {
+				return &AttributeError{Attribute: path, Err: err}
+			}
+			rv.SetBytes(b)
+			return nil
+		}
+
+		l, ok := v.GetKind().(*structpb.Value_ListValue)
+		if !ok {
+			return mismatch()
+		}
+
+		values := l.ListValue.GetValues()
+		slice := reflect.MakeSlice(rv.Type(), len(values), len(values))
+		for i, ev := range values {
+			if err := decodeValue(path+"["+strconv.Itoa(i)+"]", ev, slice.Index(i)); err != nil {
+				return err
+			}
+		}
+		rv.Set(slice)
+	case reflect.Array:
+		l, ok := v.GetKind().(*structpb.Value_ListValue)
+		if !ok {
+			return mismatch()
+		}
+
+		values := l.ListValue.GetValues()
+		if len(values) != rv.Len() {
+			return &AttributeError{Attribute: path, Err: fmt.Errorf("cannot decode list of %d values into %s", len(values), rv.Type())}
+		}
+
+		for i, ev := range values {
+			if err := decodeValue(path+"["+strconv.Itoa(i)+"]", ev, rv.Index(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		s, ok := v.GetKind().(*structpb.Value_StructValue)
+		if !ok || rv.Type().Key().Kind() != reflect.String {
+			return mismatch()
+		}
+
+		if rv.IsNil() {
+			rv.Set(reflect.MakeMapWithSize(rv.Type(), len(s.StructValue.GetFields())))
+		}
+
+		for k, fv := range s.StructValue.GetFields() {
+			ev := reflect.New(rv.Type().Elem()).Elem()
+			if err := decodeValue(attributePath(path, k), fv, ev); err != nil {
+				return err
+			}
+			rv.SetMapIndex(reflect.ValueOf(k).Convert(rv.Type().Key()), ev)
+		}
+	case reflect.Struct:
+		s, ok := v.GetKind().(*structpb.Value_StructValue)
+		if !ok {
+			return mismatch()
+		}
+		return decodeStruct(path, s.StructValue, rv)
+	default:
+		return mismatch()
+	}
+
+	return nil
+}
+
+func encodeStruct(path string, rv reflect.Value) (*structpb.Struct, error) {
+	s := &structpb.Struct{Fields: map[string]*structpb.Value{}}
+
+	for _, f := range structAttributeFields(rv.Type()) {
+		fv := rv.Field(f.index)
+		if f.omitEmpty && fv.IsZero() {
+			continue
+		}
+
+		v, err := encodeValue(attributePath(path, f.name), fv)
+		if err != nil {
+			return nil, err
+		}
+
+		s.Fields[f.name] = v
+	}
+
+	return s, nil
+}
+
+func encodeValue(path string, rv reflect.Value) (*structpb.Value, error) {
+	if !rv.IsValid() {
+		return structpb.NewNullValue(), nil
+	}
+
+	switch {
+	case rv.Type() == valueType:
+		if rv.IsNil() {
+			return structpb.NewNullValue(), nil
+		}
+		return proto.Clone(rv.Interface().(*structpb.Value)).(*structpb.Value), nil
+	case rv.Type() == timeType:
+		return structpb.NewStringValue(rv.Interface().(time.Time).Format(time.RFC3339Nano)), nil
+	case rv.Type() == durationType:
+		return structpb.NewStringValue(time.Duration(rv.Int()).String()), nil
+	case rv.Kind() != reflect.Pointer && rv.Type().Implements(reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()):
+		b, err := rv.Interface().(encoding.TextMarshaler).MarshalText()
+		if err != nil {
+			return nil, &AttributeError{Attribute: path, Err: err}
+		}
+		return structpb.NewStringValue(string(b)), nil
+	}
+
+	switch rv.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if rv.IsNil() {
+			return structpb.NewNullValue(), nil
+		}
+		return encodeValue(path, rv.Elem())
+	case reflect.Bool:
+		return structpb.NewBoolValue(rv.Bool()), nil
+	case reflect.String:
+		return structpb.NewStringValue(rv.String()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return structpb.NewNumberValue(float64(rv.Int())), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return structpb.NewNumberValue(float64(rv.Uint())), nil
+	case reflect.Float32, reflect.Float64:
+		f := rv.Float()
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return nil, &AttributeError{Attribute: path, Err: fmt.Errorf("number %v can't be represented", f)}
+		}
+		return structpb.NewNumberValue(f), nil
+	case reflect.Slice, reflect.Array:
+		if rv.Kind() == reflect.Slice && rv.Type().Elem().Kind() == reflect.Uint8 {
+			// Bytes are base64 encoded, like structpb.NewValue.
+			v, err := structpb.NewValue(rv.Bytes())
+			if err != nil {
+				return nil, &AttributeError{Attribute: path, Err: err}
+			}
+			return v, nil
+		}
+
+		values := make([]*structpb.Value, rv.Len())
+		for i := range values {
+			v, err := encodeValue(path+"["+strconv.Itoa(i)+"]", rv.Index(i))
+			if err != nil {
+				return nil, err
+			}
+			values[i] = v
+		}
+		return structpb.NewListValue(&structpb.ListValue{Values: values}), nil
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			return nil, &AttributeError{Attribute: path, Err: fmt.Errorf("cannot encode %s, map keys must be strings", rv.Type())}
+		}
+
+		fields := make(map[string]*structpb.Value, rv.Len())
+		iter := rv.MapRange()
+		for iter.Next() {
+			k := iter.Key().String()
+
+			v, err := encodeValue(attributePath(path, k), iter.Value())
+			if err != nil {
+				return nil, err
+			}
+			fields[k] = v
+		}
+		return structpb.NewStructValue(&structpb.Struct{Fields: fields}), nil
+	case reflect.Struct:
+		s, err := encodeStruct(path, rv)
+		if err != nil {
+			return nil, err
+		}
+		return structpb.NewStructValue(s), nil
+	default:
+		return nil, &AttributeError{Attribute: path, Err: fmt.Errorf("cannot encode %s", rv.Type())}
+	}
+}
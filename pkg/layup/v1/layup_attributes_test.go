@@ -0,0 +1,230 @@
+package layupv1_test
+
+import (
+	"errors"
+	"net/netip"
+	"reflect"
+	"testing"
+	"time"
+
+	layupv1 "github.com/picatz/layup/pkg/layup/v1"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+type testServer struct {
+	Name     string            `layup:"name"`
+	Port     uint16            `layup:"port"`
+	Weight   float64           `layup:"weight,omitempty"`
+	Enabled  bool              `layup:"enabled"`
+	Address  netip.Addr        `layup:"address"`
+	Started  time.Time         `layup:"started"`
+	Timeout  time.Duration     `layup:"timeout"`
+	Tags     []string          `layup:"tags,omitempty"`
+	Labels   map[string]string `layup:"labels,omitempty"`
+	Limits   testLimits        `layup:"limits"`
+	Backup   *testLimits       `layup:"backup,omitempty"`
+	Extra    any               `layup:"extra,omitempty"`
+	Checksum []byte            `layup:"checksum,omitempty"`
+	Zones    [2]string         `layup:"zones"`
+	Internal string            `layup:"-"`
+	Region   string
+}
+
+type testLimits struct {
+	CPU    float64 `layup:"cpu"`
+	Memory int     `layup:"memory"`
+}
+
+func TestDecodeAttributes(t *testing.T) {
+	g := newTestGraph(t, `
+uri = "layup://infra"
+
+layer "servers" {
+	node "web" {
+		name     = "web-1"
+		port     = 8080
+		enabled  = true
+		address  = "10.0.0.1"
+		started  = "2024-01-02T03:04:05Z"
+		timeout  = "1m30s"
+		tags     = ["a", "b"]
+		labels   = { team = "platform" }
+		limits   = { cpu = 0.5, memory = 512 }
+		backup   = { cpu = 1, memory = 1024 }
+		extra    = { nested = [1, "two"] }
+		Internal = "ignored"
+		Region   = "eu"
+		unknown  = "ignored"
+	}
+}
+`)
+
+	n, _ := g.Node("layup://infra/layers/servers/nodes/web")
+
+	var got testServer
+	if err := layupv1.DecodeAttributes(n.GetAttributes(), &got); err != nil {
+		t.Fatal(err)
+	}
+
+	want := testServer{
+		Name:    "web-1",
+		Port:    8080,
+		Enabled: true,
+		Address: netip.MustParseAddr("10.0.0.1"),
+		Started: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		Timeout: 90 * time.Second,
+		Tags:    []string{"a", "b"},
+		Labels:  map[string]string{"team": "platform"},
+		Limits:  testLimits{CPU: 0.5, Memory: 512},
+		Backup:  &testLimits{CPU: 1, Memory: 1024},
+		Extra:   map[string]any{"nested": []any{1.0, "two"}},
+		Region:  "eu",
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected:\n%+v\ngot:\n%+v", want, got)
+	}
+}
+
+func TestDecodeAttributes_errors(t *testing.T) {
+	tests := []struct {
+		name  string
+		attrs map[string]any
+		err   string
+	}{
+		{
+			name:  "type mismatch",
+			attrs: map[string]any{"name": 1},
+			err:   `attribute "name": cannot decode number into string`,
+		},
+		{
+			name:  "nested type mismatch",
+			attrs: map[string]any{"limits": map[string]any{"memory": "lots"}},
+			err:   `attribute "limits.memory": cannot decode string into int`,
+		},
+		{
+			name:  "list element type mismatch",
+			attrs: map[string]any{"tags": []any{"a", true}},
+			err:   `attribute "tags[1]": cannot decode bool into string`,
+		},
+		{
+			name:  "overflow",
+			attrs: map[string]any{"port": 70000},
+			err:   `attribute "port": number 70000 overflows uint16`,
+		},
+		{
+			name:  "negative",
+			attrs: map[string]any{"port": -1},
+			err:   `attribute "port": number -1 overflows uint16`,
+		},
+		{
+			name:  "fraction",
+			attrs: map[string]any{"port": 80.5},
+			err:   `attribute "port": number 80.5 is not an integer`,
+		},
+		{
+			name:  "int fraction",
+			attrs: map[string]any{"limits": map[string]any{"memory": 1.5}},
+			err:   `attribute "limits.memory": number 1.5 is not an integer`,
+		},
+		{
+			name:  "int overflow",
+			attrs: map[string]any{"limits": map[string]any{"memory": 1e30}},
+			err:   `attribute "limits.memory": number 1e+30 overflows int`,
+		},
+		{
+			name:  "duration",
+			attrs: map[string]any{"timeout": "soon"},
+			err:   `attribute "timeout": time: invalid duration "soon"`,
+		},
+		{
+			name:  "bytes",
+			attrs: map[string]any{"checksum": "not base64!"},
+			err:   `attribute "checksum": illegal base64 data at input byte 3`,
+		},
+		{
+			name:  "array length",
+			attrs: map[string]any{"zones": []any{"a", "b", "c"}},
+			err:   `attribute "zones": cannot decode list of 3 values into [2]string`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s, err := structpb.NewStruct(test.attrs)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var server testServer
+			err = layupv1.DecodeAttributes(s.GetFields(), &server)
+
+			var attrErr *layupv1.AttributeError
+			if !errors.As(err, &attrErr) {
+				t.Fatalf("expected attribute error, got: %v", err)
+			}
+
+			if err.Error() != test.err {
+				t.Fatalf("expected error %q, got %q", test.err, err)
+			}
+		})
+	}
+
+	if err := layupv1.DecodeAttributes(nil, testServer{}); err == nil {
+		t.Fatal("expected error decoding into a non-pointer")
+	}
+}
+
+func TestEncodeAttributes(t *testing.T) {
+	server := testServer{
+		Name:     "web-1",
+		Port:     8080,
+		Address:  netip.MustParseAddr("10.0.0.1"),
+		Started:  time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		Timeout:  90 * time.Second,
+		Limits:   testLimits{CPU: 0.5, Memory: 512},
+		Checksum: []byte{0xde, 0xad, 0xbe, 0xef},
+		Zones:    [2]string{"eu-1a", "eu-1b"},
+		Internal: "secret",
+	}
+
+	attrs, err := layupv1.EncodeAttributes(&server)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, k := range []string{"weight", "tags", "labels", "backup", "extra", "Internal"} {
+		if _, ok := attrs[k]; ok {
+			t.Fatalf("expected %q to be omitted", k)
+		}
+	}
+
+	if got := attrs["timeout"].GetStringValue(); got != "1m30s" {
+		t.Fatalf("unexpected timeout: %q", got)
+	}
+
+	if got := attrs["address"].GetStringValue(); got != "10.0.0.1" {
+		t.Fatalf("unexpected address: %q", got)
+	}
+
+	if got := attrs["checksum"].GetStringValue(); got != "3q2+7w==" {
+		t.Fatalf("unexpected checksum: %q", got)
+	}
+
+	var decoded testServer
+	if err := layupv1.DecodeAttributes(attrs, &decoded); err != nil {
+		t.Fatal(err)
+	}
+
+	server.Internal = ""
+	if !reflect.DeepEqual(decoded, server) {
+		t.Fatalf("expected:\n%+v\ngot:\n%+v", server, decoded)
+	}
+
+	_, err = layupv1.EncodeAttributes(struct {
+		Handler func() `layup:"handler"`
+	}{})
+	if err == nil || err.Error() != `attribute "handler": cannot encode func()` {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,331 @@
+package layupv1
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// Builder builds a model programmatically, as an alternative to writing
+// nested Model, Layer, Node and Link literals with structpb attributes:
+//
+//	b := layupv1.NewBuilder("layup://recipe")
+//
+//	b.Layer("ingredients").
+//		Node("flour").
+//		Link("add_flour", "flour", "tools/bowl")
+//
+//	b.Layer("tools").
+//		Node("bowl", layupv1.Attr("material", "glass")).
+//		Node("pan", layupv1.Attr("material", "steel")).
+//		Link("pour", "bowl", "pan")
+//
+//	m, err := b.Build()
+//
+// Elements are kept in the order they are first added. Errors, such as
+// attributes which can't be converted, or links to unknown nodes, are
+// returned by Build, so calls can be chained.
+type Builder struct {
+	uri    string
+	opts   []BuildOption
+	layers []*LayerBuilder
+	index  map[string]*LayerBuilder
+}
+
+// LayerBuilder builds a layer of a model, created by Builder.Layer.
+type LayerBuilder struct {
+	builder *Builder
+	id      string
+	opts    []BuildOption
+	nodes   []*nodeBuilder
+	index   map[string]*nodeBuilder
+	links   []*linkBuilder
+	errs    []error
+}
+
+type nodeBuilder struct {
+	id   string
+	opts []BuildOption
+}
+
+type linkBuilder struct {
+	id, from, to string
+	opts         []BuildOption
+}
+
+// BuildOption sets a field of an element being built, such as an
+// attribute with Attr.
+type BuildOption func(*buildConfig)
+
+// buildConfig is the fields of an element set by its build options.
+type buildConfig struct {
+	attrs   map[string]any
+	keys    []string
+	dynamic *bool
+}
+
+// Attr sets the attribute of an element to the given value, which is
+// converted to a structpb.Value like the fields encoded by EncodeAttributes,
+// so it can be any JSON-like value, a struct, or a *structpb.Value.
+func Attr(key string, value any) BuildOption {
+	return func(e *buildConfig) {
+		if _, ok := e.attrs[key]; !ok {
+			e.keys = append(e.keys, key)
+		}
+		e.attrs[key] = value
+	}
+}
+
+// Attrs sets the attributes of an element, like Attr.
+func Attrs(attrs map[string]any) BuildOption {
+	keys := make([]string, 0, len(attrs))
+	for k := range attrs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return func(e *buildConfig) {
+		for _, k := range keys {
+			Attr(k, attrs[k])(e)
+		}
+	}
+}
+
+// Dynamic sets whether a layer is dynamic. It can only be used with
+// layers.
+func Dynamic(dynamic bool) BuildOption {
+	return func(e *buildConfig) {
+		e.dynamic = &dynamic
+	}
+}
+
+// NewBuilder returns a builder for a model with the given URI, and options,
+// such as its attributes.
+func NewBuilder(uri string, opts ...BuildOption) *Builder {
+	return &Builder{
+		uri:   uri,
+		opts:  opts,
+		index: map[string]*LayerBuilder{},
+	}
+}
+
+// Layer returns the builder for the layer with the given ID, adding it if
+// it doesn't exist yet, with the given options.
+func (b *Builder) Layer(id string, opts ...BuildOption) *LayerBuilder {
+	lb, ok := b.index[id]
+	if !ok {
+		lb = &LayerBuilder{
+			builder: b,
+			id:      id,
+			index:   map[string]*nodeBuilder{},
+		}
+		b.index[id] = lb
+		b.layers = append(b.layers, lb)
+	}
+
+	lb.opts = append(lb.opts, opts...)
+
+	return lb
+}
+
+// Layer returns the builder for another layer of the model (see
+// Builder.Layer), so layers can be built in a single chain.
+func (lb *LayerBuilder) Layer(id string, opts ...BuildOption) *LayerBuilder {
+	return lb.builder.Layer(id, opts...)
+}
+
+// Node adds the node with the given ID to the layer, with the given
+// options, such as its attributes. Adding a node which already exists sets
+// its options, overriding any attributes with the same keys.
+func (lb *LayerBuilder) Node(id string, opts ...BuildOption) *LayerBuilder {
+	nb, ok := lb.index[id]
+	if !ok {
+		nb = &nodeBuilder{id: id}
+		lb.index[id] = nb
+		lb.nodes = append(lb.nodes, nb)
+	}
+
+	nb.opts = append(nb.opts, opts...)
+
+	return lb
+}
+
+// Link adds the link with the given ID to the layer, from the node with
+// the given ID within the layer, to another node.
+//
+// The node the link is going to is given by its ID within the layer, its
+// "layer/node" path within the model, or its URI (including nodes outside
+// of the model). Nodes are resolved by Build, so they can be added after
+// the links to them.
+func (lb *LayerBuilder) Link(id, from, to string, opts ...BuildOption) *LayerBuilder {
+	for _, link := range lb.links {
+		if link.id == id {
+			lb.errs = append(lb.errs, fmt.Errorf("link %q is added more than once", linkURI(&Model{Uri: lb.builder.uri}, lb.id, id)))
+			return lb
+		}
+	}
+
+	lb.links = append(lb.links, &linkBuilder{id: id, from: from, to: to, opts: opts})
+
+	return lb
+}
+
+// Build returns the model, which is validated (see Validate). It can be
+// called more than once, returning a new model each time.
+func (b *Builder) Build() (*Model, error) {
+	var errs []error
+
+	m := &Model{Uri: b.uri}
+
+	e, err := applyBuildOptions(b.opts)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("model: %w", err))
+	}
+	m.Attributes = e.values
+	if e.dynamic != nil {
+		errs = append(errs, fmt.Errorf("model: only layers can be dynamic"))
+	}
+
+	for _, lb := range b.layers {
+		errs = append(errs, lb.errs...)
+
+		layer := &Layer{Id: lb.id}
+		m.Layers = append(m.Layers, layer)
+
+		e, err := applyBuildOptions(lb.opts)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", layerURI(m, lb.id), err))
+		}
+		layer.Attributes = e.values
+		if e.dynamic != nil {
+			layer.Dynamic = proto.Bool(*e.dynamic)
+		}
+
+		for _, nb := range lb.nodes {
+			uri := nodeURI(m, lb.id, nb.id)
+
+			e, err := applyBuildOptions(nb.opts)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", uri, err))
+			}
+			if e.dynamic != nil {
+				errs = append(errs, fmt.Errorf("%s: only layers can be dynamic", uri))
+			}
+
+			layer.Nodes = append(layer.Nodes, &Node{Id: nb.id, Attributes: e.values})
+		}
+
+		for _, link := range lb.links {
+			uri := linkURI(m, lb.id, link.id)
+
+			e, err := applyBuildOptions(link.opts)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", uri, err))
+			}
+			if e.dynamic != nil {
+				errs = append(errs, fmt.Errorf("%s: only layers can be dynamic", uri))
+			}
+
+			if _, ok := lb.index[link.from]; !ok {
+				errs = append(errs, fmt.Errorf("%s: unknown node %q within layer %q", uri, link.from, lb.id))
+			}
+
+			to, err := b.resolve(lb, link.to)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", uri, err))
+			}
+
+			layer.Links = append(layer.Links, &Link{Id: link.id, From: link.from, To: to, Attributes: e.values})
+		}
+	}
+
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("failed to build model: %w", errors.Join(errs...))
+	}
+
+	if err := Validate(m); err != nil {
+		return nil, fmt.Errorf("failed to build model: %w", err)
+	}
+
+	return m, nil
+}
+
+// resolve returns the "to" of a link within the given layer, going to the
+// given node, which is its local ID, or canonical URI.
+func (b *Builder) resolve(lb *LayerBuilder, to string) (string, error) {
+	if strings.Contains(to, "://") {
+		u, err := ParseURI(to)
+		if err != nil || u.Model != b.uri {
+			// Nodes outside of the model can't be checked.
+			return to, nil
+		}
+
+		if u.Node == "" {
+			return "", fmt.Errorf("%q is not a node", to)
+		}
+
+		to = u.Layer + "/" + u.Node
+	}
+
+	layerID, nodeID, ok := strings.Cut(to, "/")
+	if !ok {
+		if _, ok := lb.index[to]; !ok {
+			return "", fmt.Errorf("unknown node %q within layer %q", to, lb.id)
+		}
+		return to, nil
+	}
+
+	other, ok := b.index[layerID]
+	if !ok {
+		return "", fmt.Errorf("unknown layer %q", layerID)
+	}
+
+	if _, ok := other.index[nodeID]; !ok {
+		return "", fmt.Errorf("unknown node %q within layer %q", nodeID, layerID)
+	}
+
+	if other == lb {
+		return nodeID, nil
+	}
+
+	return NewNodeURI(b.uri, layerID, nodeID).String(), nil
+}
+
+// builtElement is an element's fields, with its attributes converted.
+type builtElement struct {
+	values  map[string]*structpb.Value
+	dynamic *bool
+}
+
+// applyBuildOptions applies the options, converting the attributes to
+// structpb values.
+func applyBuildOptions(opts []BuildOption) (builtElement, error) {
+	e := &buildConfig{attrs: map[string]any{}}
+	for _, opt := range opts {
+		opt(e)
+	}
+
+	built := builtElement{dynamic: e.dynamic}
+	if len(e.keys) == 0 {
+		return built, nil
+	}
+
+	var errs []error
+
+	built.values = make(map[string]*structpb.Value, len(e.keys))
+	for _, k := range e.keys {
+		v, err := encodeValue(k, reflect.ValueOf(e.attrs[k]))
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		built.values[k] = v
+	}
+
+	return built, errors.Join(errs...)
+}
@@ -0,0 +1,131 @@
+package layupv1_test
+
+import (
+	"strings"
+	"testing"
+
+	layupv1 "github.com/picatz/layup/pkg/layup/v1"
+)
+
+func TestBuilder(t *testing.T) {
+	b := layupv1.NewBuilder("layup://recipe")
+
+	b.Layer("ingredients").
+		Node("flour").
+		Node("sugar").
+		Node("milk").
+		Link("add_flour", "flour", "tools/bowl").
+		Link("add_sugar", "sugar", "layup://recipe/layers/tools/nodes/bowl").
+		Link("pour_milk", "milk", "tools/jug").
+		Layer("tools").
+		Node("bowl", layupv1.Attr("material", "glass")).
+		Node("jug", layupv1.Attr("material", "plastic")).
+		Node("pan", layupv1.Attr("material", "steel")).
+		Node("spoon", layupv1.Attr("material", "wood")).
+		Link("pour", "bowl", "pan").
+		Link("pour_jug", "jug", "bowl").
+		Link("stir", "spoon", "layup://recipe/layers/tools/nodes/pan")
+
+	m, err := b.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d, err := layupv1.Diff(newTestGraph(t, testRecipe).Model(), m)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !d.Empty() {
+		t.Fatalf("expected the built model to equal the HCL model, got changes: %v", d.Changes)
+	}
+
+	// Links within the same layer use the local node ID.
+	if to := m.GetLayers()[1].GetLinks()[2].GetTo(); to != "pan" {
+		t.Fatalf("expected link to local node ID, got %q", to)
+	}
+}
+
+func TestBuilder_attributes(t *testing.T) {
+	b := layupv1.NewBuilder("layup://shop", layupv1.Attr("acyclic", true))
+
+	b.Layer("stock", layupv1.Dynamic(true), layupv1.Attrs(map[string]any{"owner": "alice"})).
+		Node("apples",
+			layupv1.Attr("count", 12),
+			layupv1.Attr("varieties", []string{"gala", "fuji"}),
+			layupv1.Attr("prices", map[string]float64{"gala": 0.5}),
+			layupv1.Attr("supplier", map[string]any{"name": "orchard", "ids": []int{1, 2}}),
+			layupv1.Attr("discontinued", nil),
+		).
+		Node("apples", layupv1.Attr("count", 10))
+
+	m, err := b.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	layer := m.GetLayers()[0]
+	if !layer.GetDynamic() || layer.GetAttributes()["owner"].GetStringValue() != "alice" {
+		t.Fatalf("unexpected layer: %v", layer)
+	}
+
+	if len(layer.GetNodes()) != 1 {
+		t.Fatalf("expected nodes added more than once to be merged, got %d nodes", len(layer.GetNodes()))
+	}
+
+	attrs := layer.GetNodes()[0].GetAttributes()
+
+	if attrs["count"].GetNumberValue() != 10 {
+		t.Fatalf("expected later attributes to override earlier ones, got %v", attrs["count"])
+	}
+
+	if v := attrs["varieties"].GetListValue().AsSlice(); len(v) != 2 || v[1] != "fuji" {
+		t.Fatalf("unexpected varieties: %v", v)
+	}
+
+	if v := attrs["prices"].GetStructValue().AsMap(); v["gala"] != 0.5 {
+		t.Fatalf("unexpected prices: %v", v)
+	}
+
+	if v := attrs["supplier"].GetStructValue().AsMap(); v["name"] != "orchard" || len(v["ids"].([]any)) != 2 {
+		t.Fatalf("unexpected supplier: %v", v)
+	}
+
+	if attrs["discontinued"].GetKind() == nil || attrs["discontinued"].AsInterface() != nil {
+		t.Fatalf("unexpected discontinued: %v", attrs["discontinued"])
+	}
+}
+
+func TestBuilder_errors(t *testing.T) {
+	b := layupv1.NewBuilder("layup://recipe")
+
+	b.Layer("tools").
+		Node("bowl", layupv1.Attr("contents", make(chan int)), layupv1.Dynamic(true)).
+		Link("pour", "bowl", "pan").
+		Link("pour", "bowl", "bowl").
+		Link("stir", "spoon", "bowl").
+		Link("serve", "bowl", "table/plate").
+		Link("order", "bowl", "https://example.com/orders")
+
+	_, err := b.Build()
+	if err == nil {
+		t.Fatal("expected error")
+	}
+
+	for _, want := range []string{
+		`layup://recipe/layers/tools/nodes/bowl: attribute "contents": cannot encode chan int`,
+		`layup://recipe/layers/tools/nodes/bowl: only layers can be dynamic`,
+		`link "layup://recipe/layers/tools/links/pour" is added more than once`,
+		`layup://recipe/layers/tools/links/pour: unknown node "pan" within layer "tools"`,
+		`layup://recipe/layers/tools/links/stir: unknown node "spoon" within layer "tools"`,
+		`layup://recipe/layers/tools/links/serve: unknown layer "table"`,
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected error to contain %q, got:\n%v", want, err)
+		}
+	}
+
+	if strings.Contains(err.Error(), "order") {
+		t.Errorf("expected links to nodes outside of the model to be allowed, got:\n%v", err)
+	}
+}
@@ -0,0 +1,426 @@
+package layupv1
+
+import (
+	"container/heap"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"text/tabwriter"
+
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// DefaultDamping is the damping factor used by PageRank, which is the
+// probability of following a link, instead of jumping to a random node.
+const DefaultDamping = 0.85
+
+// Scores are the values of a metric (such as a centrality) for each node
+// within a graph.
+type Scores struct {
+	// Name is the name of the metric, which is used as the attribute key
+	// by SetNodeScores, and as the column name by WriteScoreTable.
+	Name string
+	// Values are the values of the metric, by canonical node URI.
+	Values map[string]float64
+}
+
+// Score is the value of a metric for a single node.
+type Score struct {
+	Node  string  `json:"node"`
+	Value float64 `json:"value"`
+}
+
+// Sorted returns the scores from the highest to the lowest value, with ties
+// ordered by node URI.
+func (s Scores) Sorted() []Score {
+	scores := make([]Score, 0, len(s.Values))
+	for node, value := range s.Values {
+		scores = append(scores, Score{Node: node, Value: value})
+	}
+
+	sort.Slice(scores, func(i, j int) bool {
+		if scores[i].Value != scores[j].Value {
+			return scores[i].Value > scores[j].Value
+		}
+		return scores[i].Node < scores[j].Node
+	})
+
+	return scores
+}
+
+// SetNodeScores writes the given scores back to the model as a numeric
+// attribute of each scored node, using the name of the scores as the key.
+func (g *Graph) SetNodeScores(s Scores) {
+	for uri, value := range s.Values {
+		n, ok := g.nodes[uri]
+		if !ok {
+			continue
+		}
+
+		if n.Attributes == nil {
+			n.Attributes = map[string]*structpb.Value{}
+		}
+		n.Attributes[s.Name] = structpb.NewNumberValue(value)
+	}
+}
+
+// WriteScoreTable writes the given scores as a plain text table to the given
+// writer, with a row for each node and a column for each of the scores. Rows
+// are sorted by the first scores, from the highest to the lowest value.
+func WriteScoreTable(w io.Writer, scores ...Scores) error {
+	if len(scores) == 0 {
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	fmt.Fprint(tw, "node")
+	for _, s := range scores {
+		fmt.Fprintf(tw, "\t%s", s.Name)
+	}
+	fmt.Fprintln(tw)
+
+	for _, row := range scores[0].Sorted() {
+		fmt.Fprint(tw, row.Node)
+		for _, s := range scores {
+			fmt.Fprintf(tw, "\t%s", formatScore(s.Values[row.Node]))
+		}
+		fmt.Fprintln(tw)
+	}
+
+	return tw.Flush()
+}
+
+// formatScore formats the given score, rounded to six decimal places.
+func formatScore(v float64) string {
+	return strconv.FormatFloat(math.Round(v*1e6)/1e6, 'f', -1, 64)
+}
+
+// adjacency is a compact view of the nodes and links of a graph which are
+// allowed by a traversal's options, used by the centrality algorithms.
+type adjacency struct {
+	nodes    []string
+	out      [][]arc
+	weighted bool
+}
+
+// arc is a link to the node with the given index within an adjacency.
+type arc struct {
+	to     int
+	weight float64
+}
+
+// newAdjacency returns the adjacency of the graph, for the given options.
+// Links to nodes outside of the model (or the allowed layers) are ignored.
+func (g *Graph) newAdjacency(c *traversalConfig) (*adjacency, error) {
+	nodes, index := g.allowedNodes(c)
+
+	a := &adjacency{
+		nodes:    nodes,
+		out:      make([][]arc, len(nodes)),
+		weighted: c.weight != "",
+	}
+
+	for i, uri := range nodes {
+		for _, s := range g.steps(uri, c) {
+			j, ok := index[s.node]
+			if !ok {
+				continue
+			}
+
+			w, err := c.linkWeight(s.edge)
+			if err != nil {
+				return nil, err
+			}
+
+			a.out[i] = append(a.out[i], arc{to: j, weight: w})
+		}
+	}
+
+	return a, nil
+}
+
+// scores returns the given values, by index, as Scores.
+func (a *adjacency) scores(name string, values []float64) Scores {
+	s := Scores{Name: name, Values: make(map[string]float64, len(values))}
+	for i, v := range values {
+		s.Values[a.nodes[i]] = v
+	}
+	return s
+}
+
+// shortestPaths returns the shortest paths from the given node, for
+// Brandes' algorithm: the nodes in order of non-decreasing distance, the
+// distance to each node (infinite if unreachable), the number of shortest
+// paths to each node, and the predecessors of each node on those paths.
+func (a *adjacency) shortestPaths(s int) (order []int, dist, sigma []float64, preds [][]int) {
+	n := len(a.nodes)
+
+	dist = make([]float64, n)
+	sigma = make([]float64, n)
+	preds = make([][]int, n)
+	for i := range dist {
+		dist[i] = math.Inf(1)
+	}
+
+	dist[s] = 0
+	sigma[s] = 1
+
+	if !a.weighted {
+		queue := []int{s}
+		for len(queue) > 0 {
+			v := queue[0]
+			queue = queue[1:]
+			order = append(order, v)
+
+			for _, e := range a.out[v] {
+				if math.IsInf(dist[e.to], 1) {
+					dist[e.to] = dist[v] + 1
+					queue = append(queue, e.to)
+				}
+				if dist[e.to] == dist[v]+1 {
+					sigma[e.to] += sigma[v]
+					preds[e.to] = append(preds[e.to], v)
+				}
+			}
+		}
+		return order, dist, sigma, preds
+	}
+
+	var (
+		done  = make([]bool, n)
+		queue = &arcQueue{{to: s}}
+	)
+
+	for queue.Len() > 0 {
+		v := heap.Pop(queue).(arc).to
+		if done[v] {
+			continue
+		}
+		done[v] = true
+		order = append(order, v)
+
+		for _, e := range a.out[v] {
+			d := dist[v] + e.weight
+			switch {
+			case d < dist[e.to]:
+				dist[e.to] = d
+				sigma[e.to] = sigma[v]
+				preds[e.to] = []int{v}
+				heap.Push(queue, arc{to: e.to, weight: d})
+			case d == dist[e.to] && !done[e.to]:
+				sigma[e.to] += sigma[v]
+				preds[e.to] = append(preds[e.to], v)
+			}
+		}
+	}
+
+	return order, dist, sigma, preds
+}
+
+// arcQueue is a priority queue of nodes by their distance (weight).
+type arcQueue []arc
+
+func (q arcQueue) Len() int { return len(q) }
+
+func (q arcQueue) Less(i, j int) bool {
+	if q[i].weight != q[j].weight {
+		return q[i].weight < q[j].weight
+	}
+	return q[i].to < q[j].to
+}
+
+func (q arcQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+
+func (q *arcQueue) Push(x any) { *q = append(*q, x.(arc)) }
+
+func (q *arcQueue) Pop() any {
+	old := *q
+	item := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return item
+}
+
+// DegreeCentrality returns the number of links coming from each node within
+// the model, or going to each node when following Incoming links, or both.
+// If WithWeight is given, the weights of the links are summed instead.
+//
+// The scores are named "out_degree", "in_degree" or "degree" depending on
+// the direction. The nodes and links can be restricted with WithLayers and
+// WithLinkFilter, and links to nodes outside of the model are ignored.
+func (g *Graph) DegreeCentrality(opts ...TraversalOption) (Scores, error) {
+	c := newTraversalConfig(opts)
+
+	a, err := g.newAdjacency(c)
+	if err != nil {
+		return Scores{}, err
+	}
+
+	values := make([]float64, len(a.nodes))
+	for i, arcs := range a.out {
+		for _, e := range arcs {
+			values[i] += e.weight
+		}
+	}
+
+	name := "degree"
+	switch c.direction {
+	case Outgoing:
+		name = "out_degree"
+	case Incoming:
+		name = "in_degree"
+	}
+
+	return a.scores(name, values), nil
+}
+
+// PageRank returns the PageRank of each node within the model, which is the
+// probability of ending up at the node when randomly following links, using
+// the DefaultDamping factor. If WithWeight is given, links with a higher
+// weight are more likely to be followed. The scores sum to one.
+//
+// The nodes and links can be restricted with WithLayers and WithLinkFilter,
+// and links to nodes outside of the model are ignored.
+func (g *Graph) PageRank(opts ...TraversalOption) (Scores, error) {
+	c := newTraversalConfig(opts)
+
+	a, err := g.newAdjacency(c)
+	if err != nil {
+		return Scores{}, err
+	}
+
+	n := len(a.nodes)
+	if n == 0 {
+		return a.scores("pagerank", nil), nil
+	}
+
+	outWeight := make([]float64, n)
+	for i, arcs := range a.out {
+		for _, e := range arcs {
+			outWeight[i] += e.weight
+		}
+	}
+
+	rank := make([]float64, n)
+	for i := range rank {
+		rank[i] = 1 / float64(n)
+	}
+
+	for iteration := 0; iteration < 100; iteration++ {
+		// Rank from nodes without links (or weights) is spread evenly.
+		var dangling float64
+		for i, r := range rank {
+			if outWeight[i] == 0 {
+				dangling += r
+			}
+		}
+
+		next := make([]float64, n)
+		for i := range next {
+			next[i] = (1-DefaultDamping)/float64(n) + DefaultDamping*dangling/float64(n)
+		}
+
+		for i, arcs := range a.out {
+			if outWeight[i] == 0 {
+				continue
+			}
+			for _, e := range arcs {
+				next[e.to] += DefaultDamping * rank[i] * e.weight / outWeight[i]
+			}
+		}
+
+		var delta float64
+		for i := range rank {
+			delta += math.Abs(next[i] - rank[i])
+		}
+
+		rank = next
+
+		if delta < 1e-10 {
+			break
+		}
+	}
+
+	return a.scores("pagerank", rank), nil
+}
+
+// BetweennessCentrality returns the fraction of shortest paths between
+// every other pair of nodes which pass through each node within the model,
+// using Brandes' algorithm. If WithWeight is given, the shortest paths are
+// the cheapest paths.
+//
+// The nodes and links can be restricted with WithLayers and WithLinkFilter,
+// and links to nodes outside of the model are ignored.
+func (g *Graph) BetweennessCentrality(opts ...TraversalOption) (Scores, error) {
+	c := newTraversalConfig(opts)
+
+	a, err := g.newAdjacency(c)
+	if err != nil {
+		return Scores{}, err
+	}
+
+	n := len(a.nodes)
+	values := make([]float64, n)
+
+	for s := 0; s < n; s++ {
+		order, _, sigma, preds := a.shortestPaths(s)
+
+		delta := make([]float64, n)
+		for i := len(order) - 1; i >= 0; i-- {
+			w := order[i]
+			for _, v := range preds[w] {
+				delta[v] += sigma[v] / sigma[w] * (1 + delta[w])
+			}
+			if w != s {
+				values[w] += delta[w]
+			}
+		}
+	}
+
+	// Normalize by the number of pairs of other nodes.
+	if n > 2 {
+		for i := range values {
+			values[i] /= float64((n - 1) * (n - 2))
+		}
+	}
+
+	return a.scores("betweenness", values), nil
+}
+
+// ClosenessCentrality returns how close each node within the model is to
+// the nodes it can reach, which is the inverse of the average distance to
+// them, scaled by the fraction of nodes it can reach (so nodes which can
+// only reach a few other nodes aren't considered central). If WithWeight is
+// given, the distances are the costs of the cheapest paths.
+//
+// The nodes and links can be restricted with WithLayers and WithLinkFilter,
+// and links to nodes outside of the model are ignored.
+func (g *Graph) ClosenessCentrality(opts ...TraversalOption) (Scores, error) {
+	c := newTraversalConfig(opts)
+
+	a, err := g.newAdjacency(c)
+	if err != nil {
+		return Scores{}, err
+	}
+
+	n := len(a.nodes)
+	values := make([]float64, n)
+
+	for s := 0; s < n; s++ {
+		order, dist, _, _ := a.shortestPaths(s)
+
+		var total float64
+		for _, v := range order {
+			total += dist[v]
+		}
+
+		reached := float64(len(order) - 1)
+		if total > 0 && n > 1 {
+			values[s] = (reached / total) * (reached / float64(n-1))
+		}
+	}
+
+	return a.scores("closeness", values), nil
+}
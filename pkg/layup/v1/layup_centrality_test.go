@@ -0,0 +1,187 @@
+package layupv1_test
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+
+	layupv1 "github.com/picatz/layup/pkg/layup/v1"
+)
+
+const testStar = `
+uri = "layup://test"
+
+layer "services" {
+	node "api" {}
+	node "auth" {}
+	node "db" {}
+	node "web" {}
+	node "worker" {}
+
+	link "web_api" {
+		from    = node.web
+		to      = node.api
+		latency = 1
+	}
+
+	link "api_auth" {
+		from    = node.api
+		to      = node.auth
+		latency = 1
+	}
+
+	link "api_db" {
+		from    = node.api
+		to      = node.db
+		latency = 2
+	}
+
+	link "worker_db" {
+		from    = node.worker
+		to      = node.db
+		latency = 2
+	}
+
+	link "auth_db" {
+		from    = node.auth
+		to      = node.db
+		latency = 5
+	}
+}
+`
+
+func service(id string) string {
+	return "layup://test/layers/services/nodes/" + id
+}
+
+func TestGraph_DegreeCentrality(t *testing.T) {
+	g := newTestGraph(t, testStar)
+
+	out, err := g.DegreeCentrality()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if out.Name != "out_degree" || out.Values[service("api")] != 2 || out.Values[service("db")] != 0 {
+		t.Fatalf("unexpected out degree: %v", out)
+	}
+
+	in, err := g.DegreeCentrality(layupv1.WithDirection(layupv1.Incoming))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if in.Name != "in_degree" || in.Values[service("db")] != 3 {
+		t.Fatalf("unexpected in degree: %v", in)
+	}
+
+	weighted, err := g.DegreeCentrality(layupv1.WithDirection(layupv1.Both), layupv1.WithWeight("latency"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if weighted.Values[service("db")] != 9 {
+		t.Fatalf("unexpected weighted degree: %v", weighted)
+	}
+}
+
+func TestGraph_PageRank(t *testing.T) {
+	g := newTestGraph(t, testStar)
+
+	pr, err := g.PageRank()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var total float64
+	for _, v := range pr.Values {
+		total += v
+	}
+
+	if math.Abs(total-1) > 1e-9 {
+		t.Fatalf("expected scores to sum to 1, got %v", total)
+	}
+
+	if top := pr.Sorted()[0]; top.Node != service("db") {
+		t.Fatalf("unexpected top node: %v", top)
+	}
+}
+
+func TestGraph_BetweennessCentrality(t *testing.T) {
+	g := newTestGraph(t, testStar)
+
+	b, err := g.BetweennessCentrality()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The api is on the shortest paths from web to auth and db.
+	if got := b.Values[service("api")]; got != 2.0/12 {
+		t.Fatalf("unexpected api betweenness: %v", got)
+	}
+
+	// With latency, web to db is cheapest through api directly, but
+	// auth is never on a shortest path either way.
+	bw, err := g.BetweennessCentrality(layupv1.WithWeight("latency"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if bw.Values[service("auth")] != 0 {
+		t.Fatalf("unexpected auth betweenness: %v", bw.Values[service("auth")])
+	}
+}
+
+func TestGraph_ClosenessCentrality(t *testing.T) {
+	g := newTestGraph(t, testStar)
+
+	c, err := g.ClosenessCentrality()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// api reaches auth and db at distance one: (2/2) * (2/4).
+	if got := c.Values[service("api")]; got != 0.5 {
+		t.Fatalf("unexpected api closeness: %v", got)
+	}
+
+	if got := c.Values[service("db")]; got != 0 {
+		t.Fatalf("unexpected db closeness: %v", got)
+	}
+}
+
+func TestWriteScoreTable(t *testing.T) {
+	g := newTestGraph(t, testStar)
+
+	in, err := g.DegreeCentrality(layupv1.WithDirection(layupv1.Incoming))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pr, err := g.PageRank()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+
+	if err := layupv1.WriteScoreTable(&buf, in, pr); err != nil {
+		t.Fatal(err)
+	}
+
+	fmt.Println(buf.String())
+
+	lines := strings.Split(buf.String(), "\n")
+	if !strings.HasPrefix(lines[0], "node") || !strings.HasPrefix(lines[1], service("db")) {
+		t.Fatalf("unexpected table:\n%s", buf.String())
+	}
+
+	g.SetNodeScores(pr)
+
+	n, _ := g.Node(service("db"))
+	if n.GetAttributes()["pagerank"].GetNumberValue() != pr.Values[service("db")] {
+		t.Fatalf("unexpected attributes: %v", n.GetAttributes())
+	}
+}
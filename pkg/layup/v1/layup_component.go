@@ -0,0 +1,241 @@
+package layupv1
+
+import (
+	"slices"
+)
+
+// Components describes how the nodes of a graph are connected to each
+// other, to find the parts of a model which are disconnected.
+type Components struct {
+	// Weak are the weakly connected components of the graph.
+	Weak [][]string `json:"weak"`
+	// Strong are the strongly connected components of the graph.
+	Strong [][]string `json:"strong"`
+	// Isolated are the canonical URIs of the nodes without any links.
+	Isolated []string `json:"isolated"`
+	// UnreachableLayers are the IDs of the layers without any links to or
+	// from the other layers.
+	UnreachableLayers []string `json:"unreachable_layers"`
+}
+
+// Components returns the weakly and strongly connected components of the
+// graph, along with its isolated nodes and unreachable layers.
+func (g *Graph) Components(opts ...TraversalOption) *Components {
+	return &Components{
+		Weak:              nonNil(g.WeaklyConnectedComponents(opts...)),
+		Strong:            nonNil(g.StronglyConnectedComponents(opts...)),
+		Isolated:          nonNil(g.IsolatedNodes(opts...)),
+		UnreachableLayers: nonNil(g.UnreachableLayers(opts...)),
+	}
+}
+
+// nonNil returns the given slice, or an empty slice if it is nil, so it is
+// encoded as an empty JSON array instead of null.
+func nonNil[T any](s []T) []T {
+	if s == nil {
+		return []T{}
+	}
+	return s
+}
+
+// WeaklyConnectedComponents returns the groups of nodes within the model
+// which are connected to each other by links in either direction, across
+// all layers. Each component is a list of canonical node URIs in model
+// order, and components are ordered by their first node.
+//
+// The nodes and links can be restricted with WithLayers and WithLinkFilter.
+// The direction links are followed in is ignored.
+func (g *Graph) WeaklyConnectedComponents(opts ...TraversalOption) [][]string {
+	c := newTraversalConfig(opts)
+	c.direction = Both
+
+	nodes, index := g.allowedNodes(c)
+
+	var (
+		components [][]string
+		seen       = make([]bool, len(nodes))
+	)
+
+	for i, uri := range nodes {
+		if seen[i] {
+			continue
+		}
+		seen[i] = true
+
+		var (
+			component = []string{uri}
+			queue     = []string{uri}
+		)
+
+		for len(queue) > 0 {
+			node := queue[0]
+			queue = queue[1:]
+
+			for _, s := range g.steps(node, c) {
+				j, ok := index[s.node]
+				if !ok || seen[j] {
+					continue
+				}
+				seen[j] = true
+
+				component = append(component, s.node)
+				queue = append(queue, s.node)
+			}
+		}
+
+		sortByIndex(component, index)
+		components = append(components, component)
+	}
+
+	return components
+}
+
+// StronglyConnectedComponents returns the groups of nodes within the model
+// which can all reach each other by following links, found using Tarjan's
+// algorithm. Nodes which aren't part of a cycle are a component of their
+// own. Each component is a list of canonical node URIs in model order, and
+// components are ordered by their first node.
+//
+// The nodes and links can be restricted with WithLayers and WithLinkFilter.
+func (g *Graph) StronglyConnectedComponents(opts ...TraversalOption) [][]string {
+	c := newTraversalConfig(opts)
+
+	nodes, index := g.allowedNodes(c)
+
+	var (
+		components [][]string
+		counter    int
+		indices    = map[string]int{}
+		lowlinks   = map[string]int{}
+		onStack    = map[string]bool{}
+		stack      []string
+	)
+
+	var connect func(node string)
+	connect = func(node string) {
+		indices[node] = counter
+		lowlinks[node] = counter
+		counter++
+
+		stack = append(stack, node)
+		onStack[node] = true
+
+		for _, s := range g.steps(node, c) {
+			if _, ok := index[s.node]; !ok {
+				continue
+			}
+
+			if _, ok := indices[s.node]; !ok {
+				connect(s.node)
+				lowlinks[node] = min(lowlinks[node], lowlinks[s.node])
+			} else if onStack[s.node] {
+				lowlinks[node] = min(lowlinks[node], indices[s.node])
+			}
+		}
+
+		if lowlinks[node] != indices[node] {
+			return
+		}
+
+		var component []string
+		for {
+			top := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[top] = false
+
+			component = append(component, top)
+
+			if top == node {
+				break
+			}
+		}
+
+		sortByIndex(component, index)
+		components = append(components, component)
+	}
+
+	for _, uri := range nodes {
+		if _, ok := indices[uri]; !ok {
+			connect(uri)
+		}
+	}
+
+	slices.SortFunc(components, func(a, b []string) int {
+		return index[a[0]] - index[b[0]]
+	})
+
+	return components
+}
+
+// IsolatedNodes returns the canonical URIs of the nodes within the model
+// which don't have any links going to or from them, in model order.
+//
+// The nodes and links can be restricted with WithLayers and WithLinkFilter.
+// The direction links are followed in is ignored.
+func (g *Graph) IsolatedNodes(opts ...TraversalOption) []string {
+	c := newTraversalConfig(opts)
+	c.direction = Both
+
+	nodes, _ := g.allowedNodes(c)
+
+	var isolated []string
+	for _, uri := range nodes {
+		if len(g.steps(uri, c)) == 0 {
+			isolated = append(isolated, uri)
+		}
+	}
+
+	return isolated
+}
+
+// UnreachableLayers returns the IDs of the layers which don't have any links
+// going to or from the nodes in the other layers, so they can't be reached
+// from the rest of the model, in model order. Models with a single layer
+// don't have any unreachable layers.
+//
+// The layers and links can be restricted with WithLayers and WithLinkFilter.
+// The direction links are followed in is ignored.
+func (g *Graph) UnreachableLayers(opts ...TraversalOption) []string {
+	c := newTraversalConfig(opts)
+	c.direction = Both
+
+	var layers []*Layer
+	for _, layer := range g.Layers() {
+		if _, ok := c.layers[layer.GetId()]; c.layers == nil || ok {
+			layers = append(layers, layer)
+		}
+	}
+
+	if len(layers) < 2 {
+		return nil
+	}
+
+	var unreachable []string
+
+	for _, layer := range layers {
+		reachable := false
+
+	nodes:
+		for _, uri := range g.LayerNodes(layer.GetId()) {
+			for _, s := range g.steps(uri, c) {
+				if other, ok := g.NodeLayer(s.node); ok && other != layer {
+					reachable = true
+					break nodes
+				}
+			}
+		}
+
+		if !reachable {
+			unreachable = append(unreachable, layer.GetId())
+		}
+	}
+
+	return unreachable
+}
+
+// sortByIndex sorts the given node URIs by their index.
+func sortByIndex(nodes []string, index map[string]int) {
+	slices.SortFunc(nodes, func(a, b string) int {
+		return index[a] - index[b]
+	})
+}
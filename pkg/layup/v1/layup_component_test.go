@@ -0,0 +1,140 @@
+package layupv1_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+
+	layupv1 "github.com/picatz/layup/pkg/layup/v1"
+)
+
+const testComponents = `
+uri = "layup://test"
+
+layer "one" {
+	node "a" {}
+	node "b" {}
+	node "c" {}
+	node "lonely" {}
+
+	link "ab" {
+		from = node.a
+		to   = node.b
+	}
+
+	link "ba" {
+		from = node.b
+		to   = node.a
+	}
+
+	link "bc" {
+		from = node.b
+		to   = node.c
+	}
+}
+
+layer "two" {
+	node "d" {}
+	node "e" {}
+
+	link "de" {
+		from = node.d
+		to   = node.e
+	}
+}
+`
+
+func TestGraph_Components(t *testing.T) {
+	g := newTestGraph(t, testComponents)
+
+	uri := func(layer, node string) string {
+		return "layup://test/layers/" + layer + "/nodes/" + node
+	}
+
+	weak := g.WeaklyConnectedComponents()
+	if !slices.EqualFunc(weak, [][]string{
+		{uri("one", "a"), uri("one", "b"), uri("one", "c")},
+		{uri("one", "lonely")},
+		{uri("two", "d"), uri("two", "e")},
+	}, slices.Equal) {
+		t.Fatalf("unexpected weak components: %v", weak)
+	}
+
+	strong := g.StronglyConnectedComponents()
+	if !slices.EqualFunc(strong, [][]string{
+		{uri("one", "a"), uri("one", "b")},
+		{uri("one", "c")},
+		{uri("one", "lonely")},
+		{uri("two", "d")},
+		{uri("two", "e")},
+	}, slices.Equal) {
+		t.Fatalf("unexpected strong components: %v", strong)
+	}
+
+	if isolated := g.IsolatedNodes(); !slices.Equal(isolated, []string{uri("one", "lonely")}) {
+		t.Fatalf("unexpected isolated nodes: %v", isolated)
+	}
+
+	if layers := g.UnreachableLayers(); !slices.Equal(layers, []string{"one", "two"}) {
+		t.Fatalf("unexpected unreachable layers: %v", layers)
+	}
+
+	t.Run("this project", func(t *testing.T) {
+		g := newTestGraph(t, thisProject)
+
+		components := g.Components()
+
+		if len(components.Weak) != 1 {
+			t.Fatalf("unexpected weak components: %v", components.Weak)
+		}
+
+		if len(components.Isolated) != 0 || len(components.UnreachableLayers) != 0 {
+			t.Fatalf("unexpected components: %+v", components)
+		}
+
+		b, err := json.Marshal(components)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !strings.Contains(string(b), `"isolated":[]`) {
+			t.Fatalf("unexpected JSON: %s", b)
+		}
+	})
+}
+
+func TestWriteDOT_colors(t *testing.T) {
+	m, err := layupv1.ParseHCL(strings.NewReader(testComponents))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	g, err := layupv1.NewGraph(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	colors := layupv1.GroupColors(g.WeaklyConnectedComponents())
+
+	var buf bytes.Buffer
+
+	err = layupv1.WriteDOT(&buf, m,
+		layupv1.WithNodeColors(colors),
+		layupv1.WithLinkColors(map[string]string{"layup://test/layers/two/links/de": "red"}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{
+		`fillcolor="` + layupv1.DefaultPalette[0] + `"`,
+		`fillcolor="` + layupv1.DefaultPalette[2] + `"`,
+		`two_d -> two_e [label="de" color="red"]`,
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Fatalf("expected %q in:\n%s", want, buf.String())
+		}
+	}
+}
@@ -0,0 +1,438 @@
+package layupv1
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// Reserved CSV columns, any other column is treated as an attribute.
+var (
+	csvNodeColumns = []string{"layer", "id"}
+	csvLinkColumns = []string{"layer", "id", "from", "to"}
+)
+
+// csvTable is a CSV file with a header row, which maps each of the reserved
+// columns to their index, and every other column to an attribute.
+//
+// The records are read up front, so the type of each attribute column can
+// be inferred from all of its cells.
+type csvTable struct {
+	columns map[string]int
+	attrs   map[int]string
+	kinds   map[int]csvKind
+	records [][]string
+	lines   []int
+}
+
+func newCSVTable(name string, r io.Reader, reserved []string) (*csvTable, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = 0
+
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s CSV header: %w", name, err)
+	}
+
+	t := &csvTable{
+		columns: map[string]int{},
+		attrs:   map[int]string{},
+		kinds:   map[int]csvKind{},
+	}
+
+	for i, column := range header {
+		column = strings.TrimSpace(column)
+
+		if column == "" {
+			return nil, fmt.Errorf("%s CSV column %d has an empty name", name, i+1)
+		}
+
+		if _, ok := t.columns[column]; ok {
+			return nil, fmt.Errorf("%s CSV column %q is duplicated", name, column)
+		}
+
+		t.columns[column] = i
+	}
+
+	for _, column := range reserved {
+		if _, ok := t.columns[column]; !ok {
+			return nil, fmt.Errorf("%s CSV is missing required column %q", name, column)
+		}
+	}
+
+	for column, i := range t.columns {
+		if !slices.Contains(reserved, column) {
+			t.attrs[i] = column
+		}
+	}
+
+	for {
+		record, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s CSV: %w", name, err)
+		}
+
+		line, _ := cr.FieldPos(0)
+
+		t.records = append(t.records, record)
+		t.lines = append(t.lines, line)
+	}
+
+	for i := range t.attrs {
+		cells := make([]string, 0, len(t.records))
+		for _, record := range t.records {
+			cells = append(cells, record[i])
+		}
+
+		t.kinds[i] = inferCSVKind(cells)
+	}
+
+	return t, nil
+}
+
+// get returns the value of the given reserved column in the record.
+func (t *csvTable) get(record []string, column string) string {
+	return strings.TrimSpace(record[t.columns[column]])
+}
+
+// attributes returns the attributes of the record, omitting empty (or
+// whitespace only) cells.
+func (t *csvTable) attributes(record []string) map[string]*structpb.Value {
+	attrs := map[string]*structpb.Value{}
+
+	for i, key := range t.attrs {
+		if strings.TrimSpace(record[i]) == "" {
+			continue
+		}
+
+		attrs[key] = parseCSVValue(t.kinds[i], record[i])
+	}
+
+	return attrs
+}
+
+// csvKind is the type inferred for an attribute column.
+type csvKind int
+
+const (
+	csvString csvKind = iota
+	csvBool
+	csvNumber
+	csvJSON
+)
+
+// inferCSVKind infers the type of an attribute column from its cells,
+// ignoring empty (or whitespace only) ones. Booleans, numbers, and JSON
+// lists or objects are only inferred if every cell is one, so a column
+// has a single type, falling back to strings.
+func inferCSVKind(cells []string) csvKind {
+	for _, kind := range []csvKind{csvBool, csvNumber, csvJSON} {
+		matched := false
+
+		for _, cell := range cells {
+			cell = strings.TrimSpace(cell)
+			if cell == "" {
+				continue
+			}
+
+			if !isCSVKind(kind, cell) {
+				matched = false
+				break
+			}
+
+			matched = true
+		}
+
+		if matched {
+			return kind
+		}
+	}
+
+	return csvString
+}
+
+// isCSVKind reports whether the trimmed cell is a value of the given kind.
+func isCSVKind(kind csvKind, s string) bool {
+	switch kind {
+	case csvBool:
+		_, ok := parseCSVBool(s)
+		return ok
+	case csvNumber:
+		_, ok := parseCSVNumber(s)
+		return ok
+	case csvJSON:
+		_, ok := parseCSVJSON(s)
+		return ok
+	default:
+		return true
+	}
+}
+
+// parseCSVValue converts a CSV cell into an attribute value of the kind
+// inferred for its column.
+func parseCSVValue(kind csvKind, s string) *structpb.Value {
+	trimmed := strings.TrimSpace(s)
+
+	switch kind {
+	case csvBool:
+		if b, ok := parseCSVBool(trimmed); ok {
+			return structpb.NewBoolValue(b)
+		}
+	case csvNumber:
+		if f, ok := parseCSVNumber(trimmed); ok {
+			return structpb.NewNumberValue(f)
+		}
+	case csvJSON:
+		if v, ok := parseCSVJSON(trimmed); ok {
+			return v
+		}
+	}
+
+	return structpb.NewStringValue(trimmed)
+}
+
+func parseCSVBool(s string) (bool, bool) {
+	switch strings.ToLower(s) {
+	case "true":
+		return true, true
+	case "false":
+		return false, true
+	}
+	return false, false
+}
+
+func parseCSVNumber(s string) (float64, bool) {
+	// Numbers with leading zeros, like ZIP codes or IDs, are kept as
+	// strings to avoid losing information.
+	if len(s) > 1 && s[0] == '0' && s[1] != '.' {
+		return 0, false
+	}
+
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsInf(f, 0) || math.IsNaN(f) {
+		return 0, false
+	}
+
+	return f, true
+}
+
+func parseCSVJSON(s string) (*structpb.Value, bool) {
+	if !strings.HasPrefix(s, "[") && !strings.HasPrefix(s, "{") {
+		return nil, false
+	}
+
+	v := &structpb.Value{}
+	if err := protojson.Unmarshal([]byte(s), v); err != nil {
+		return nil, false
+	}
+
+	return v, true
+}
+
+// ReadCSV reads a model with the given URI from a nodes CSV file with
+// "layer" and "id" columns, and a links CSV file with "layer", "id", "from"
+// and "to" columns. Every other column is an attribute, with numeric,
+// boolean and JSON values inferred from the column's contents, and empty
+// cells omitted.
+//
+// Layers are created in the order they first appear, and either reader
+// may be nil if there are no nodes or links. The model is validated
+// before it is returned.
+func ReadCSV(uri string, nodes, links io.Reader) (*Model, error) {
+	m := &Model{
+		Uri: uri,
+	}
+
+	layers := map[string]*Layer{}
+
+	getLayer := func(id string) *Layer {
+		layer, ok := layers[id]
+		if !ok {
+			layer = &Layer{Id: id}
+			layers[id] = layer
+			m.Layers = append(m.Layers, layer)
+		}
+		return layer
+	}
+
+	if nodes != nil {
+		t, err := newCSVTable("nodes", nodes, csvNodeColumns)
+		if err != nil {
+			return nil, err
+		}
+
+		for i, record := range t.records {
+			line := t.lines[i]
+
+			layerID, nodeID := t.get(record, "layer"), t.get(record, "id")
+			if layerID == "" || nodeID == "" {
+				return nil, fmt.Errorf("nodes CSV line %d: layer and id are required", line)
+			}
+
+			layer := getLayer(layerID)
+			layer.Nodes = append(layer.Nodes, &Node{
+				Id:         nodeID,
+				Attributes: t.attributes(record),
+			})
+		}
+	}
+
+	if links != nil {
+		t, err := newCSVTable("links", links, csvLinkColumns)
+		if err != nil {
+			return nil, err
+		}
+
+		for i, record := range t.records {
+			line := t.lines[i]
+
+			link := &Link{
+				Id:         t.get(record, "id"),
+				From:       t.get(record, "from"),
+				To:         t.get(record, "to"),
+				Attributes: t.attributes(record),
+			}
+
+			layerID := t.get(record, "layer")
+			if layerID == "" || link.Id == "" || link.From == "" || link.To == "" {
+				return nil, fmt.Errorf("links CSV line %d: layer, id, from and to are required", line)
+			}
+
+			layer := getLayer(layerID)
+			layer.Links = append(layer.Links, link)
+		}
+	}
+
+	// Apply validation rules to the model after reading to ensure
+	// invalid models are not created from CSV files.
+	if err := Validate(m); err != nil {
+		return m, err
+	}
+
+	return m, nil
+}
+
+// formatCSVValue converts an attribute value into a CSV cell, which can
+// be inferred back into the same value by ReadCSV.
+func formatCSVValue(v *structpb.Value) (string, error) {
+	switch kind := v.GetKind().(type) {
+	case *structpb.Value_StringValue:
+		return kind.StringValue, nil
+	case *structpb.Value_NumberValue:
+		return strconv.FormatFloat(kind.NumberValue, 'f', -1, 64), nil
+	case *structpb.Value_BoolValue:
+		return strconv.FormatBool(kind.BoolValue), nil
+	case *structpb.Value_NullValue, nil:
+		return "", nil
+	default:
+		b, err := json.Marshal(v.AsInterface())
+		if err != nil {
+			return "", err
+		}
+		return string(b), nil
+	}
+}
+
+// writeCSVTable writes a CSV file with the given reserved columns, followed
+// by a column for every attribute key (in sorted order).
+func writeCSVTable(name string, w io.Writer, reserved []string, rows [][]string, attrs []map[string]*structpb.Value) error {
+	keys := map[string]struct{}{}
+	for _, a := range attrs {
+		for k := range a {
+			if slices.Contains(reserved, k) {
+				return fmt.Errorf("%s CSV attribute %q conflicts with a reserved column", name, k)
+			}
+			keys[k] = struct{}{}
+		}
+	}
+
+	columns := sortedKeys(keys)
+
+	cw := csv.NewWriter(w)
+
+	if err := cw.Write(append(append([]string{}, reserved...), columns...)); err != nil {
+		return err
+	}
+
+	for i, row := range rows {
+		for _, k := range columns {
+			var cell string
+			if v, ok := attrs[i][k]; ok {
+				var err error
+				cell, err = formatCSVValue(v)
+				if err != nil {
+					return fmt.Errorf("failed to format %s CSV attribute %q: %w", name, k, err)
+				}
+			}
+			row = append(row, cell)
+		}
+
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+
+	return cw.Error()
+}
+
+// WriteCSV writes the given Layup model's nodes and links to the given
+// writers as CSV files which can be read back with ReadCSV. Either writer
+// may be nil to skip writing nodes or links.
+//
+// Layer and model attributes have no representation in the CSV files, and
+// string attributes which look like other types (e.g. "true") will be
+// inferred as those types when read back, if every other value in their
+// column does too.
+func WriteCSV(nodes, links io.Writer, m *Model) error {
+	if nodes != nil {
+		var (
+			rows  [][]string
+			attrs []map[string]*structpb.Value
+		)
+
+		for _, layer := range m.GetLayers() {
+			for _, n := range layer.GetNodes() {
+				rows = append(rows, []string{layer.GetId(), n.GetId()})
+				attrs = append(attrs, n.GetAttributes())
+			}
+		}
+
+		if err := writeCSVTable("nodes", nodes, csvNodeColumns, rows, attrs); err != nil {
+			return err
+		}
+	}
+
+	if links != nil {
+		var (
+			rows  [][]string
+			attrs []map[string]*structpb.Value
+		)
+
+		for _, layer := range m.GetLayers() {
+			for _, link := range layer.GetLinks() {
+				rows = append(rows, []string{layer.GetId(), link.GetId(), link.GetFrom(), link.GetTo()})
+				attrs = append(attrs, link.GetAttributes())
+			}
+		}
+
+		if err := writeCSVTable("links", links, csvLinkColumns, rows, attrs); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
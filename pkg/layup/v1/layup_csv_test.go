@@ -0,0 +1,153 @@
+package layupv1_test
+
+import (
+	"strings"
+	"testing"
+
+	layupv1 "github.com/picatz/layup/pkg/layup/v1"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestReadCSV(t *testing.T) {
+	nodes := `layer,id,material,count,fragile,zip,tags
+tools,bowl,glass,1,true,02134,"[""kitchen"",""mixing""]"
+tools,spoon,wood,,false,,
+ingredients,flour,,2.5,,,
+`
+
+	links := `layer,id,from,to,until
+tools,mixup,spoon,bowl,smooth
+ingredients,add_flour,flour,layup://cake/layers/tools/nodes/bowl,
+`
+
+	m, err := layupv1.ReadCSV("layup://cake", strings.NewReader(nodes), strings.NewReader(links))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(m.GetLayers()) != 2 {
+		t.Fatalf("expected 2 layers, got %d", len(m.GetLayers()))
+	}
+
+	bowl := m.GetLayers()[0].GetNodes()[0]
+
+	if got := bowl.GetAttributes()["material"].GetStringValue(); got != "glass" {
+		t.Fatalf("unexpected material: %q", got)
+	}
+
+	if got := bowl.GetAttributes()["count"].GetNumberValue(); got != 1 {
+		t.Fatalf("unexpected count: %v", got)
+	}
+
+	if got := bowl.GetAttributes()["fragile"].GetBoolValue(); !got {
+		t.Fatalf("unexpected fragile: %v", got)
+	}
+
+	if got := bowl.GetAttributes()["zip"].GetStringValue(); got != "02134" {
+		t.Fatalf("unexpected zip: %q", got)
+	}
+
+	if got := len(bowl.GetAttributes()["tags"].GetListValue().GetValues()); got != 2 {
+		t.Fatalf("unexpected tags: %v", bowl.GetAttributes()["tags"])
+	}
+
+	spoon := m.GetLayers()[0].GetNodes()[1]
+	if _, ok := spoon.GetAttributes()["count"]; ok {
+		t.Fatal("expected empty cell to be omitted")
+	}
+
+	mixup := m.GetLayers()[0].GetLinks()[0]
+	if got := mixup.GetAttributes()["until"].GetStringValue(); got != "smooth" {
+		t.Fatalf("unexpected until: %q", got)
+	}
+
+	t.Run("round trip", func(t *testing.T) {
+		var nodesBuffer, linksBuffer strings.Builder
+
+		if err := layupv1.WriteCSV(&nodesBuffer, &linksBuffer, m); err != nil {
+			t.Fatal(err)
+		}
+
+		m2, err := layupv1.ReadCSV("layup://cake", strings.NewReader(nodesBuffer.String()), strings.NewReader(linksBuffer.String()))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !proto.Equal(m, m2) {
+			t.Fatalf("expected models to be equal:\n%v\n%v", m, m2)
+		}
+	})
+
+	t.Run("column types", func(t *testing.T) {
+		m, err := layupv1.ReadCSV("layup://cake", strings.NewReader("layer,id,size,fragile,note,count\ntools,bowl,1, true , glass ,2\ntools,pan,large,false,, \n"), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		bowl, pan := m.GetLayers()[0].GetNodes()[0], m.GetLayers()[0].GetNodes()[1]
+
+		if got := bowl.GetAttributes()["size"].GetStringValue(); got != "1" {
+			t.Fatalf("expected size in a mixed column to be a string, got %v", bowl.GetAttributes()["size"])
+		}
+
+		if got := pan.GetAttributes()["size"].GetStringValue(); got != "large" {
+			t.Fatalf("unexpected size: %v", pan.GetAttributes()["size"])
+		}
+
+		if got, ok := bowl.GetAttributes()["fragile"].GetKind().(*structpb.Value_BoolValue); !ok || !got.BoolValue {
+			t.Fatalf("unexpected fragile: %v", bowl.GetAttributes()["fragile"])
+		}
+
+		if got := bowl.GetAttributes()["note"].GetStringValue(); got != "glass" {
+			t.Fatalf("expected note to be trimmed, got %q", got)
+		}
+
+		if got, ok := bowl.GetAttributes()["count"].GetKind().(*structpb.Value_NumberValue); !ok || got.NumberValue != 2 {
+			t.Fatalf("expected count with a blank cell to be a number, got %v", bowl.GetAttributes()["count"])
+		}
+
+		if _, ok := pan.GetAttributes()["count"]; ok {
+			t.Fatalf("expected blank cell to be omitted, got %v", pan.GetAttributes()["count"])
+		}
+	})
+
+	t.Run("missing column", func(t *testing.T) {
+		_, err := layupv1.ReadCSV("layup://cake", strings.NewReader("layer,name\ntools,bowl\n"), nil)
+		if err == nil {
+			t.Fatal("expected error, got nil")
+		}
+
+		t.Log(err)
+	})
+
+	t.Run("invalid model", func(t *testing.T) {
+		_, err := layupv1.ReadCSV("layup://cake", nil, strings.NewReader("layer,id,from,to\ntools,pour,bowl,pan\n"))
+		if err == nil {
+			t.Fatal("expected error, got nil")
+		}
+
+		t.Log(err)
+	})
+}
+
+func TestWriteCSV(t *testing.T) {
+	model, err := layupv1.ParseHCL(strings.NewReader(thisProject))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var nodesBuffer, linksBuffer strings.Builder
+
+	if err := layupv1.WriteCSV(&nodesBuffer, &linksBuffer, model); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.HasPrefix(nodesBuffer.String(), "layer,id,url\ngithub,my_account,https://github.com/picatz\n") {
+		t.Fatalf("unexpected nodes CSV:\n%s", nodesBuffer.String())
+	}
+
+	if !strings.Contains(linksBuffer.String(), "buf,uses,cli,layup://example/layers/go/nodes/runtime\n") {
+		t.Fatalf("unexpected links CSV:\n%s", linksBuffer.String())
+	}
+}
@@ -0,0 +1,411 @@
+package layupv1
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// AcyclicAttribute is the boolean model or layer attribute which requires
+// the model (or the links within the layer) to not contain any cycles, which
+// is checked by Validate:
+//
+//	uri     = "layup://build"
+//	acyclic = true
+const AcyclicAttribute = "acyclic"
+
+// maxReportedCycles is the maximum number of cycles reported by a
+// CycleError, since there can be a very large number of them.
+const maxReportedCycles = 10
+
+// Cycle is a sequence of nodes connected by links which leads back to the
+// node it started from.
+type Cycle struct {
+	// Nodes are the canonical URIs of the nodes in the cycle, without
+	// repeating the first node at the end.
+	Nodes []string `json:"nodes"`
+	// Links are the canonical URIs of the links between each of the nodes,
+	// where the last link goes back to the first node.
+	Links []string `json:"links"`
+}
+
+// String returns the nodes of the cycle, separated by arrows, ending with
+// the node it started from.
+func (c Cycle) String() string {
+	return strings.Join(append(slices.Clone(c.Nodes), c.Nodes[0]), " -> ")
+}
+
+// CycleError is returned when a graph contains cycles, but must not.
+type CycleError struct {
+	// Cycles are (some of) the cycles within the graph, with one cycle
+	// for each group of nodes which are all reachable from one another
+	// (strongly connected component).
+	Cycles []Cycle
+}
+
+// Error returns a description of the first cycle in the graph.
+func (e *CycleError) Error() string {
+	if len(e.Cycles) == 0 {
+		return "graph contains a cycle"
+	}
+
+	msg := "graph contains a cycle: " + e.Cycles[0].String()
+	if len(e.Cycles) > 1 {
+		msg += fmt.Sprintf(" (and %d more)", len(e.Cycles)-1)
+	}
+
+	return msg
+}
+
+// Cycles returns the elementary cycles within the graph (which never visit
+// the same node twice), in model order, following the configured links.
+//
+// Since there can be a very large number of cycles, they can be limited by
+// length with WithMaxDepth, and by number with WithMaxPaths.
+func (g *Graph) Cycles(opts ...TraversalOption) []Cycle {
+	c := newTraversalConfig(opts)
+
+	nodes, index := g.allowedNodes(c)
+
+	// Cycles never leave a strongly connected component, so only the
+	// nodes within the start's component are walked, and nodes which
+	// aren't part of any cycle are never walked at all.
+	component, cyclic := g.stronglyConnected(nodes, index, c)
+
+	var (
+		cycles  []Cycle
+		current Cycle
+		onPath  = map[string]struct{}{}
+		done    = errors.New("done")
+	)
+
+	// Each cycle is found exactly once, starting from its earliest node,
+	// by only walking through the nodes which come after it.
+	var walk func(start int, node string) error
+	walk = func(start int, node string) error {
+		if c.maxDepth >= 0 && len(current.Links) >= c.maxDepth {
+			return nil
+		}
+
+		for _, s := range g.steps(node, c) {
+			i, ok := index[s.node]
+			if !ok || i < start || component[i] != component[start] {
+				continue
+			}
+
+			if i == start {
+				cycles = append(cycles, Cycle{
+					Nodes: slices.Clone(current.Nodes),
+					Links: append(slices.Clone(current.Links), s.edge.URI),
+				})
+				if c.maxPaths >= 0 && len(cycles) >= c.maxPaths {
+					return done
+				}
+				continue
+			}
+
+			if _, ok := onPath[s.node]; ok {
+				continue
+			}
+
+			onPath[s.node] = struct{}{}
+			current.Nodes = append(current.Nodes, s.node)
+			current.Links = append(current.Links, s.edge.URI)
+
+			if err := walk(start, s.node); err != nil {
+				return err
+			}
+
+			delete(onPath, s.node)
+			current.Nodes = current.Nodes[:len(current.Nodes)-1]
+			current.Links = current.Links[:len(current.Links)-1]
+		}
+
+		return nil
+	}
+
+	for i, uri := range nodes {
+		if !cyclic[component[i]] {
+			continue
+		}
+
+		current = Cycle{Nodes: []string{uri}}
+		onPath = map[string]struct{}{uri: {}}
+
+		if err := walk(i, uri); err != nil {
+			break
+		}
+	}
+
+	return cycles
+}
+
+// stronglyConnected finds the strongly connected components of the given
+// nodes using Tarjan's algorithm, returning the component of each node (by
+// its index in nodes), and whether each component contains a cycle, which
+// is when it has more than one node, or a node with a link to itself.
+func (g *Graph) stronglyConnected(nodes []string, index map[string]int, c *traversalConfig) (component []int, cyclic []bool) {
+	var (
+		next     int
+		order    = make([]int, len(nodes))
+		low      = make([]int, len(nodes))
+		onStack  = make([]bool, len(nodes))
+		selfLoop = make([]bool, len(nodes))
+		stack    []int
+	)
+
+	component = make([]int, len(nodes))
+
+	var connect func(v int)
+	connect = func(v int) {
+		// Orders start from 1, so 0 means the node hasn't been visited.
+		next++
+		order[v], low[v] = next, next
+		stack = append(stack, v)
+		onStack[v] = true
+
+		for _, s := range g.steps(nodes[v], c) {
+			w, ok := index[s.node]
+			if !ok {
+				continue
+			}
+
+			switch {
+			case w == v:
+				selfLoop[v] = true
+			case order[w] == 0:
+				connect(w)
+				low[v] = min(low[v], low[w])
+			case onStack[w]:
+				low[v] = min(low[v], order[w])
+			}
+		}
+
+		if low[v] != order[v] {
+			return
+		}
+
+		id, size := len(cyclic), 0
+		for {
+			w := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[w] = false
+			component[w] = id
+			size++
+
+			if w == v {
+				break
+			}
+		}
+
+		cyclic = append(cyclic, size > 1 || selfLoop[v])
+	}
+
+	for v := range nodes {
+		if order[v] == 0 {
+			connect(v)
+		}
+	}
+
+	return component, cyclic
+}
+
+// componentCycles returns a single cycle within each strongly connected
+// component which contains one, in model order, up to the given limit.
+//
+// Unlike Cycles, which can take exponential time to find every cycle, each
+// cycle is the shortest one through the earliest node of its component,
+// found by a breadth-first search, so this is linear in the size of the
+// graph.
+func (g *Graph) componentCycles(c *traversalConfig, limit int) []Cycle {
+	nodes, index := g.allowedNodes(c)
+
+	component, cyclic := g.stronglyConnected(nodes, index, c)
+
+	type parent struct {
+		node int
+		link string
+	}
+
+	var (
+		cycles   []Cycle
+		reported = make([]bool, len(cyclic))
+	)
+
+	for start := range nodes {
+		id := component[start]
+		if !cyclic[id] || reported[id] {
+			continue
+		}
+		reported[id] = true
+
+		parents := map[int]parent{start: {node: -1}}
+		queue := []int{start}
+
+	search:
+		for len(queue) > 0 {
+			v := queue[0]
+			queue = queue[1:]
+
+			for _, s := range g.steps(nodes[v], c) {
+				w, ok := index[s.node]
+				if !ok || component[w] != id {
+					continue
+				}
+
+				if w == start {
+					// Walk back through the parents to the start.
+					cycle := Cycle{Links: []string{s.edge.URI}}
+					for p := v; p != -1; p = parents[p].node {
+						cycle.Nodes = append(cycle.Nodes, nodes[p])
+						if parents[p].node != -1 {
+							cycle.Links = append(cycle.Links, parents[p].link)
+						}
+					}
+					slices.Reverse(cycle.Nodes)
+					slices.Reverse(cycle.Links)
+
+					cycles = append(cycles, cycle)
+					break search
+				}
+
+				if _, ok := parents[w]; !ok {
+					parents[w] = parent{node: v, link: s.edge.URI}
+					queue = append(queue, w)
+				}
+			}
+		}
+
+		if len(cycles) >= limit {
+			break
+		}
+	}
+
+	return cycles
+}
+
+// TopologicalSort returns the canonical URIs of the nodes within the graph,
+// ordered so that every node comes before the nodes its links are going to
+// (or after, when following Incoming links). Nodes which aren't ordered by
+// any link keep their model order.
+//
+// The nodes and links can be restricted with WithLayers and WithLinkFilter,
+// such as to sort a single layer. If the graph contains cycles, a
+// *CycleError is returned reporting them.
+func (g *Graph) TopologicalSort(opts ...TraversalOption) ([]string, error) {
+	c := newTraversalConfig(opts)
+
+	nodes, index := g.allowedNodes(c)
+
+	indegree := make([]int, len(nodes))
+	for _, uri := range nodes {
+		for _, s := range g.steps(uri, c) {
+			if i, ok := index[s.node]; ok {
+				indegree[i]++
+			}
+		}
+	}
+
+	// Nodes which are ready to be sorted, by their model order.
+	var ready []int
+	for i, n := range indegree {
+		if n == 0 {
+			ready = append(ready, i)
+		}
+	}
+
+	sorted := make([]string, 0, len(nodes))
+
+	for len(ready) > 0 {
+		i := ready[0]
+		ready = ready[1:]
+
+		sorted = append(sorted, nodes[i])
+
+		for _, s := range g.steps(nodes[i], c) {
+			j, ok := index[s.node]
+			if !ok {
+				continue
+			}
+
+			indegree[j]--
+			if indegree[j] == 0 {
+				pos, _ := slices.BinarySearch(ready, j)
+				ready = slices.Insert(ready, pos, j)
+			}
+		}
+	}
+
+	if len(sorted) < len(nodes) {
+		return nil, &CycleError{
+			Cycles: g.componentCycles(c, maxReportedCycles),
+		}
+	}
+
+	return sorted, nil
+}
+
+// validateAcyclic checks that the model, and each of its layers, doesn't
+// contain any cycles if its AcyclicAttribute is true.
+func validateAcyclic(m *Model) error {
+	modelAcyclic, err := isAcyclic(m.GetAttributes())
+	if err != nil {
+		return fmt.Errorf("invalid model: %w", err)
+	}
+
+	var layers []*Layer
+	for _, layer := range m.GetLayers() {
+		acyclic, err := isAcyclic(layer.GetAttributes())
+		if err != nil {
+			return fmt.Errorf("invalid layer %q: %w", layer.GetId(), err)
+		}
+		if acyclic {
+			layers = append(layers, layer)
+		}
+	}
+
+	if !modelAcyclic && len(layers) == 0 {
+		return nil
+	}
+
+	g, err := NewGraph(m)
+	if err != nil {
+		return err
+	}
+
+	if modelAcyclic {
+		if _, err := g.TopologicalSort(); err != nil {
+			return fmt.Errorf("model must be acyclic: %w", err)
+		}
+	}
+
+	for _, layer := range layers {
+		_, err := g.TopologicalSort(WithLinkFilter(func(e *Edge) bool {
+			return e.Layer == layer
+		}))
+		if err != nil {
+			return fmt.Errorf("layer %q must be acyclic: %w", layer.GetId(), err)
+		}
+	}
+
+	return nil
+}
+
+// isAcyclic returns the value of the AcyclicAttribute within the given
+// attributes, which must be a boolean if it is set.
+func isAcyclic(attrs map[string]*structpb.Value) (bool, error) {
+	v, ok := attrs[AcyclicAttribute]
+	if !ok {
+		return false, nil
+	}
+
+	b, ok := v.GetKind().(*structpb.Value_BoolValue)
+	if !ok {
+		return false, fmt.Errorf("attribute %q must be a bool", AcyclicAttribute)
+	}
+
+	return b.BoolValue, nil
+}
@@ -0,0 +1,230 @@
+package layupv1_test
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	layupv1 "github.com/picatz/layup/pkg/layup/v1"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+const testCycles = `
+uri = "layup://test"
+
+layer "build" {
+	node "a" {}
+	node "b" {}
+	node "c" {}
+	node "d" {}
+
+	link "ab" {
+		from = node.a
+		to   = node.b
+	}
+
+	link "bc" {
+		from = node.b
+		to   = node.c
+	}
+
+	link "ca" {
+		from = node.c
+		to   = node.a
+		kind = "feedback"
+	}
+
+	link "dd" {
+		from = node.d
+		to   = node.d
+		kind = "feedback"
+	}
+}
+`
+
+func TestGraph_Cycles(t *testing.T) {
+	g := newTestGraph(t, testCycles)
+
+	cycles := g.Cycles()
+
+	if len(cycles) != 2 {
+		t.Fatalf("unexpected cycles: %v", cycles)
+	}
+
+	if got := cycles[0].String(); got != "layup://test/layers/build/nodes/a -> layup://test/layers/build/nodes/b -> layup://test/layers/build/nodes/c -> layup://test/layers/build/nodes/a" {
+		t.Fatalf("unexpected cycle: %s", got)
+	}
+
+	if !slices.Equal(cycles[1].Links, []string{"layup://test/layers/build/links/dd"}) {
+		t.Fatalf("unexpected self-loop: %v", cycles[1])
+	}
+
+	if cycles := g.Cycles(layupv1.WithMaxPaths(1)); len(cycles) != 1 {
+		t.Fatalf("unexpected cycles: %v", cycles)
+	}
+
+	if cycles := g.Cycles(layupv1.WithLinkFilter(func(e *layupv1.Edge) bool {
+		return e.Link.GetAttributes()["kind"].GetStringValue() != "feedback"
+	})); len(cycles) != 0 {
+		t.Fatalf("unexpected cycles: %v", cycles)
+	}
+}
+
+func TestGraph_TopologicalSort(t *testing.T) {
+	g := newTestGraph(t, thisProject)
+
+	sorted, err := g.TopologicalSort()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	position := map[string]int{}
+	for i, uri := range sorted {
+		position[uri] = i
+	}
+
+	if len(sorted) != len(g.Nodes()) {
+		t.Fatalf("unexpected number of nodes: %d", len(sorted))
+	}
+
+	for _, e := range g.Links() {
+		if e.External {
+			continue
+		}
+		if position[e.From] >= position[e.To] {
+			t.Fatalf("link %q is not in order", e.URI)
+		}
+	}
+
+	t.Run("layer", func(t *testing.T) {
+		sorted, err := g.TopologicalSort(layupv1.WithLayers("go"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(sorted) != len(g.LayerNodes("go")) {
+			t.Fatalf("unexpected nodes: %v", sorted)
+		}
+	})
+
+	t.Run("cycle", func(t *testing.T) {
+		g := newTestGraph(t, testCycles)
+
+		_, err := g.TopologicalSort()
+
+		var cycleErr *layupv1.CycleError
+		if !errors.As(err, &cycleErr) {
+			t.Fatalf("expected cycle error, got %v", err)
+		}
+
+		if len(cycleErr.Cycles) != 2 {
+			t.Fatalf("unexpected cycles: %v", cycleErr.Cycles)
+		}
+
+		sorted, err := g.TopologicalSort(layupv1.WithLinkAttribute("kind", "feedback"))
+		if err == nil {
+			t.Fatalf("expected error, got %v", sorted)
+		}
+
+		sorted, err = g.TopologicalSort(layupv1.WithLinkIDs("ab", "bc"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got := strings.Join(sorted, " "); got != "layup://test/layers/build/nodes/a layup://test/layers/build/nodes/b layup://test/layers/build/nodes/c layup://test/layers/build/nodes/d" {
+			t.Fatalf("unexpected order: %s", got)
+		}
+	})
+}
+
+func TestValidate_acyclic(t *testing.T) {
+	for name, hcl := range map[string]string{
+		"model": strings.Replace(testCycles, `uri = "layup://test"`, `uri = "layup://test"
+acyclic = true`, 1),
+		"layer": strings.Replace(testCycles, `layer "build" {`, `layer "build" {
+	acyclic = true`, 1),
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := layupv1.ParseHCL(strings.NewReader(hcl))
+
+			var cycleErr *layupv1.CycleError
+			if !errors.As(err, &cycleErr) {
+				t.Fatalf("expected cycle error, got %v", err)
+			}
+		})
+	}
+
+	t.Run("acyclic", func(t *testing.T) {
+		hcl := strings.Replace(thisProject, `uri = "layup://example"`, `uri = "layup://example"
+acyclic = true`, 1)
+
+		m, err := layupv1.ParseHCL(strings.NewReader(hcl))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !m.GetAttributes()[layupv1.AcyclicAttribute].GetBoolValue() {
+			t.Fatal("expected model to be acyclic")
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		hcl := strings.Replace(testCycles, `layer "build" {`, `layer "build" {
+	acyclic = "yes"`, 1)
+
+		if _, err := layupv1.ParseHCL(strings.NewReader(hcl)); err == nil {
+			t.Fatal("expected error, got nil")
+		}
+	})
+}
+
+func TestValidate_acyclicLadder(t *testing.T) {
+	// A ladder has an exponential number of paths, which mustn't all be
+	// walked to report the one cycle at its end.
+	layer := &layupv1.Layer{Id: "ladder"}
+
+	const rungs = 60
+	for i := 0; i <= rungs; i++ {
+		layer.Nodes = append(layer.Nodes,
+			&layupv1.Node{Id: fmt.Sprintf("l%d", i)},
+			&layupv1.Node{Id: fmt.Sprintf("r%d", i)},
+		)
+	}
+
+	link := func(from, to string) {
+		layer.Links = append(layer.Links, &layupv1.Link{Id: from + "_" + to, From: from, To: to})
+	}
+
+	for i := 0; i < rungs; i++ {
+		for _, from := range []string{"l", "r"} {
+			for _, to := range []string{"l", "r"} {
+				link(fmt.Sprintf("%s%d", from, i), fmt.Sprintf("%s%d", to, i+1))
+			}
+		}
+	}
+
+	link(fmt.Sprintf("l%d", rungs), "cycle")
+	link("cycle", fmt.Sprintf("l%d", rungs))
+	layer.Nodes = append(layer.Nodes, &layupv1.Node{Id: "cycle"})
+
+	err := layupv1.Validate(&layupv1.Model{
+		Uri:        "layup://ladder",
+		Attributes: map[string]*structpb.Value{layupv1.AcyclicAttribute: structpb.NewBoolValue(true)},
+		Layers:     []*layupv1.Layer{layer},
+	})
+
+	var cycleErr *layupv1.CycleError
+	if !errors.As(err, &cycleErr) {
+		t.Fatalf("expected cycle error, got %v", err)
+	}
+
+	if len(cycleErr.Cycles) != 1 {
+		t.Fatalf("unexpected cycles: %v", cycleErr.Cycles)
+	}
+
+	if got := cycleErr.Cycles[0].String(); got != "layup://ladder/layers/ladder/nodes/l60 -> layup://ladder/layers/ladder/nodes/cycle -> layup://ladder/layers/ladder/nodes/l60" {
+		t.Fatalf("unexpected cycle: %s", got)
+	}
+}
@@ -0,0 +1,124 @@
+package layupv1
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// cytoscapeDocument is the Cytoscape.js elements JSON document, which can
+// be given directly to cytoscape({ elements: ... }) or imported by the
+// Cytoscape desktop application.
+//
+// https://js.cytoscape.org/#notation/elements-json
+type cytoscapeDocument struct {
+	Elements cytoscapeElements `json:"elements"`
+}
+
+type cytoscapeElements struct {
+	Nodes []cytoscapeElement `json:"nodes"`
+	Edges []cytoscapeElement `json:"edges"`
+}
+
+type cytoscapeElement struct {
+	Data    map[string]any `json:"data"`
+	Classes string         `json:"classes,omitempty"`
+}
+
+// cytoscapeData returns the "data" object for an element, with the given
+// attributes flattened into it so they can be used directly in Cytoscape.js
+// selectors and style mappers (e.g. data(url)). Reserved keys, like "id"
+// and "parent", always take precedence over attributes of the same name.
+func cytoscapeData(reserved map[string]any, attrs map[string]any) map[string]any {
+	data := make(map[string]any, len(reserved)+len(attrs))
+
+	for k, v := range attrs {
+		data[k] = v
+	}
+
+	for k, v := range reserved {
+		data[k] = v
+	}
+
+	return data
+}
+
+// WriteCytoscape writes a Cytoscape.js elements JSON document to the given
+// writer using the given Layup model's data.
+//
+// Layers are written as compound (parent) nodes containing their nodes,
+// and links are written as edges. Elements are identified by their
+// canonical URIs, and any link going to a node outside of the model is
+// given an "external" node so that every edge has both of its endpoints.
+func WriteCytoscape(w io.Writer, m *Model) error {
+	doc := cytoscapeDocument{
+		Elements: cytoscapeElements{
+			Nodes: []cytoscapeElement{},
+			Edges: []cytoscapeElement{},
+		},
+	}
+
+	nodes := map[string]struct{}{}
+
+	for _, layer := range m.GetLayers() {
+		id := layerURI(m, layer.GetId())
+
+		doc.Elements.Nodes = append(doc.Elements.Nodes, cytoscapeElement{
+			Data: cytoscapeData(map[string]any{
+				"id":    id,
+				"label": layer.GetId(),
+			}, attributesToMap(layer.GetAttributes())),
+			Classes: "layer",
+		})
+
+		for _, n := range layer.GetNodes() {
+			id := nodeURI(m, layer.GetId(), n.GetId())
+
+			doc.Elements.Nodes = append(doc.Elements.Nodes, cytoscapeElement{
+				Data: cytoscapeData(map[string]any{
+					"id":     id,
+					"label":  n.GetId(),
+					"layer":  layer.GetId(),
+					"parent": layerURI(m, layer.GetId()),
+				}, attributesToMap(n.GetAttributes())),
+				Classes: "node",
+			})
+
+			nodes[id] = struct{}{}
+		}
+	}
+
+	for _, layer := range m.GetLayers() {
+		for _, link := range layer.GetLinks() {
+			target := linkTargetURI(m, layer, link)
+
+			// Add a node for any link target outside of the model.
+			if _, ok := nodes[target]; !ok {
+				doc.Elements.Nodes = append(doc.Elements.Nodes, cytoscapeElement{
+					Data: map[string]any{
+						"id":    target,
+						"label": target,
+					},
+					Classes: "external",
+				})
+
+				nodes[target] = struct{}{}
+			}
+
+			doc.Elements.Edges = append(doc.Elements.Edges, cytoscapeElement{
+				Data: cytoscapeData(map[string]any{
+					"id":     linkURI(m, layer.GetId(), link.GetId()),
+					"label":  link.GetId(),
+					"layer":  layer.GetId(),
+					"source": nodeURI(m, layer.GetId(), link.GetFrom()),
+					"target": target,
+				}, attributesToMap(link.GetAttributes())),
+				Classes: "link",
+			})
+		}
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(doc)
+}
@@ -0,0 +1,97 @@
+package layupv1_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	layupv1 "github.com/picatz/layup/pkg/layup/v1"
+	structpb "google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestWriteCytoscape(t *testing.T) {
+	model := &layupv1.Model{
+		Uri: "layup://test",
+		Layers: []*layupv1.Layer{
+			{
+				Id: "1",
+				Nodes: []*layupv1.Node{
+					{
+						Id: "a",
+						Attributes: map[string]*structpb.Value{
+							"id":   structpb.NewStringValue("ignored"),
+							"size": structpb.NewNumberValue(3),
+						},
+					},
+					{
+						Id: "b",
+					},
+				},
+				Links: []*layupv1.Link{
+					{
+						Id:   "within",
+						From: "a",
+						To:   "b",
+					},
+					{
+						Id:   "outside",
+						From: "a",
+						To:   "github://picatz/layup",
+					},
+				},
+			},
+		},
+	}
+
+	cyBuffer := strings.Builder{}
+
+	if err := layupv1.WriteCytoscape(&cyBuffer, model); err != nil {
+		t.Fatal(err)
+	}
+
+	var doc struct {
+		Elements struct {
+			Nodes []struct {
+				Data    map[string]any `json:"data"`
+				Classes string         `json:"classes"`
+			} `json:"nodes"`
+			Edges []struct {
+				Data map[string]any `json:"data"`
+			} `json:"edges"`
+		} `json:"elements"`
+	}
+
+	if err := json.Unmarshal([]byte(cyBuffer.String()), &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	// 1 layer + 2 nodes + 1 external node
+	if len(doc.Elements.Nodes) != 4 {
+		t.Fatalf("expected 4 nodes, got %d", len(doc.Elements.Nodes))
+	}
+
+	a := doc.Elements.Nodes[1].Data
+	if a["id"] != "layup://test/layers/1/nodes/a" {
+		t.Fatalf("reserved key was overwritten by attribute: %v", a["id"])
+	}
+
+	if a["parent"] != "layup://test/layers/1" {
+		t.Fatalf("unexpected parent: %v", a["parent"])
+	}
+
+	if a["size"] != 3.0 {
+		t.Fatalf("unexpected size attribute: %v", a["size"])
+	}
+
+	if doc.Elements.Nodes[3].Classes != "external" {
+		t.Fatalf("expected external node, got %q", doc.Elements.Nodes[3].Classes)
+	}
+
+	if len(doc.Elements.Edges) != 2 {
+		t.Fatalf("expected 2 edges, got %d", len(doc.Elements.Edges))
+	}
+
+	if doc.Elements.Edges[1].Data["target"] != "github://picatz/layup" {
+		t.Fatalf("unexpected target: %v", doc.Elements.Edges[1].Data["target"])
+	}
+}
@@ -4,7 +4,6 @@ import (
 	"bufio"
 	"fmt"
 	"io"
-	"strings"
 	"text/tabwriter"
 
 	structpb "google.golang.org/protobuf/types/known/structpb"
@@ -12,7 +11,9 @@ import (
 
 // WriteD2 writes a D2 (Terrastruct) graph to the given writer using the
 // given Layup model's data.
-func WriteD2(w io.Writer, m *Model) error {
+func WriteD2(w io.Writer, m *Model, opts ...RenderOption) error {
+	c := newRenderConfig(opts)
+
 	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
 	defer tw.Flush()
 
@@ -38,6 +39,12 @@ func WriteD2(w io.Writer, m *Model) error {
 
 				bw.WriteString("\t\t" + attrStr + "\n")
 			}
+			if label, ok := c.nodeLabels[nodeURI(m, layer.Id, n.Id)]; ok {
+				bw.WriteString("\t\t" + fmt.Sprintf("label: %q", label) + "\n")
+			}
+			if color, ok := c.nodeColors[nodeURI(m, layer.Id, n.Id)]; ok {
+				bw.WriteString("\t\t" + fmt.Sprintf("style.fill: %q", color) + "\n")
+			}
 			bw.WriteString("\t}\n\n")
 		}
 
@@ -46,28 +53,21 @@ func WriteD2(w io.Writer, m *Model) error {
 
 	for _, layer := range m.Layers {
 		for _, link := range layer.Links {
-			linkToID := link.To
-
-			// If the link has an ID, use that as the label, otherwise
-			// if it's using a URI, we need to clean it up a bit to match
-			// D2's syntax. We control the ID of each node in the subgraph,
-			// so this is fairly safe.
-			if strings.Contains(linkToID, "://") {
-				linkToID = strings.TrimPrefix(linkToID, m.GetUri())
-
-				// The link is likely to be a URI to a different layer, so we need
-				// to remove the layer name from the URI.
-				linkToID = strings.TrimPrefix(linkToID, "/layers/")
-
-				// Replace the "/nodes/" with _ to match the node ID.
-				linkToID = strings.Replace(linkToID, "/nodes/", ".", 1)
-			} else {
-				linkToID = fmt.Sprintf("%s.%s", layer.Id, link.To)
+			linkToID, external := linkTargetID(m, layer, link, ".")
+			if external {
+				linkToID = fmt.Sprintf("%q", linkToID)
 			}
 
 			linkFromID := fmt.Sprintf("%s.%s", layer.Id, link.From)
 
-			bw.WriteString("" + fmt.Sprintf("%s -> %s: %q", linkFromID, linkToID, link.Id) + "\n")
+			linkLabel := c.linkLabel(m, layer, link)
+
+			if color, ok := c.linkColors[linkURI(m, layer.Id, link.Id)]; ok {
+				bw.WriteString("" + fmt.Sprintf("%s -> %s: %q {style.stroke: %q}", linkFromID, linkToID, linkLabel, color) + "\n")
+				continue
+			}
+
+			bw.WriteString("" + fmt.Sprintf("%s -> %s: %q", linkFromID, linkToID, linkLabel) + "\n")
 		}
 	}
 
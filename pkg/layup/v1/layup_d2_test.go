@@ -23,3 +23,32 @@ func TestWriteD2(t *testing.T) {
 
 	fmt.Println(d2Buffer.String())
 }
+
+func TestWriteD2_external(t *testing.T) {
+	model, err := layupv1.ParseHCL(strings.NewReader(`
+uri = "layup://test"
+
+layer "1" {
+	node "a" {}
+
+	link "outside" {
+		from = node.a
+		to   = "github://picatz/layup"
+	}
+}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var sb strings.Builder
+
+	if err := layupv1.WriteD2(&sb, model); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `1.a -> "github://picatz/layup"`
+	if !strings.Contains(sb.String(), want) {
+		t.Fatalf("expected external link %q in:\n%s", want, sb.String())
+	}
+}
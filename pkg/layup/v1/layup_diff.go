@@ -0,0 +1,457 @@
+package layupv1
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// ChangeType is the type of a change between two models.
+type ChangeType string
+
+const (
+	// ChangeAdded is an element only in the new model.
+	ChangeAdded ChangeType = "added"
+	// ChangeRemoved is an element only in the old model.
+	ChangeRemoved ChangeType = "removed"
+	// ChangeChanged is an element in both models, with different fields.
+	ChangeChanged ChangeType = "changed"
+)
+
+// Symbol returns the symbol for the change type used by WriteDiff, which
+// is "+" for added, "-" for removed and "~" for changed.
+func (t ChangeType) Symbol() string {
+	switch t {
+	case ChangeAdded:
+		return "+"
+	case ChangeRemoved:
+		return "-"
+	default:
+		return "~"
+	}
+}
+
+// ElementKind is the kind of element within a model.
+type ElementKind string
+
+const (
+	ElementModel ElementKind = "model"
+	ElementLayer ElementKind = "layer"
+	ElementNode  ElementKind = "node"
+	ElementLink  ElementKind = "link"
+)
+
+// ModelDiff is the structural difference between two models.
+type ModelDiff struct {
+	// From and To are the URIs of the old and new models.
+	From string `json:"from"`
+	To   string `json:"to"`
+	// Changes are the changed elements, in model order.
+	Changes []Change `json:"changes"`
+}
+
+// Change is an added, removed or changed element of a model.
+type Change struct {
+	// Type is the type of the change.
+	Type ChangeType `json:"type"`
+	// Element is the kind of element which changed.
+	Element ElementKind `json:"element"`
+	// URI is the canonical URI of the element, within the new model, or
+	// the old model if it was removed.
+	URI string `json:"uri"`
+	// Fields are the changed fields of the element, or all of the fields of
+	// an added or removed element.
+	Fields []FieldChange `json:"fields,omitempty"`
+}
+
+// FieldChange is a change to a field of an element, such as the "to" of a
+// link, or one of its attributes, which are prefixed by "attributes.".
+//
+// Attribute values are a *structpb.Value, so a null attribute (which is
+// a non-nil *structpb.Value) isn't mistaken for a missing one.
+type FieldChange struct {
+	// Field is the name of the field.
+	Field string `json:"field"`
+	// Old is the old value, or nil if the field was added.
+	Old any `json:"old,omitempty"`
+	// New is the new value, or nil if the field was removed.
+	New any `json:"new,omitempty"`
+}
+
+// MarshalJSON encodes the change with attribute values as JSON, including
+// null values, which are only left out if the field was added or removed.
+func (f FieldChange) MarshalJSON() ([]byte, error) {
+	oldValue, err := jsonFieldValue(f.Old)
+	if err != nil {
+		return nil, err
+	}
+
+	newValue, err := jsonFieldValue(f.New)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(struct {
+		Field string          `json:"field"`
+		Old   json.RawMessage `json:"old,omitempty"`
+		New   json.RawMessage `json:"new,omitempty"`
+	}{
+		Field: f.Field,
+		Old:   oldValue,
+		New:   newValue,
+	})
+}
+
+// Empty reports whether there are no changes between the models.
+func (d *ModelDiff) Empty() bool {
+	return len(d.Changes) == 0
+}
+
+// Diff returns the structural difference between the old model a and the
+// new model b, with the added, removed and changed layers, nodes and links,
+// and their attributes.
+//
+// Elements are compared by their path within each model (such as a layer
+// and node ID), so changing the URI of a model is a single change to the
+// model, rather than every element being removed and added. Links are
+// compared by the canonical URIs of the nodes they connect, so a link
+// changing from a local node ID to the same node's canonical URI isn't a
+// change.
+func Diff(a, b *Model) (*ModelDiff, error) {
+	ga, err := NewGraph(a)
+	if err != nil {
+		return nil, fmt.Errorf("failed to index old model: %w", err)
+	}
+
+	gb, err := NewGraph(b)
+	if err != nil {
+		return nil, fmt.Errorf("failed to index new model: %w", err)
+	}
+
+	d := &ModelDiff{
+		From:    a.GetUri(),
+		To:      b.GetUri(),
+		Changes: []Change{},
+	}
+
+	modelFields := []FieldChange{}
+	if a.GetUri() != b.GetUri() {
+		modelFields = append(modelFields, FieldChange{Field: "uri", Old: a.GetUri(), New: b.GetUri()})
+	}
+	modelFields = append(modelFields, diffAttributes(a.GetAttributes(), b.GetAttributes())...)
+
+	if len(modelFields) > 0 {
+		d.Changes = append(d.Changes, Change{Type: ChangeChanged, Element: ElementModel, URI: b.GetUri(), Fields: modelFields})
+	}
+
+	layerIDs := func(m *Model) []string {
+		var ids []string
+		for _, layer := range m.GetLayers() {
+			ids = append(ids, layer.GetId())
+		}
+		return ids
+	}
+
+	for _, layerID := range unionOrder(layerIDs(a), layerIDs(b)) {
+		la, inA := ga.layers[layerID]
+		lb, inB := gb.layers[layerID]
+
+		d.add(ElementLayer, layerURI(a, layerID), layerURI(b, layerID), inA, inB, layerFields(la), layerFields(lb))
+
+		nodeIDs := func(layer *Layer) []string {
+			var ids []string
+			for _, n := range layer.GetNodes() {
+				ids = append(ids, n.GetId())
+			}
+			return ids
+		}
+
+		for _, nodeID := range unionOrder(nodeIDs(la), nodeIDs(lb)) {
+			na, inA := ga.nodes[nodeURI(a, layerID, nodeID)]
+			nb, inB := gb.nodes[nodeURI(b, layerID, nodeID)]
+
+			d.add(ElementNode, nodeURI(a, layerID, nodeID), nodeURI(b, layerID, nodeID), inA, inB, nodeFields(na), nodeFields(nb))
+		}
+
+		linkIDs := func(layer *Layer) []string {
+			var ids []string
+			for _, link := range layer.GetLinks() {
+				ids = append(ids, link.GetId())
+			}
+			return ids
+		}
+
+		for _, linkID := range unionOrder(linkIDs(la), linkIDs(lb)) {
+			ea, inA := ga.links[linkURI(a, layerID, linkID)]
+			eb, inB := gb.links[linkURI(b, layerID, linkID)]
+
+			d.add(ElementLink, linkURI(a, layerID, linkID), linkURI(b, layerID, linkID), inA, inB, linkFields(ea, a, b), linkFields(eb, b, b))
+		}
+	}
+
+	return d, nil
+}
+
+// add adds the change (if any) to an element, given whether it is in the
+// old and new models, and its fields in each.
+func (d *ModelDiff) add(kind ElementKind, oldURI, newURI string, inA, inB bool, a, b map[string]any) {
+	switch {
+	case inA && inB:
+		if fields := diffFields(a, b); len(fields) > 0 {
+			d.Changes = append(d.Changes, Change{Type: ChangeChanged, Element: kind, URI: newURI, Fields: fields})
+		}
+	case inA:
+		d.Changes = append(d.Changes, Change{Type: ChangeRemoved, Element: kind, URI: oldURI, Fields: diffFields(a, nil)})
+	case inB:
+		d.Changes = append(d.Changes, Change{Type: ChangeAdded, Element: kind, URI: newURI, Fields: diffFields(nil, b)})
+	}
+}
+
+// layerFields returns the fields of a layer which are compared.
+func layerFields(layer *Layer) map[string]any {
+	if layer == nil {
+		return nil
+	}
+
+	fields := attributeFields(layer.GetAttributes())
+	if layer.Dynamic != nil {
+		fields["dynamic"] = layer.GetDynamic()
+	}
+
+	return fields
+}
+
+// nodeFields returns the fields of a node which are compared.
+func nodeFields(n *Node) map[string]any {
+	if n == nil {
+		return nil
+	}
+
+	return attributeFields(n.GetAttributes())
+}
+
+// linkFields returns the fields of a link which are compared, with the
+// URIs of the nodes it connects relative to the to model, so links within
+// models with different URIs can be compared.
+func linkFields(e *Edge, from, to *Model) map[string]any {
+	if e == nil {
+		return nil
+	}
+
+	fields := attributeFields(e.Link.GetAttributes())
+	fields["from"] = rebaseURI(e.From, from, to)
+	fields["to"] = rebaseURI(e.To, from, to)
+
+	return fields
+}
+
+// rebaseURI returns the given canonical URI within the from model as the
+// same URI within the to model.
+func rebaseURI(uri string, from, to *Model) string {
+	u, err := ParseURI(uri)
+	if err != nil || u.Model != from.GetUri() {
+		return uri
+	}
+
+	u.Model = to.GetUri()
+
+	return u.String()
+}
+
+// attributeFields returns the given attributes as fields, prefixed by
+// "attributes.".
+func attributeFields(attrs map[string]*structpb.Value) map[string]any {
+	fields := map[string]any{}
+	for k, v := range attrs {
+		fields["attributes."+k] = v
+	}
+	return fields
+}
+
+// diffAttributes returns the changes between the given attributes.
+func diffAttributes(a, b map[string]*structpb.Value) []FieldChange {
+	return diffFields(attributeFields(a), attributeFields(b))
+}
+
+// diffFields returns the changed fields between a and b, sorted by name.
+func diffFields(a, b map[string]any) []FieldChange {
+	names := map[string]struct{}{}
+	for k := range a {
+		names[k] = struct{}{}
+	}
+	for k := range b {
+		names[k] = struct{}{}
+	}
+
+	sorted := make([]string, 0, len(names))
+	for k := range names {
+		sorted = append(sorted, k)
+	}
+	sort.Strings(sorted)
+
+	var changes []FieldChange
+
+	for _, name := range sorted {
+		va, inA := a[name]
+		vb, inB := b[name]
+
+		if inA && inB && fieldEqual(va, vb) {
+			continue
+		}
+
+		change := FieldChange{Field: name}
+		if inA {
+			change.Old = va
+		}
+		if inB {
+			change.New = vb
+		}
+
+		changes = append(changes, change)
+	}
+
+	return changes
+}
+
+func fieldEqual(a, b any) bool {
+	va, ok := a.(*structpb.Value)
+	if !ok {
+		return a == b
+	}
+
+	vb, ok := b.(*structpb.Value)
+
+	return ok && proto.Equal(va, vb)
+}
+
+// jsonFieldValue encodes a field value as JSON, or returns nil if there
+// is no value.
+func jsonFieldValue(v any) (json.RawMessage, error) {
+	switch v := v.(type) {
+	case nil:
+		return nil, nil
+	case *structpb.Value:
+		return json.Marshal(v.AsInterface())
+	default:
+		return json.Marshal(v)
+	}
+}
+
+// unionOrder returns the IDs in b, in order, followed by the IDs only in
+// a, in order.
+func unionOrder(a, b []string) []string {
+	seen := map[string]struct{}{}
+
+	ids := make([]string, 0, len(b))
+	for _, id := range b {
+		seen[id] = struct{}{}
+		ids = append(ids, id)
+	}
+
+	for _, id := range a {
+		if _, ok := seen[id]; !ok {
+			ids = append(ids, id)
+		}
+	}
+
+	return ids
+}
+
+// WriteDiff writes the changes in a human-readable format, with one line
+// for each changed element, followed by its changed fields:
+//
+//	~ node layup://example/layers/go/nodes/runtime
+//	    attributes.url: "https://golang.org/pkg/runtime" → "https://go.dev/pkg/runtime"
+func WriteDiff(w io.Writer, d *ModelDiff) error {
+	for _, c := range d.Changes {
+		if _, err := fmt.Fprintf(w, "%s %s %s\n", c.Type.Symbol(), c.Element, c.URI); err != nil {
+			return err
+		}
+
+		for _, f := range c.Fields {
+			var err error
+
+			switch c.Type {
+			case ChangeAdded:
+				_, err = fmt.Fprintf(w, "    %s: %s\n", f.Field, formatFieldValue(f.New))
+			case ChangeRemoved:
+				_, err = fmt.Fprintf(w, "    %s: %s\n", f.Field, formatFieldValue(f.Old))
+			default:
+				_, err = fmt.Fprintf(w, "    %s: %s → %s\n", f.Field, formatFieldValue(f.Old), formatFieldValue(f.New))
+			}
+
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// WriteUnifiedDiff writes the changes in a format similar to a unified
+// diff, with a hunk for each changed element, containing the old and new
+// values of its changed fields:
+//
+//	--- layup://example
+//	+++ layup://example
+//	@@ node layup://example/layers/go/nodes/runtime @@
+//	-attributes.url = "https://golang.org/pkg/runtime"
+//	+attributes.url = "https://go.dev/pkg/runtime"
+func WriteUnifiedDiff(w io.Writer, d *ModelDiff) error {
+	if d.Empty() {
+		return nil
+	}
+
+	if _, err := fmt.Fprintf(w, "--- %s\n+++ %s\n", d.From, d.To); err != nil {
+		return err
+	}
+
+	for _, c := range d.Changes {
+		if _, err := fmt.Fprintf(w, "@@ %s %s @@\n", c.Element, c.URI); err != nil {
+			return err
+		}
+
+		// Added and removed elements have a line for the element itself,
+		// since they may not have any fields.
+		switch c.Type {
+		case ChangeAdded, ChangeRemoved:
+			if _, err := fmt.Fprintf(w, "%s%s\n", c.Type.Symbol(), c.Element); err != nil {
+				return err
+			}
+		}
+
+		for _, f := range c.Fields {
+			if f.Old != nil {
+				if _, err := fmt.Fprintf(w, "-%s = %s\n", f.Field, formatFieldValue(f.Old)); err != nil {
+					return err
+				}
+			}
+			if f.New != nil {
+				if _, err := fmt.Fprintf(w, "+%s = %s\n", f.Field, formatFieldValue(f.New)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// formatFieldValue formats a field value as JSON, or "(none)" if it is nil.
+func formatFieldValue(v any) string {
+	if v == nil {
+		return "(none)"
+	}
+
+	b, err := jsonFieldValue(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+
+	return string(b)
+}
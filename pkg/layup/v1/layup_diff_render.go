@@ -0,0 +1,158 @@
+package layupv1
+
+import (
+	"fmt"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// Colors used by ModelDiff.RenderOptions to highlight the changes.
+const (
+	DiffAddedColor   = "#4daf4a"
+	DiffRemovedColor = "#e41a1c"
+	DiffChangedColor = "#ff7f00"
+)
+
+// DiffUnion returns the union of the old model a and the new model b, which
+// is a copy of the new model, with the layers, nodes and links removed from
+// the old model added back in, so every change of their ModelDiff can be
+// rendered in a single diagram, using its RenderOptions.
+//
+// Removed links going to nodes within the old model are rewritten to the
+// canonical URIs of the same nodes within the union, which has the URI of
+// the new model.
+func DiffUnion(a, b *Model) (*Model, error) {
+	ga, err := NewGraph(a)
+	if err != nil {
+		return nil, fmt.Errorf("failed to index old model: %w", err)
+	}
+
+	gb, err := NewGraph(b)
+	if err != nil {
+		return nil, fmt.Errorf("failed to index new model: %w", err)
+	}
+
+	union := proto.Clone(b).(*Model)
+
+	layers := map[string]*Layer{}
+	for _, layer := range union.GetLayers() {
+		layers[layer.GetId()] = layer
+	}
+
+	for _, la := range a.GetLayers() {
+		layer, ok := layers[la.GetId()]
+		if !ok {
+			layer = &Layer{
+				Id:         la.GetId(),
+				Attributes: cloneAttributes(la.GetAttributes()),
+			}
+			if la.Dynamic != nil {
+				layer.Dynamic = proto.Bool(la.GetDynamic())
+			}
+
+			layers[la.GetId()] = layer
+			union.Layers = append(union.Layers, layer)
+		}
+
+		for _, n := range la.GetNodes() {
+			if _, ok := gb.nodes[nodeURI(b, la.GetId(), n.GetId())]; !ok {
+				layer.Nodes = append(layer.Nodes, proto.Clone(n).(*Node))
+			}
+		}
+
+		for _, link := range la.GetLinks() {
+			if _, ok := gb.links[linkURI(b, la.GetId(), link.GetId())]; ok {
+				continue
+			}
+
+			e := ga.links[linkURI(a, la.GetId(), link.GetId())]
+
+			removed := proto.Clone(link).(*Link)
+			if !e.External {
+				removed.To = rebaseURI(e.To, a, b)
+			}
+
+			layer.Links = append(layer.Links, removed)
+		}
+	}
+
+	// The union may have cycles which neither model has, such as when a
+	// link is reversed, so it can't be required to be acyclic.
+	union.Attributes = projectedAttributes(union.GetAttributes())
+	for _, layer := range union.GetLayers() {
+		layer.Attributes = projectedAttributes(layer.GetAttributes())
+	}
+
+	if err := Validate(union); err != nil {
+		return nil, fmt.Errorf("failed to create union of models: %w", err)
+	}
+
+	return union, nil
+}
+
+// RenderOptions returns the options to highlight the changes when rendering
+// the union of the models (see DiffUnion), with added elements in green,
+// removed elements in red, and changed elements in orange, labeled with
+// their changed fields.
+func (d *ModelDiff) RenderOptions() []RenderOption {
+	var (
+		nodeColors = map[string]string{}
+		linkColors = map[string]string{}
+		nodeLabels = map[string]string{}
+		linkLabels = map[string]string{}
+	)
+
+	for _, c := range d.Changes {
+		// Removed elements have the URIs of the old model, but are rendered
+		// within the union, which has the URI of the new model.
+		uri := c.URI
+		if c.Type == ChangeRemoved && d.From != d.To {
+			if u, err := ParseURI(uri); err == nil {
+				u.Model = d.To
+				uri = u.String()
+			}
+		}
+
+		color := DiffChangedColor
+		switch c.Type {
+		case ChangeAdded:
+			color = DiffAddedColor
+		case ChangeRemoved:
+			color = DiffRemovedColor
+		}
+
+		switch c.Element {
+		case ElementNode:
+			nodeColors[uri] = color
+			if c.Type == ChangeChanged {
+				nodeLabels[uri] = changeLabel(c)
+			}
+		case ElementLink:
+			linkColors[uri] = color
+			if c.Type == ChangeChanged {
+				linkLabels[uri] = changeLabel(c)
+			}
+		}
+	}
+
+	return []RenderOption{
+		WithNodeColors(nodeColors),
+		WithLinkColors(linkColors),
+		WithNodeLabels(nodeLabels),
+		WithLinkLabels(linkLabels),
+	}
+}
+
+// changeLabel returns the label of a changed node or link, which is its ID
+// followed by a line for each changed field.
+func changeLabel(c Change) string {
+	u, _ := ParseURI(c.URI)
+
+	lines := []string{u.Node + u.Link}
+	for _, f := range c.Fields {
+		lines = append(lines, fmt.Sprintf("%s: %s → %s", f.Field, formatFieldValue(f.Old), formatFieldValue(f.New)))
+	}
+
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,110 @@
+package layupv1_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	layupv1 "github.com/picatz/layup/pkg/layup/v1"
+)
+
+func TestDiffUnion(t *testing.T) {
+	before := newTestGraph(t, testRecipe).Model()
+
+	after := newTestGraph(t, strings.NewReplacer(
+		`material = "wood"`, `material = "bamboo"`,
+		`node "pan" {`, `node "pot" {`,
+		`to   = node.pan`, `to   = node.pot`,
+	).Replace(testRecipe)).Model()
+
+	union, err := layupv1.DiffUnion(before, after)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	g, err := layupv1.NewGraph(union)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, uri := range []string{
+		"layup://recipe/layers/tools/nodes/pan",
+		"layup://recipe/layers/tools/nodes/pot",
+	} {
+		if _, ok := g.Node(uri); !ok {
+			t.Fatalf("expected %s in union", uri)
+		}
+	}
+
+	d, err := layupv1.Diff(before, after)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+
+	if err := layupv1.WriteDOT(&buf, union, d.RenderOptions()...); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{
+		`fillcolor="` + layupv1.DiffAddedColor + `"`,
+		`fillcolor="` + layupv1.DiffRemovedColor + `"`,
+		`label="spoon\nattributes.material: \"wood\" → \"bamboo\""`,
+		`color="` + layupv1.DiffChangedColor + `"`,
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Fatalf("expected %s in:\n%s", want, buf.String())
+		}
+	}
+
+	buf.Reset()
+
+	if err := layupv1.WriteMermiad(&buf, union, d.RenderOptions()...); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(buf.String(), `subgraph tools_spoon ["spoon<br/>attributes.material: #quot;wood#quot; → #quot;bamboo#quot;"]`) {
+		t.Fatalf("unexpected mermaid:\n%s", buf.String())
+	}
+}
+
+func TestDiffUnion_removedLayer(t *testing.T) {
+	before := newTestGraph(t, thisProject).Model()
+	after := newTestGraph(t, testRecipe).Model()
+
+	union, err := layupv1.DiffUnion(before, after)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	g, err := layupv1.NewGraph(union)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The removed links between layers now go to nodes within the union.
+	e, ok := g.Link("layup://recipe/layers/buf/links/uses")
+	if !ok || e.External {
+		t.Fatalf("unexpected link: %+v", e)
+	}
+
+	d, err := layupv1.Diff(before, after)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+
+	if err := layupv1.WriteD2(&buf, union, d.RenderOptions()...); err != nil {
+		t.Fatal(err)
+	}
+
+	// Every node and link of the old model was removed.
+	removed := len(g.Nodes()) - len(after.GetLayers()[0].GetNodes()) - len(after.GetLayers()[1].GetNodes())
+	removed += len(g.Links()) - len(after.GetLayers()[0].GetLinks()) - len(after.GetLayers()[1].GetLinks())
+
+	if got := strings.Count(buf.String(), layupv1.DiffRemovedColor); got != removed {
+		t.Fatalf("expected %d removed elements, got %d:\n%s", removed, got, buf.String())
+	}
+}
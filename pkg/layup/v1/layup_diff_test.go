@@ -0,0 +1,104 @@
+package layupv1_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	layupv1 "github.com/picatz/layup/pkg/layup/v1"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestDiff(t *testing.T) {
+	before := newTestGraph(t, testRecipe).Model()
+
+	after := newTestGraph(t, strings.NewReplacer(
+		`material = "wood"`, `material = "bamboo"`,
+		`node "pan" {`, `node "pot" {`,
+		`to   = node.pan`, `to   = node.pot`,
+	).Replace(testRecipe)).Model()
+
+	d, err := layupv1.Diff(before, after)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, c := range d.Changes {
+		got = append(got, c.Type.Symbol()+" "+c.URI)
+	}
+
+	want := []string{
+		"+ layup://recipe/layers/tools/nodes/pot",
+		"~ layup://recipe/layers/tools/nodes/spoon",
+		"- layup://recipe/layers/tools/nodes/pan",
+		"~ layup://recipe/layers/tools/links/pour",
+		"~ layup://recipe/layers/tools/links/stir",
+	}
+
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("expected:\n%s\ngot:\n%s", strings.Join(want, "\n"), strings.Join(got, "\n"))
+	}
+
+	spoon := d.Changes[1].Fields
+	if len(spoon) != 1 || spoon[0].Field != "attributes.material" ||
+		!proto.Equal(spoon[0].Old.(*structpb.Value), structpb.NewStringValue("wood")) ||
+		!proto.Equal(spoon[0].New.(*structpb.Value), structpb.NewStringValue("bamboo")) {
+		t.Fatalf("unexpected fields: %v", spoon)
+	}
+
+	var buf bytes.Buffer
+
+	if err := layupv1.WriteDiff(&buf, d); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(buf.String(), `attributes.material: "wood" → "bamboo"`) {
+		t.Fatalf("unexpected diff:\n%s", buf.String())
+	}
+
+	buf.Reset()
+
+	if err := layupv1.WriteUnifiedDiff(&buf, d); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, line := range []string{
+		"@@ link layup://recipe/layers/tools/links/pour @@",
+		`-to = "layup://recipe/layers/tools/nodes/pan"`,
+		`+to = "layup://recipe/layers/tools/nodes/pot"`,
+		"-node",
+	} {
+		if !strings.Contains(buf.String(), line+"\n") {
+			t.Fatalf("expected %q in unified diff:\n%s", line, buf.String())
+		}
+	}
+}
+
+func TestDiff_same(t *testing.T) {
+	g := newTestGraph(t, thisProject)
+
+	d, err := layupv1.Diff(g.Model(), g.Model())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !d.Empty() {
+		t.Fatalf("unexpected changes: %v", d.Changes)
+	}
+}
+
+func TestDiff_modelURI(t *testing.T) {
+	before := newTestGraph(t, thisProject).Model()
+	after := newTestGraph(t, strings.Replace(thisProject, "layup://example", "layup://renamed", 1)).Model()
+
+	d, err := layupv1.Diff(before, after)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(d.Changes) != 1 || d.Changes[0].Element != layupv1.ElementModel {
+		t.Fatalf("unexpected changes: %v", d.Changes)
+	}
+}
@@ -4,7 +4,6 @@ import (
 	"bufio"
 	"fmt"
 	"io"
-	"strings"
 	"text/tabwriter"
 
 	structpb "google.golang.org/protobuf/types/known/structpb"
@@ -12,7 +11,9 @@ import (
 
 // WriteDOT writes a DOT graph to the given writer using the
 // given Layup model's data.
-func WriteDOT(w io.Writer, m *Model) error {
+func WriteDOT(w io.Writer, m *Model, opts ...RenderOption) error {
+	c := newRenderConfig(opts)
+
 	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
 	defer tw.Flush()
 
@@ -30,7 +31,7 @@ func WriteDOT(w io.Writer, m *Model) error {
 
 		for _, n := range layer.Nodes {
 			bw.WriteString("\t\t" + layer.Id + "_" + n.Id + " [\n")
-			bw.WriteString("\t\t\tlabel=" + fmt.Sprintf("%q", n.Id) + "\n")
+			bw.WriteString("\t\t\tlabel=" + fmt.Sprintf("%q", c.nodeLabel(m, layer, n)) + "\n")
 			for k, v := range n.Attributes {
 				var attrStr string
 
@@ -45,30 +46,25 @@ func WriteDOT(w io.Writer, m *Model) error {
 
 				bw.WriteString("\t\t\t" + attrStr + "\n")
 			}
+			if color, ok := c.nodeColors[nodeURI(m, layer.Id, n.Id)]; ok {
+				bw.WriteString("\t\t\tstyle=filled\n")
+				bw.WriteString("\t\t\tfillcolor=" + fmt.Sprintf("%q", color) + "\n")
+			}
 			bw.WriteString("\t\t]\n\n")
 		}
 
 		for _, link := range layer.Links {
-			linkToID := link.To
-
-			// If the link has an ID, use that as the label, otherwise
-			// if it's using a URI, we need to clean it up a bit to match
-			// DOT's syntax. We control the ID of each node in the subgraph,
-			// so this is fairly safe.
-			if strings.Contains(linkToID, "://") {
-				linkToID = strings.TrimPrefix(linkToID, m.GetUri())
-
-				// The link is likely to be a URI to a different layer, so we need
-				// to remove the layer name from the URI.
-				linkToID = strings.TrimPrefix(linkToID, "/layers/")
-
-				// Replace the "/nodes/" with _ to match the node ID.
-				linkToID = strings.Replace(linkToID, "/nodes/", "_", 1)
-			} else {
-				linkToID = fmt.Sprintf("%s_%s", layer.Id, link.To)
+			linkToID, external := linkTargetID(m, layer, link, "_")
+			if external {
+				linkToID = fmt.Sprintf("%q", linkToID)
+			}
+
+			linkAttrs := fmt.Sprintf("label=%q", c.linkLabel(m, layer, link))
+			if color, ok := c.linkColors[linkURI(m, layer.Id, link.Id)]; ok {
+				linkAttrs += fmt.Sprintf(" color=%q", color)
 			}
 
-			bw.WriteString("\t\t" + fmt.Sprintf("%s_%s -> %s [label=%q]", layer.Id, link.From, linkToID, link.Id) + "\n")
+			bw.WriteString("\t\t" + fmt.Sprintf("%s_%s -> %s [%s]", layer.Id, link.From, linkToID, linkAttrs) + "\n")
 		}
 
 		bw.WriteString("\t}\n")
@@ -0,0 +1,259 @@
+package layupv1
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Edge is a link within a Graph, resolved to the canonical URIs of the
+// nodes it connects.
+type Edge struct {
+	// URI is the canonical URI of the link.
+	URI string
+	// Layer is the layer the link belongs to.
+	Layer *Layer
+	// Link is the underlying link in the model.
+	Link *Link
+	// From is the canonical URI of the node the link is coming from.
+	From string
+	// To is the canonical URI of the node the link is going to.
+	To string
+	// External is true if the node the link is going to is not within
+	// the model, such as a URI to another system.
+	External bool
+}
+
+// Graph is an indexed view of a Model, which makes it possible to look up
+// layers, nodes and links by their canonical URIs, and to find the links
+// going to or from a node, in constant time.
+//
+// The Graph doesn't copy the Model, so the Model should not be modified
+// while the Graph is in use. Slices returned by the Graph are shared, and
+// must not be modified by the caller.
+type Graph struct {
+	model *Model
+
+	layers     map[string]*Layer
+	nodes      map[string]*Node
+	nodeLayers map[string]*Layer
+	nodeOrder  []string
+	layerNodes map[string][]string
+	links      map[string]*Edge
+	linkOrder  []*Edge
+	out        map[string][]*Edge
+	in         map[string][]*Edge
+}
+
+// NewGraph returns a Graph indexing the given model.
+//
+// An error is returned if the model can't be indexed unambiguously, such
+// as when layers, nodes or links have duplicate IDs, or a link is coming
+// from a node which doesn't exist in its layer.
+func NewGraph(m *Model) (*Graph, error) {
+	g := &Graph{
+		model:      m,
+		layers:     map[string]*Layer{},
+		nodes:      map[string]*Node{},
+		nodeLayers: map[string]*Layer{},
+		layerNodes: map[string][]string{},
+		links:      map[string]*Edge{},
+		out:        map[string][]*Edge{},
+		in:         map[string][]*Edge{},
+	}
+
+	for _, layer := range m.GetLayers() {
+		if _, ok := g.layers[layer.GetId()]; ok {
+			return nil, fmt.Errorf("duplicate layer %q", layer.GetId())
+		}
+
+		g.layers[layer.GetId()] = layer
+
+		for _, n := range layer.GetNodes() {
+			uri := nodeURI(m, layer.GetId(), n.GetId())
+
+			if _, ok := g.nodes[uri]; ok {
+				return nil, fmt.Errorf("duplicate node %q", uri)
+			}
+
+			g.nodes[uri] = n
+			g.nodeLayers[uri] = layer
+			g.nodeOrder = append(g.nodeOrder, uri)
+			g.layerNodes[layer.GetId()] = append(g.layerNodes[layer.GetId()], uri)
+		}
+	}
+
+	for _, layer := range m.GetLayers() {
+		for _, link := range layer.GetLinks() {
+			uri := linkURI(m, layer.GetId(), link.GetId())
+
+			if _, ok := g.links[uri]; ok {
+				return nil, fmt.Errorf("duplicate link %q", uri)
+			}
+
+			from, ok := g.Resolve(layer.GetId(), link.GetFrom())
+			if !ok {
+				return nil, fmt.Errorf("link %q is from unknown node %q", uri, link.GetFrom())
+			}
+
+			to, ok := g.Resolve(layer.GetId(), link.GetTo())
+
+			e := &Edge{
+				URI:      uri,
+				Layer:    layer,
+				Link:     link,
+				From:     from,
+				To:       to,
+				External: !ok,
+			}
+
+			g.links[uri] = e
+			g.linkOrder = append(g.linkOrder, e)
+			g.out[from] = append(g.out[from], e)
+			g.in[to] = append(g.in[to], e)
+		}
+	}
+
+	return g, nil
+}
+
+// Model returns the model the graph is indexing.
+func (g *Graph) Model() *Model {
+	return g.model
+}
+
+// Resolve returns the canonical URI of the given node reference, which is
+// either a local node ID within the given layer, a URI reference relative
+// to the layer's nodes (e.g. "../../other/nodes/a"), or an absolute URI.
+// It returns false if the node is not within the model, in which case the
+// returned string is the best-effort canonical URI of the reference.
+func (g *Graph) Resolve(layerID, ref string) (string, bool) {
+	u, err := resolveReference(layerURI(g.model, layerID)+"/nodes/", ref)
+	if err != nil {
+		return ref, false
+	}
+
+	uri := u.String()
+
+	_, ok := g.nodes[uri]
+
+	return uri, ok
+}
+
+// Layer returns the layer with the given ID or canonical URI.
+func (g *Graph) Layer(id string) (*Layer, bool) {
+	if u, err := ParseURI(id); err == nil {
+		if !u.IsLayer() || u.Model != g.model.GetUri() {
+			return nil, false
+		}
+		id = u.Layer
+	}
+
+	layer, ok := g.layers[id]
+
+	return layer, ok
+}
+
+// Layers returns the layers of the model, in order.
+func (g *Graph) Layers() []*Layer {
+	return g.model.GetLayers()
+}
+
+// Node returns the node with the given canonical URI.
+func (g *Graph) Node(uri string) (*Node, bool) {
+	n, ok := g.nodes[uri]
+	return n, ok
+}
+
+// FindNode returns the canonical URI of the node with the given reference,
+// which is either a canonical node URI, or a "layer/node" path relative to
+// the model. An error is returned if the node is not within the model.
+func (g *Graph) FindNode(ref string) (string, error) {
+	uri := ref
+
+	if _, err := ParseURI(ref); err != nil {
+		layerID, nodeID, ok := strings.Cut(ref, "/")
+		if !ok {
+			return "", fmt.Errorf("invalid node %q, must be a URI or layer/node path", ref)
+		}
+		uri = nodeURI(g.model, layerID, nodeID)
+	}
+
+	if _, ok := g.nodes[uri]; !ok {
+		return "", fmt.Errorf("node %q not found in model", ref)
+	}
+
+	return uri, nil
+}
+
+// NodeLayer returns the layer containing the node with the given
+// canonical URI.
+func (g *Graph) NodeLayer(uri string) (*Layer, bool) {
+	layer, ok := g.nodeLayers[uri]
+	return layer, ok
+}
+
+// Nodes returns the canonical URIs of every node in the model, in order.
+func (g *Graph) Nodes() []string {
+	return g.nodeOrder
+}
+
+// LayerNodes returns the canonical URIs of the nodes within the layer with
+// the given ID, in order.
+func (g *Graph) LayerNodes(layerID string) []string {
+	return g.layerNodes[layerID]
+}
+
+// Link returns the link with the given canonical URI.
+func (g *Graph) Link(uri string) (*Edge, bool) {
+	e, ok := g.links[uri]
+	return e, ok
+}
+
+// Links returns every link in the model, in order.
+func (g *Graph) Links() []*Edge {
+	return g.linkOrder
+}
+
+// OutLinks returns the links coming from the node with the given
+// canonical URI.
+func (g *Graph) OutLinks(uri string) []*Edge {
+	return g.out[uri]
+}
+
+// InLinks returns the links going to the node with the given canonical
+// URI, from any layer. The node may be outside of the model, to find the
+// links going to another system.
+func (g *Graph) InLinks(uri string) []*Edge {
+	return g.in[uri]
+}
+
+// Neighbors returns the canonical URIs of the nodes within the model which
+// are linked to or from the node with the given canonical URI, without
+// duplicates. Nodes linked to are returned before nodes linked from.
+func (g *Graph) Neighbors(uri string) []string {
+	var (
+		neighbors []string
+		seen      = map[string]struct{}{}
+	)
+
+	add := func(other string) {
+		if _, ok := g.nodes[other]; !ok {
+			return
+		}
+		if _, ok := seen[other]; ok {
+			return
+		}
+		seen[other] = struct{}{}
+		neighbors = append(neighbors, other)
+	}
+
+	for _, e := range g.out[uri] {
+		add(e.To)
+	}
+
+	for _, e := range g.in[uri] {
+		add(e.From)
+	}
+
+	return neighbors
+}
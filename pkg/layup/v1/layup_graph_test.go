@@ -0,0 +1,140 @@
+package layupv1_test
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	layupv1 "github.com/picatz/layup/pkg/layup/v1"
+)
+
+func TestGraph(t *testing.T) {
+	model, err := layupv1.ParseHCL(strings.NewReader(thisProject))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	g, err := layupv1.NewGraph(model)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(g.Nodes()) != 10 {
+		t.Fatalf("expected 10 nodes, got %d", len(g.Nodes()))
+	}
+
+	if len(g.Links()) != 9 {
+		t.Fatalf("expected 9 links, got %d", len(g.Links()))
+	}
+
+	runtime := "layup://example/layers/go/nodes/runtime"
+
+	n, ok := g.Node(runtime)
+	if !ok || n.GetId() != "runtime" {
+		t.Fatalf("unexpected node: %v", n)
+	}
+
+	layer, ok := g.NodeLayer(runtime)
+	if !ok || layer.GetId() != "go" {
+		t.Fatalf("unexpected layer: %v", layer)
+	}
+
+	var from []string
+	for _, e := range g.InLinks(runtime) {
+		from = append(from, e.From)
+	}
+
+	want := []string{
+		"layup://example/layers/go/nodes/language",
+		"layup://example/layers/buf/nodes/cli",
+		"layup://example/layers/layup/nodes/cli",
+	}
+
+	if !slices.Equal(from, want) {
+		t.Fatalf("unexpected in links: %v", from)
+	}
+
+	out := g.OutLinks("layup://example/layers/buf/nodes/cli")
+	if len(out) != 2 || out[0].Link.GetId() != "maintenance" || out[0].To != "layup://example/layers/github/nodes/buf_organization" {
+		t.Fatalf("unexpected out links: %v", out)
+	}
+
+	neighbors := g.Neighbors("layup://example/layers/layup/nodes/schema")
+	if !slices.Equal(neighbors, []string{
+		"layup://example/layers/buf/nodes/cli",
+		"layup://example/layers/github/nodes/this_repository",
+		"layup://example/layers/layup/nodes/hcl",
+	}) {
+		t.Fatalf("unexpected neighbors: %v", neighbors)
+	}
+
+	e, ok := g.Link("layup://example/layers/layup/links/conversion")
+	if !ok || e.From != "layup://example/layers/layup/nodes/hcl" || e.To != "layup://example/layers/layup/nodes/schema" || e.External {
+		t.Fatalf("unexpected link: %v", e)
+	}
+
+	for _, id := range []string{"go", "layup://example/layers/go"} {
+		if layer, ok := g.Layer(id); !ok || layer.GetId() != "go" {
+			t.Fatalf("unexpected layer for %q: %v", id, layer)
+		}
+	}
+
+	if uri, ok := g.Resolve("go", "owner"); !ok || uri != "layup://example/layers/go/nodes/owner" {
+		t.Fatalf("unexpected resolved URI: %q", uri)
+	}
+
+	if _, ok := g.Resolve("go", "nope"); ok {
+		t.Fatal("expected unknown node to not resolve")
+	}
+}
+
+func TestGraph_external(t *testing.T) {
+	model, err := layupv1.ParseHCL(strings.NewReader(`
+uri = "layup://test"
+
+layer "1" {
+	node "a" {}
+
+	link "outside" {
+		from = "a"
+		to = "github://picatz/layup"
+	}
+}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	g, err := layupv1.NewGraph(model)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	in := g.InLinks("github://picatz/layup")
+	if len(in) != 1 || !in[0].External {
+		t.Fatalf("expected external link, got %v", in)
+	}
+
+	if neighbors := g.Neighbors("layup://test/layers/1/nodes/a"); len(neighbors) != 0 {
+		t.Fatalf("expected no neighbors within the model, got %v", neighbors)
+	}
+}
+
+func TestNewGraph_invalid(t *testing.T) {
+	_, err := layupv1.NewGraph(&layupv1.Model{
+		Uri: "layup://test",
+		Layers: []*layupv1.Layer{
+			{
+				Id: "1",
+				Links: []*layupv1.Link{
+					{Id: "a", From: "nope", To: "nope"},
+				},
+			},
+		},
+	})
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+
+	t.Log(err)
+}
@@ -0,0 +1,270 @@
+package layupv1
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// HashPrefix is the prefix of the hashes returned by Hash and
+// ElementHashes, naming the hash function.
+const HashPrefix = "sha256:"
+
+// canonicalModel is the canonical form of a model, which is serialized
+// as JSON by CanonicalJSON.
+type canonicalModel struct {
+	URI        string           `json:"uri"`
+	Attributes map[string]any   `json:"attributes,omitempty"`
+	Layers     []canonicalLayer `json:"layers,omitempty"`
+}
+
+type canonicalLayer struct {
+	ID         string          `json:"id"`
+	Dynamic    *bool           `json:"dynamic,omitempty"`
+	Attributes map[string]any  `json:"attributes,omitempty"`
+	Nodes      []canonicalNode `json:"nodes,omitempty"`
+	Links      []canonicalLink `json:"links,omitempty"`
+}
+
+type canonicalNode struct {
+	ID         string         `json:"id"`
+	Attributes map[string]any `json:"attributes,omitempty"`
+}
+
+type canonicalLink struct {
+	ID         string         `json:"id"`
+	From       string         `json:"from"`
+	To         string         `json:"to"`
+	Attributes map[string]any `json:"attributes,omitempty"`
+}
+
+// CanonicalJSON returns the canonical serialization of the model, which is
+// the same for models which are semantically identical, so it can be
+// hashed, signed or compared byte for byte.
+//
+// The canonical form is compact JSON, with layers, nodes and links sorted
+// by ID, attributes sorted by key, numbers in their shortest form (with
+// negative zero as zero), and links going to nodes by their canonical URI,
+// rather than a local ID. Empty attributes are omitted, as is the dynamic
+// flag of layers which don't set it.
+func CanonicalJSON(m *Model) ([]byte, error) {
+	c, err := canonicalize(m)
+	if err != nil {
+		return nil, err
+	}
+
+	return marshalCanonical(c)
+}
+
+// Hash returns the content hash of the model, which is the SHA-256 hash of
+// its canonical serialization (see CanonicalJSON), prefixed by HashPrefix.
+func Hash(m *Model) (string, error) {
+	b, err := CanonicalJSON(m)
+	if err != nil {
+		return "", err
+	}
+
+	return hashBytes(b), nil
+}
+
+// ElementHash is the content hash of a layer, node or link.
+type ElementHash struct {
+	// Element is the kind of element.
+	Element ElementKind `json:"element"`
+	// URI is the canonical URI of the element.
+	URI string `json:"uri"`
+	// Hash is the hash of the element's canonical serialization, prefixed
+	// by HashPrefix.
+	Hash string `json:"hash"`
+}
+
+// ElementHashes returns the content hashes of every layer, node and link
+// within the model, in canonical order, so changes can be detected at a
+// finer granularity than the whole model, such as to only re-render the
+// layers which changed.
+//
+// Each hash is of the element's canonical serialization (the same as
+// within CanonicalJSON), so a layer's hash covers its nodes and links.
+// Links refer to the nodes they connect by canonical URI, so their hashes
+// (and those of their layers) include the model's URI.
+func ElementHashes(m *Model) ([]ElementHash, error) {
+	c, err := canonicalize(m)
+	if err != nil {
+		return nil, err
+	}
+
+	var hashes []ElementHash
+
+	add := func(kind ElementKind, uri string, v any) error {
+		b, err := marshalCanonical(v)
+		if err != nil {
+			return err
+		}
+
+		hashes = append(hashes, ElementHash{Element: kind, URI: uri, Hash: hashBytes(b)})
+
+		return nil
+	}
+
+	for _, layer := range c.Layers {
+		if err := add(ElementLayer, layerURI(m, layer.ID), layer); err != nil {
+			return nil, err
+		}
+
+		for _, n := range layer.Nodes {
+			if err := add(ElementNode, nodeURI(m, layer.ID, n.ID), n); err != nil {
+				return nil, err
+			}
+		}
+
+		for _, link := range layer.Links {
+			if err := add(ElementLink, linkURI(m, layer.ID, link.ID), link); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return hashes, nil
+}
+
+// canonicalize returns the canonical form of the model.
+func canonicalize(m *Model) (*canonicalModel, error) {
+	g, err := NewGraph(m)
+	if err != nil {
+		return nil, fmt.Errorf("failed to index model: %w", err)
+	}
+
+	c := &canonicalModel{URI: m.GetUri()}
+
+	if c.Attributes, err = canonicalAttributes(m.GetAttributes()); err != nil {
+		return nil, fmt.Errorf("invalid model attributes: %w", err)
+	}
+
+	for _, layer := range m.GetLayers() {
+		cl := canonicalLayer{ID: layer.GetId()}
+
+		if layer.Dynamic != nil {
+			dynamic := layer.GetDynamic()
+			cl.Dynamic = &dynamic
+		}
+
+		if cl.Attributes, err = canonicalAttributes(layer.GetAttributes()); err != nil {
+			return nil, fmt.Errorf("invalid attributes of layer %q: %w", layer.GetId(), err)
+		}
+
+		for _, n := range layer.GetNodes() {
+			attrs, err := canonicalAttributes(n.GetAttributes())
+			if err != nil {
+				return nil, fmt.Errorf("invalid attributes of node %q: %w", nodeURI(m, layer.GetId(), n.GetId()), err)
+			}
+
+			cl.Nodes = append(cl.Nodes, canonicalNode{ID: n.GetId(), Attributes: attrs})
+		}
+
+		for _, link := range layer.GetLinks() {
+			uri := linkURI(m, layer.GetId(), link.GetId())
+
+			attrs, err := canonicalAttributes(link.GetAttributes())
+			if err != nil {
+				return nil, fmt.Errorf("invalid attributes of link %q: %w", uri, err)
+			}
+
+			cl.Links = append(cl.Links, canonicalLink{
+				ID:         link.GetId(),
+				From:       link.GetFrom(),
+				To:         g.links[uri].To,
+				Attributes: attrs,
+			})
+		}
+
+		sort.Slice(cl.Nodes, func(i, j int) bool { return cl.Nodes[i].ID < cl.Nodes[j].ID })
+		sort.Slice(cl.Links, func(i, j int) bool { return cl.Links[i].ID < cl.Links[j].ID })
+
+		c.Layers = append(c.Layers, cl)
+	}
+
+	sort.Slice(c.Layers, func(i, j int) bool { return c.Layers[i].ID < c.Layers[j].ID })
+
+	return c, nil
+}
+
+// canonicalAttributes returns the attributes as JSON values, with their
+// numbers normalized, or nil if there aren't any.
+func canonicalAttributes(attrs map[string]*structpb.Value) (map[string]any, error) {
+	if len(attrs) == 0 {
+		return nil, nil
+	}
+
+	c := make(map[string]any, len(attrs))
+	for k, v := range attrs {
+		cv, err := canonicalValue(v.AsInterface())
+		if err != nil {
+			return nil, fmt.Errorf("attribute %q: %w", k, err)
+		}
+		c[k] = cv
+	}
+
+	return c, nil
+}
+
+// canonicalValue returns the given JSON value with its numbers normalized.
+func canonicalValue(v any) (any, error) {
+	switch v := v.(type) {
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return nil, fmt.Errorf("%v can't be serialized", v)
+		}
+		if v == 0 {
+			// Negative zero is the same number, but is serialized as "-0".
+			return 0.0, nil
+		}
+		return v, nil
+	case map[string]any:
+		for k, fv := range v {
+			cv, err := canonicalValue(fv)
+			if err != nil {
+				return nil, err
+			}
+			v[k] = cv
+		}
+		return v, nil
+	case []any:
+		for i, ev := range v {
+			cv, err := canonicalValue(ev)
+			if err != nil {
+				return nil, err
+			}
+			v[i] = cv
+		}
+		return v, nil
+	default:
+		return v, nil
+	}
+}
+
+// marshalCanonical returns the compact JSON serialization of the value,
+// which sorts map keys, without escaping HTML characters.
+func marshalCanonical(v any) ([]byte, error) {
+	var buf bytes.Buffer
+
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+
+	if err := enc.Encode(v); err != nil {
+		return nil, fmt.Errorf("failed to serialize model: %w", err)
+	}
+
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// hashBytes returns the SHA-256 hash of b, prefixed by HashPrefix.
+func hashBytes(b []byte) string {
+	sum := sha256.Sum256(b)
+	return HashPrefix + hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,127 @@
+package layupv1_test
+
+import (
+	"strings"
+	"testing"
+
+	layupv1 "github.com/picatz/layup/pkg/layup/v1"
+)
+
+func TestCanonicalJSON(t *testing.T) {
+	m := newTestGraph(t, `
+uri = "layup://shop"
+
+layer "staff" {
+	node "cashier" {
+		till   = 1.0
+		drawer = -0
+	}
+
+	link "serves" {
+		from = node.cashier
+		to   = layer.customers.node.alice
+	}
+}
+
+layer "customers" {
+	node "bob" {}
+	node "alice" {
+		note = "<vip>"
+	}
+}
+`).Model()
+
+	b, err := layupv1.CanonicalJSON(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `{"uri":"layup://shop","layers":[` +
+		`{"id":"customers","nodes":[{"id":"alice","attributes":{"note":"<vip>"}},{"id":"bob"}]},` +
+		`{"id":"staff","nodes":[{"id":"cashier","attributes":{"drawer":0,"till":1}}],` +
+		`"links":[{"id":"serves","from":"cashier","to":"layup://shop/layers/customers/nodes/alice"}]}]}`
+
+	if string(b) != want {
+		t.Fatalf("expected:\n%s\ngot:\n%s", want, b)
+	}
+}
+
+func TestHash(t *testing.T) {
+	a := newTestGraph(t, testRecipe).Model()
+
+	// The same model, with its layers in a different order, and links
+	// going to nodes within their own layer by canonical URI.
+	ingredients, tools, _ := strings.Cut(strings.TrimPrefix(testRecipe, "\nuri = \"layup://recipe\"\n"), `layer "tools" {`)
+	b := newTestGraph(t, strings.ReplaceAll(
+		`uri = "layup://recipe"`+"\n\n"+`layer "tools" {`+tools+"\n"+ingredients,
+		"to   = node.pan", "to   = layer.tools.node.pan",
+	)).Model()
+
+	ha, err := layupv1.Hash(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hb, err := layupv1.Hash(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ha != hb {
+		t.Fatalf("expected identical models to have the same hash: %s != %s", ha, hb)
+	}
+
+	if !strings.HasPrefix(ha, layupv1.HashPrefix) || len(ha) != len(layupv1.HashPrefix)+64 {
+		t.Fatalf("unexpected hash: %s", ha)
+	}
+
+	c := newTestGraph(t, strings.Replace(testRecipe, `material = "wood"`, `material = "bamboo"`, 1)).Model()
+
+	hc, err := layupv1.Hash(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ha == hc {
+		t.Fatal("expected different models to have different hashes")
+	}
+}
+
+func TestElementHashes(t *testing.T) {
+	a := newTestGraph(t, testRecipe).Model()
+	b := newTestGraph(t, strings.Replace(testRecipe, `material = "wood"`, `material = "bamboo"`, 1)).Model()
+
+	ha, err := layupv1.ElementHashes(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hb, err := layupv1.ElementHashes(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(ha) != len(hb) || len(ha) != 2+7+6 {
+		t.Fatalf("expected a hash for each layer, node and link, got %d and %d", len(ha), len(hb))
+	}
+
+	var changed []string
+	for i := range ha {
+		if ha[i].URI != hb[i].URI {
+			t.Fatalf("expected the same order, got %s and %s", ha[i].URI, hb[i].URI)
+		}
+
+		if ha[i].Hash != hb[i].Hash {
+			changed = append(changed, ha[i].URI)
+		}
+	}
+
+	want := []string{
+		"layup://recipe/layers/tools",
+		"layup://recipe/layers/tools/nodes/spoon",
+	}
+
+	if strings.Join(changed, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("expected changed hashes:\n%s\ngot:\n%s", strings.Join(want, "\n"), strings.Join(changed, "\n"))
+	}
+}
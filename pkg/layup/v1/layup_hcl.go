@@ -3,8 +3,8 @@ package layupv1
 import (
 	"fmt"
 	"io"
+	"slices"
 
-	"github.com/bufbuild/protovalidate-go"
 	"github.com/hashicorp/hcl/v2"
 	"github.com/hashicorp/hcl/v2/hclsimple"
 	"github.com/hashicorp/hcl/v2/hclsyntax"
@@ -46,19 +46,17 @@ func ParseHCL(r io.Reader) (*Model, error) {
 		return nil, err
 	}
 
-	v, err := protovalidate.New(
-		protovalidate.WithMessages(&Model{}),
-		protovalidate.WithDisableLazy(true),
-	)
-	if err != nil {
-		return nil, err
-	}
-
 	m := &Model{
 		Uri: topLevel.URI,
 	}
 
 	if topLevelBody, ok := topLevel.Rest.(*hclsyntax.Body); ok {
+		// Any other top-level attributes are the model's attributes.
+		m.Attributes, err = parseHCLAttributes(topLevelBody.Attributes, htx, "uri")
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse model attributes: %w", err)
+		}
+
 		// TODO: consider parsing all layers, then nodes, then links.
 		for _, topLevelBlock := range topLevelBody.Blocks {
 			switch topLevelBlock.Type {
@@ -211,7 +209,7 @@ func ParseHCL(r io.Reader) (*Model, error) {
 										// 	return nil, fmt.Errorf("unknown node: %s", nodeName)
 										// }
 
-										link.To = fmt.Sprintf("%s/layers/%s/nodes/%s", m.Uri, layerName, nodeName)
+										link.To = NewNodeURI(m.Uri, layerName, nodeName).String()
 									case "node":
 										to, diags := expr.Value(layerHtx)
 										if diags.HasErrors() {
@@ -242,16 +240,25 @@ func ParseHCL(r io.Reader) (*Model, error) {
 								default:
 									return nil, fmt.Errorf("unknown to type: %#+v", expr)
 								}
-
 							}
 						}
 
+						link.Attributes, err = parseHCLAttributes(layerBlock.Body.Attributes, layerHtx, "id", "from", "to")
+						if err != nil {
+							return nil, fmt.Errorf("failed to parse link %q attributes: %w", link.Id, err)
+						}
+
 						layer.Links = append(layer.Links, link)
 					default:
 						return nil, fmt.Errorf("unknown layer block type: %s", layerBlock.Type)
 					}
 				}
 
+				layer.Attributes, err = parseHCLAttributes(topLevelBlock.Body.Attributes, layerHtx, "id")
+				if err != nil {
+					return nil, fmt.Errorf("failed to parse layer %q attributes: %w", layerID, err)
+				}
+
 				vm := htx.Variables["layer"].AsValueMap()
 
 				if vm == nil {
@@ -281,7 +288,7 @@ func ParseHCL(r io.Reader) (*Model, error) {
 
 	// Apply validation rules to the model after parsing to ensure
 	// invalid models are not created through the HCL parser.
-	err = v.Validate(m)
+	err = Validate(m)
 	if err != nil {
 		return m, err
 	}
@@ -289,6 +296,36 @@ func ParseHCL(r io.Reader) (*Model, error) {
 	return m, nil
 }
 
+// parseHCLAttributes evaluates the given HCL attributes into a map of
+// structpb.Values, ignoring any of the given reserved attribute names.
+// It returns nil if there are no attributes.
+func parseHCLAttributes(attrs hclsyntax.Attributes, htx *hcl.EvalContext, reserved ...string) (map[string]*structpb.Value, error) {
+	var values map[string]*structpb.Value
+
+	for name, attr := range attrs {
+		if slices.Contains(reserved, name) {
+			continue
+		}
+
+		val, diags := attr.Expr.Value(htx)
+		if diags.HasErrors() {
+			return nil, fmt.Errorf("failed to parse attribute %q: %w", name, diags.Errs()[0])
+		}
+
+		pbVal, err := ctyValue2PBValue(val)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert attribute %q to structpb.Value: %w", name, err)
+		}
+
+		if values == nil {
+			values = map[string]*structpb.Value{}
+		}
+		values[name] = pbVal
+	}
+
+	return values, nil
+}
+
 func ctyValue2PBValue(val cty.Value) (*structpb.Value, error) {
 	// Handle basic (primitive) types first and then handle
 	// complex types (lists, maps, objects, etc.)
@@ -304,8 +341,9 @@ func ctyValue2PBValue(val cty.Value) (*structpb.Value, error) {
 
 	// Handle complex types (lists, maps, objects, etc.)
 
-	// List types
-	if val.Type().IsListType() {
+	// List types, including tuples, which are the type of list literals
+	// like ["a", "b"].
+	if val.Type().IsListType() || val.Type().IsTupleType() || val.Type().IsSetType() {
 		list := val.AsValueSlice()
 
 		pbList := &structpb.ListValue{
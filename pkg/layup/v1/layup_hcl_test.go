@@ -157,3 +157,34 @@ layer "2" {
 
 	t.Log(m)
 }
+
+func TestParseHCL_lists(t *testing.T) {
+	config := `
+uri = "layup://test"
+
+layer "1" {
+	node "a" {
+		tags  = ["x", "y"]
+		ports = [80, 443]
+		empty = []
+	}
+}
+`
+
+	m, err := layupv1.ParseHCL(strings.NewReader(config))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	attrs := m.GetLayers()[0].GetNodes()[0].GetAttributes()
+
+	for key, want := range map[string]int{"tags": 2, "ports": 2, "empty": 0} {
+		got := attrs[key].GetListValue()
+		if got == nil {
+			t.Fatalf("expected %q to be a list, got %v", key, attrs[key])
+		}
+		if n := len(got.GetValues()); n != want {
+			t.Errorf("expected %q to have %d values, got %d", key, want, n)
+		}
+	}
+}
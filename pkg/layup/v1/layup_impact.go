@@ -0,0 +1,135 @@
+package layupv1
+
+// Colors used by Impact.RenderOptions to highlight the affected subgraph.
+const (
+	ImpactSourceColor   = "#fb8072"
+	ImpactAffectedColor = "#fdb462"
+	ImpactLinkColor     = "#e41a1c"
+)
+
+// Impact is the set of nodes affected by a change to (or failure of) a node,
+// which is everything depending on it, directly or transitively.
+type Impact struct {
+	// Node is the canonical URI of the changed node.
+	Node string `json:"node"`
+	// Affected are the nodes affected by the change, in order of increasing
+	// depth from the changed node.
+	Affected []AffectedNode `json:"affected"`
+	// Layers are the affected nodes within the model, grouped by layer,
+	// in model order.
+	Layers []AffectedLayer `json:"layers"`
+	// Links are the canonical URIs of the links between the changed and
+	// affected nodes, which make up the affected subgraph.
+	Links []string `json:"links"`
+}
+
+// AffectedNode is a node affected by a change.
+type AffectedNode struct {
+	// Node is the canonical URI of the node.
+	Node string `json:"node"`
+	// Depth is the number of links between the node and the changed node.
+	Depth int `json:"depth"`
+	// Via is the canonical URI of the link the node is affected through.
+	Via string `json:"via"`
+	// External is true if the node is outside of the model.
+	External bool `json:"external,omitempty"`
+}
+
+// AffectedLayer is a layer containing nodes affected by a change.
+type AffectedLayer struct {
+	// Layer is the ID of the layer.
+	Layer string `json:"layer"`
+	// Nodes are the canonical URIs of the affected nodes within the layer,
+	// in model order.
+	Nodes []string `json:"nodes"`
+}
+
+// Impact returns the nodes affected by a change to the node with the given
+// canonical URI, by following its links in reverse (Incoming) to find the
+// nodes which depend on it, and then the nodes depending on those, and so on.
+//
+// Which links are followed can be restricted with WithLinkIDs (or any other
+// link filter), WithLayers and WithMaxDepth. For models where links point
+// from dependencies to their dependents instead, use WithDirection(Outgoing).
+func (g *Graph) Impact(node string, opts ...TraversalOption) (*Impact, error) {
+	opts = append([]TraversalOption{WithDirection(Incoming)}, opts...)
+
+	c := newTraversalConfig(opts)
+
+	impact := &Impact{
+		Node:     node,
+		Affected: []AffectedNode{},
+		Layers:   []AffectedLayer{},
+		Links:    []string{},
+	}
+
+	affected := map[string]struct{}{}
+
+	err := g.BFS(node, func(v Visit) error {
+		affected[v.Node] = struct{}{}
+
+		if v.Via != nil {
+			impact.Affected = append(impact.Affected, AffectedNode{
+				Node:     v.Node,
+				Depth:    v.Depth,
+				Via:      v.Via.URI,
+				External: v.External,
+			})
+		}
+
+		return nil
+	}, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, layer := range g.Layers() {
+		var nodes []string
+		for _, uri := range g.LayerNodes(layer.GetId()) {
+			if _, ok := affected[uri]; ok && uri != node {
+				nodes = append(nodes, uri)
+			}
+		}
+
+		if len(nodes) > 0 {
+			impact.Layers = append(impact.Layers, AffectedLayer{Layer: layer.GetId(), Nodes: nodes})
+		}
+	}
+
+	// The affected subgraph includes every followed link between the
+	// affected nodes, not just the first link each node was reached by.
+	for _, e := range g.Links() {
+		if !c.allowsLink(e) {
+			continue
+		}
+
+		_, from := affected[e.From]
+		_, to := affected[e.To]
+
+		if from && to {
+			impact.Links = append(impact.Links, e.URI)
+		}
+	}
+
+	return impact, nil
+}
+
+// RenderOptions returns the options to highlight the affected subgraph when
+// rendering the model, with the changed node, affected nodes and the links
+// between them in their own colors.
+func (i *Impact) RenderOptions() []RenderOption {
+	nodeColors := map[string]string{i.Node: ImpactSourceColor}
+	for _, n := range i.Affected {
+		nodeColors[n.Node] = ImpactAffectedColor
+	}
+
+	linkColors := map[string]string{}
+	for _, uri := range i.Links {
+		linkColors[uri] = ImpactLinkColor
+	}
+
+	return []RenderOption{
+		WithNodeColors(nodeColors),
+		WithLinkColors(linkColors),
+	}
+}
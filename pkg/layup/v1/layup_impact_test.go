@@ -0,0 +1,80 @@
+package layupv1_test
+
+import (
+	"bytes"
+	"slices"
+	"strings"
+	"testing"
+
+	layupv1 "github.com/picatz/layup/pkg/layup/v1"
+)
+
+func TestGraph_Impact(t *testing.T) {
+	g := newTestGraph(t, thisProject)
+
+	impact, err := g.Impact("layup://example/layers/go/nodes/runtime")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var affected []string
+	for _, n := range impact.Affected {
+		affected = append(affected, n.Node)
+	}
+
+	if !slices.Equal(affected, []string{
+		"layup://example/layers/go/nodes/language",
+		"layup://example/layers/buf/nodes/cli",
+		"layup://example/layers/layup/nodes/cli",
+		"layup://example/layers/go/nodes/owner",
+		"layup://example/layers/layup/nodes/schema",
+		"layup://example/layers/layup/nodes/hcl",
+	}) {
+		t.Fatalf("unexpected affected nodes: %v", affected)
+	}
+
+	var layers []string
+	for _, l := range impact.Layers {
+		layers = append(layers, l.Layer)
+	}
+
+	if !slices.Equal(layers, []string{"go", "buf", "layup"}) {
+		t.Fatalf("unexpected affected layers: %v", layers)
+	}
+
+	if impact.Affected[0].Depth != 1 || impact.Affected[0].Via != "layup://example/layers/go/links/implementation" {
+		t.Fatalf("unexpected first affected node: %+v", impact.Affected[0])
+	}
+
+	t.Run("depth and links", func(t *testing.T) {
+		impact, err := g.Impact("layup://example/layers/go/nodes/runtime",
+			layupv1.WithMaxDepth(1),
+			layupv1.WithLinkIDs("uses"),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(impact.Affected) != 2 {
+			t.Fatalf("unexpected affected nodes: %+v", impact.Affected)
+		}
+
+		for _, n := range impact.Affected {
+			if !strings.HasSuffix(n.Via, "/links/uses") {
+				t.Fatalf("unexpected link: %q", n.Via)
+			}
+		}
+	})
+
+	t.Run("render", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		if err := layupv1.WriteDOT(&buf, g.Model(), impact.RenderOptions()...); err != nil {
+			t.Fatal(err)
+		}
+
+		if strings.Count(buf.String(), "fillcolor=") != 7 {
+			t.Fatalf("expected 7 highlighted nodes:\n%s", buf.String())
+		}
+	})
+}
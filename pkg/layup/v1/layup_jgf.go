@@ -0,0 +1,135 @@
+package layupv1
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// jgfDocument is the top-level JSON Graph Format (v2) document.
+//
+// https://jsongraphformat.info/
+type jgfDocument struct {
+	Graph jgfGraph `json:"graph"`
+}
+
+type jgfGraph struct {
+	ID       string             `json:"id,omitempty"`
+	Label    string             `json:"label,omitempty"`
+	Type     string             `json:"type,omitempty"`
+	Directed bool               `json:"directed"`
+	Metadata map[string]any     `json:"metadata,omitempty"`
+	Nodes    map[string]jgfNode `json:"nodes"`
+	Edges    []jgfEdge          `json:"edges"`
+}
+
+type jgfNode struct {
+	Label    string         `json:"label,omitempty"`
+	Metadata map[string]any `json:"metadata,omitempty"`
+}
+
+type jgfEdge struct {
+	ID       string         `json:"id,omitempty"`
+	Source   string         `json:"source"`
+	Target   string         `json:"target"`
+	Relation string         `json:"relation,omitempty"`
+	Directed bool           `json:"directed"`
+	Label    string         `json:"label,omitempty"`
+	Metadata map[string]any `json:"metadata,omitempty"`
+}
+
+// WriteJGF writes a JSON Graph Format (JGF) document to the given writer
+// using the given Layup model's data.
+//
+// JGF has no notion of nested nodes, so each layer is written as a node
+// of type "layer", and each node within it points back to the layer with
+// a "parent" metadata key. Nodes and edges are identified by their
+// canonical URIs, and any link going to a node outside of the model is
+// given an "external" node so that every edge has both of its endpoints.
+func WriteJGF(w io.Writer, m *Model) error {
+	g := jgfGraph{
+		ID:       m.GetUri(),
+		Label:    m.GetUri(),
+		Type:     "layup",
+		Directed: true,
+		Nodes:    map[string]jgfNode{},
+		Edges:    []jgfEdge{},
+	}
+
+	if attrs := attributesToMap(m.GetAttributes()); attrs != nil {
+		g.Metadata = map[string]any{
+			"attributes": attrs,
+		}
+	}
+
+	for _, layer := range m.GetLayers() {
+		lmd := map[string]any{
+			"type": "layer",
+		}
+
+		if attrs := attributesToMap(layer.GetAttributes()); attrs != nil {
+			lmd["attributes"] = attrs
+		}
+
+		g.Nodes[layerURI(m, layer.GetId())] = jgfNode{
+			Label:    layer.GetId(),
+			Metadata: lmd,
+		}
+
+		for _, n := range layer.GetNodes() {
+			nmd := map[string]any{
+				"type":   "node",
+				"layer":  layer.GetId(),
+				"parent": layerURI(m, layer.GetId()),
+			}
+
+			if attrs := attributesToMap(n.GetAttributes()); attrs != nil {
+				nmd["attributes"] = attrs
+			}
+
+			g.Nodes[nodeURI(m, layer.GetId(), n.GetId())] = jgfNode{
+				Label:    n.GetId(),
+				Metadata: nmd,
+			}
+		}
+	}
+
+	for _, layer := range m.GetLayers() {
+		for _, link := range layer.GetLinks() {
+			target := linkTargetURI(m, layer, link)
+
+			// Add a node for any link target outside of the model.
+			if _, ok := g.Nodes[target]; !ok {
+				g.Nodes[target] = jgfNode{
+					Label: target,
+					Metadata: map[string]any{
+						"type":     "external",
+						"external": true,
+					},
+				}
+			}
+
+			emd := map[string]any{
+				"layer": layer.GetId(),
+			}
+
+			if attrs := attributesToMap(link.GetAttributes()); attrs != nil {
+				emd["attributes"] = attrs
+			}
+
+			g.Edges = append(g.Edges, jgfEdge{
+				ID:       linkURI(m, layer.GetId(), link.GetId()),
+				Source:   nodeURI(m, layer.GetId(), link.GetFrom()),
+				Target:   target,
+				Relation: link.GetId(),
+				Directed: true,
+				Label:    link.GetId(),
+				Metadata: emd,
+			})
+		}
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(jgfDocument{Graph: g})
+}
@@ -0,0 +1,74 @@
+package layupv1_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	layupv1 "github.com/picatz/layup/pkg/layup/v1"
+)
+
+func TestWriteJGF(t *testing.T) {
+	model, err := layupv1.ParseHCL(strings.NewReader(thisProject))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jgfBuffer := strings.Builder{}
+
+	err = layupv1.WriteJGF(&jgfBuffer, model)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var doc struct {
+		Graph struct {
+			Directed bool `json:"directed"`
+			Nodes    map[string]struct {
+				Label    string         `json:"label"`
+				Metadata map[string]any `json:"metadata"`
+			} `json:"nodes"`
+			Edges []struct {
+				Source string `json:"source"`
+				Target string `json:"target"`
+			} `json:"edges"`
+		} `json:"graph"`
+	}
+
+	if err := json.Unmarshal([]byte(jgfBuffer.String()), &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	if !doc.Graph.Directed {
+		t.Fatal("expected directed graph")
+	}
+
+	// 4 layers + 10 nodes
+	if len(doc.Graph.Nodes) != 14 {
+		t.Fatalf("expected 14 nodes, got %d", len(doc.Graph.Nodes))
+	}
+
+	if len(doc.Graph.Edges) != 9 {
+		t.Fatalf("expected 9 edges, got %d", len(doc.Graph.Edges))
+	}
+
+	for _, e := range doc.Graph.Edges {
+		if _, ok := doc.Graph.Nodes[e.Source]; !ok {
+			t.Fatalf("edge source %q is not a node", e.Source)
+		}
+
+		if _, ok := doc.Graph.Nodes[e.Target]; !ok {
+			t.Fatalf("edge target %q is not a node", e.Target)
+		}
+	}
+
+	runtime := doc.Graph.Nodes["layup://example/layers/go/nodes/runtime"]
+	if runtime.Metadata["parent"] != "layup://example/layers/go" {
+		t.Fatalf("unexpected parent: %v", runtime.Metadata["parent"])
+	}
+
+	attrs, _ := runtime.Metadata["attributes"].(map[string]any)
+	if attrs["url"] != "https://golang.org/pkg/runtime" {
+		t.Fatalf("unexpected attributes: %v", attrs)
+	}
+}
@@ -0,0 +1,516 @@
+package layupv1
+
+import (
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Layout dimensions, in pixels, used by the layered layout engine.
+const (
+	layoutCharWidth     = 7.0
+	layoutNodeHeight    = 32.0
+	layoutLineHeight    = 16.0
+	layoutNodeMinWidth  = 48.0
+	layoutNodePadding   = 12.0
+	layoutNodeSep       = 24.0
+	layoutRankSep       = 72.0
+	layoutClusterPad    = 16.0
+	layoutClusterLabel  = 20.0
+	layoutClusterSep    = 24.0
+	layoutMargin        = 16.0
+	layoutOrderingSteps = 8
+)
+
+// layoutNode is a node positioned by the layout engine, which is either a
+// real node in the model, an external link target, or a "dummy" node used
+// to route edges which span more than one rank.
+type layoutNode struct {
+	uri   string
+	label string
+	// group is the index of the layer (cluster) the node belongs to, or
+	// the number of layers for nodes outside of the model.
+	group int
+	rank  int
+	order float64
+	dummy bool
+
+	// Center point and size of the node.
+	x, y, w, h float64
+
+	in, out []*layoutNode
+}
+
+// layoutEdge is a link between two positioned nodes, routed through any
+// dummy nodes between their ranks.
+type layoutEdge struct {
+	uri   string
+	label string
+	from  *layoutNode
+	to    *layoutNode
+	// reversed is true if the edge was reversed to break a cycle, which
+	// means the route is stored from "to" to "from".
+	reversed bool
+	loop     bool
+	// dummies are the nodes the edge is routed through, in rank order.
+	dummies []*layoutNode
+	points  [][2]float64
+}
+
+// layoutCluster is the bounding box of all the nodes within a layer.
+type layoutCluster struct {
+	uri        string
+	label      string
+	x, y, w, h float64
+}
+
+// layout is the result of a Sugiyama-style hierarchical layout of a model:
+//
+//  1. Cycles are broken by reversing edges found by a depth-first search.
+//  2. Nodes are assigned ranks (rows) using the longest path from a source.
+//  3. Edges spanning more than one rank are split with dummy nodes.
+//  4. Nodes are ordered within each rank using the barycenter heuristic
+//     to reduce the number of crossings.
+//  5. Coordinates are assigned, with each layer given its own column so
+//     that the layers can be drawn as non-overlapping clusters.
+type layout struct {
+	nodes    []*layoutNode
+	edges    []*layoutEdge
+	clusters []*layoutCluster
+	width    float64
+	height   float64
+	// rowHeight is the height of each rank, which fits the tallest node.
+	rowHeight float64
+}
+
+// newLayout computes the layout of the given model, sizing the nodes to
+// fit their (possibly multi-line) labels from the given render config.
+func newLayout(m *Model, c *renderConfig) *layout {
+	l := &layout{
+		rowHeight: layoutNodeHeight,
+	}
+
+	byURI := map[string]*layoutNode{}
+
+	for i, layer := range m.GetLayers() {
+		l.clusters = append(l.clusters, &layoutCluster{
+			uri:   layerURI(m, layer.GetId()),
+			label: layer.GetId(),
+		})
+
+		for _, n := range layer.GetNodes() {
+			ln := &layoutNode{
+				uri:   nodeURI(m, layer.GetId(), n.GetId()),
+				label: c.nodeLabel(m, layer, n),
+				group: i,
+			}
+			byURI[ln.uri] = ln
+			l.nodes = append(l.nodes, ln)
+		}
+	}
+
+	external := len(m.GetLayers())
+
+	for _, layer := range m.GetLayers() {
+		for _, link := range layer.GetLinks() {
+			from, ok := byURI[nodeURI(m, layer.GetId(), link.GetFrom())]
+			if !ok {
+				continue
+			}
+
+			target := linkTargetURI(m, layer, link)
+
+			to, ok := byURI[target]
+			if !ok {
+				to = &layoutNode{
+					uri:   target,
+					label: target,
+					group: external,
+				}
+				byURI[target] = to
+				l.nodes = append(l.nodes, to)
+			}
+
+			l.edges = append(l.edges, &layoutEdge{
+				uri:   linkURI(m, layer.GetId(), link.GetId()),
+				label: c.linkLabel(m, layer, link),
+				from:  from,
+				to:    to,
+				loop:  from == to,
+			})
+		}
+	}
+
+	for _, n := range l.nodes {
+		lines := strings.Split(n.label, "\n")
+
+		widest := 0
+		for _, line := range lines {
+			widest = max(widest, utf8.RuneCountInString(line))
+		}
+
+		n.w = max(layoutNodeMinWidth, float64(widest)*layoutCharWidth+2*layoutNodePadding)
+		n.h = layoutNodeHeight + float64(len(lines)-1)*layoutLineHeight
+		l.rowHeight = max(l.rowHeight, n.h)
+	}
+
+	l.breakCycles()
+	l.assignRanks()
+	l.insertDummies()
+	l.orderNodes()
+	l.assignCoordinates(len(m.GetLayers()) + 1)
+	l.routeEdges()
+
+	return l
+}
+
+// breakCycles reverses the edges which would otherwise create a cycle,
+// found as back edges during a depth-first search from each node.
+func (l *layout) breakCycles() {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+
+	state := map[*layoutNode]int{}
+	adj := map[*layoutNode][]*layoutEdge{}
+
+	for _, e := range l.edges {
+		if !e.loop {
+			adj[e.from] = append(adj[e.from], e)
+		}
+	}
+
+	var visit func(n *layoutNode)
+	visit = func(n *layoutNode) {
+		state[n] = visiting
+		for _, e := range adj[n] {
+			switch state[e.to] {
+			case unvisited:
+				visit(e.to)
+			case visiting:
+				e.reversed = true
+			}
+		}
+		state[n] = visited
+	}
+
+	for _, n := range l.nodes {
+		if state[n] == unvisited {
+			visit(n)
+		}
+	}
+
+	for _, e := range l.edges {
+		if e.loop {
+			continue
+		}
+
+		from, to := e.from, e.to
+		if e.reversed {
+			from, to = to, from
+		}
+
+		from.out = append(from.out, to)
+		to.in = append(to.in, from)
+	}
+}
+
+// assignRanks assigns each node a rank one greater than the highest rank
+// of the nodes linking to it, processing nodes in topological order.
+func (l *layout) assignRanks() {
+	indegree := map[*layoutNode]int{}
+	for _, n := range l.nodes {
+		indegree[n] = len(n.in)
+	}
+
+	var queue []*layoutNode
+	for _, n := range l.nodes {
+		if indegree[n] == 0 {
+			queue = append(queue, n)
+		}
+	}
+
+	for len(queue) > 0 {
+		n := queue[0]
+		queue = queue[1:]
+
+		for _, next := range n.out {
+			next.rank = max(next.rank, n.rank+1)
+
+			indegree[next]--
+			if indegree[next] == 0 {
+				queue = append(queue, next)
+			}
+		}
+	}
+}
+
+// insertDummies splits edges spanning more than one rank into a chain of
+// dummy nodes, one per rank, which are positioned like any other node and
+// later used as the points of the routed edge.
+func (l *layout) insertDummies() {
+	for _, e := range l.edges {
+		if e.loop {
+			continue
+		}
+
+		from, to := e.from, e.to
+		if e.reversed {
+			from, to = to, from
+		}
+
+		if to.rank-from.rank <= 1 {
+			continue
+		}
+
+		from.out = removeLayoutNode(from.out, to)
+		to.in = removeLayoutNode(to.in, from)
+
+		prev := from
+		for r := from.rank + 1; r < to.rank; r++ {
+			d := &layoutNode{
+				group: from.group,
+				rank:  r,
+				dummy: true,
+				w:     layoutNodeSep,
+				h:     l.rowHeight,
+			}
+			l.nodes = append(l.nodes, d)
+			e.dummies = append(e.dummies, d)
+
+			prev.out = append(prev.out, d)
+			d.in = append(d.in, prev)
+			prev = d
+		}
+
+		prev.out = append(prev.out, to)
+		to.in = append(to.in, prev)
+	}
+}
+
+// removeLayoutNode removes the first occurrence of n from the given nodes.
+func removeLayoutNode(nodes []*layoutNode, n *layoutNode) []*layoutNode {
+	for i, other := range nodes {
+		if other == n {
+			return append(nodes[:i:i], nodes[i+1:]...)
+		}
+	}
+	return nodes
+}
+
+// ranks returns the nodes within each rank, sorted by group then order.
+func (l *layout) ranks() [][]*layoutNode {
+	var ranks [][]*layoutNode
+	for _, n := range l.nodes {
+		for len(ranks) <= n.rank {
+			ranks = append(ranks, nil)
+		}
+		ranks[n.rank] = append(ranks[n.rank], n)
+	}
+
+	for _, rank := range ranks {
+		sort.SliceStable(rank, func(i, j int) bool {
+			if rank[i].group != rank[j].group {
+				return rank[i].group < rank[j].group
+			}
+			return rank[i].order < rank[j].order
+		})
+	}
+
+	return ranks
+}
+
+// orderNodes orders the nodes within each rank by sweeping down and up the
+// ranks, moving each node to the average (barycenter) position of the nodes
+// it is connected to in the previous rank.
+func (l *layout) orderNodes() {
+	ranks := l.ranks()
+
+	renumber := func(rank []*layoutNode) {
+		for i, n := range rank {
+			n.order = float64(i)
+		}
+	}
+
+	for _, rank := range ranks {
+		renumber(rank)
+	}
+
+	barycenter := func(n *layoutNode, neighbors []*layoutNode) {
+		if len(neighbors) == 0 {
+			return
+		}
+		var sum float64
+		for _, other := range neighbors {
+			sum += other.order
+		}
+		n.order = sum / float64(len(neighbors))
+	}
+
+	sortRank := func(rank []*layoutNode) {
+		sort.SliceStable(rank, func(i, j int) bool {
+			if rank[i].group != rank[j].group {
+				return rank[i].group < rank[j].group
+			}
+			return rank[i].order < rank[j].order
+		})
+		renumber(rank)
+	}
+
+	for step := 0; step < layoutOrderingSteps; step++ {
+		if step%2 == 0 {
+			for r := 1; r < len(ranks); r++ {
+				for _, n := range ranks[r] {
+					barycenter(n, n.in)
+				}
+				sortRank(ranks[r])
+			}
+		} else {
+			for r := len(ranks) - 2; r >= 0; r-- {
+				for _, n := range ranks[r] {
+					barycenter(n, n.out)
+				}
+				sortRank(ranks[r])
+			}
+		}
+	}
+}
+
+// assignCoordinates positions every node, giving each group (layer) its own
+// column wide enough for its widest rank, with ranks laid out top to bottom.
+func (l *layout) assignCoordinates(groups int) {
+	ranks := l.ranks()
+
+	// Each group uses fixed width slots, sized to its widest node, so its
+	// nodes line up neatly between ranks.
+	slots := make([]float64, groups)
+	counts := make([]int, groups)
+
+	for _, n := range l.nodes {
+		slots[n.group] = max(slots[n.group], n.w)
+	}
+
+	for _, rank := range ranks {
+		perGroup := make([]int, groups)
+		for _, n := range rank {
+			perGroup[n.group]++
+		}
+		for g, c := range perGroup {
+			counts[g] = max(counts[g], c)
+		}
+	}
+
+	columns := make([]float64, groups)
+	widths := make([]float64, groups)
+
+	x := layoutMargin
+	for g := 0; g < groups; g++ {
+		// Groups without any nodes (e.g. a layer with no nodes, or no
+		// external targets) only take up space if they are drawn.
+		if counts[g] == 0 && (g == groups-1 || g >= len(l.clusters)) {
+			continue
+		}
+
+		slots[g] = max(slots[g], layoutNodeMinWidth)
+		inner := float64(counts[g])*(slots[g]+layoutNodeSep) - layoutNodeSep
+		widths[g] = max(inner, float64(len(l.labelOf(g)))*layoutCharWidth) + 2*layoutClusterPad
+		columns[g] = x
+		x += widths[g] + layoutClusterSep
+	}
+
+	l.width = x - layoutClusterSep + layoutMargin
+
+	top := layoutMargin + layoutClusterLabel + layoutClusterPad
+
+	for r, rank := range ranks {
+		y := top + float64(r)*(l.rowHeight+layoutRankSep) + l.rowHeight/2
+
+		perGroup := map[int][]*layoutNode{}
+		for _, n := range rank {
+			perGroup[n.group] = append(perGroup[n.group], n)
+		}
+
+		for g, nodes := range perGroup {
+			inner := float64(len(nodes))*(slots[g]+layoutNodeSep) - layoutNodeSep
+			start := columns[g] + (widths[g]-inner)/2
+
+			for i, n := range nodes {
+				n.x = start + float64(i)*(slots[g]+layoutNodeSep) + slots[g]/2
+				n.y = y
+			}
+		}
+	}
+
+	rows := max(len(ranks), 1)
+	l.height = top + float64(rows)*(l.rowHeight+layoutRankSep) - layoutRankSep + layoutClusterPad + layoutMargin
+
+	for g, c := range l.clusters {
+		minRank, maxRank := -1, -1
+		for _, n := range l.nodes {
+			if n.group != g || n.dummy {
+				continue
+			}
+			if minRank == -1 || n.rank < minRank {
+				minRank = n.rank
+			}
+			maxRank = max(maxRank, n.rank)
+		}
+
+		if minRank == -1 {
+			minRank, maxRank = 0, 0
+		}
+
+		c.x = columns[g]
+		c.w = widths[g]
+		c.y = top + float64(minRank)*(l.rowHeight+layoutRankSep) - layoutClusterPad - layoutClusterLabel
+		c.h = float64(maxRank-minRank)*(l.rowHeight+layoutRankSep) + l.rowHeight + 2*layoutClusterPad + layoutClusterLabel
+	}
+}
+
+// labelOf returns the label of the cluster for the given group, if any.
+func (l *layout) labelOf(group int) string {
+	if group < len(l.clusters) {
+		return l.clusters[group].label
+	}
+	return ""
+}
+
+// routeEdges computes the points of each edge, from the bottom of its
+// source node, through any dummy nodes, to the top of its target node.
+func (l *layout) routeEdges() {
+	for _, e := range l.edges {
+		if e.loop {
+			n := e.from
+			e.points = [][2]float64{
+				{n.x + n.w/2, n.y - n.h/4},
+				{n.x + n.w/2 + layoutNodeSep, n.y - n.h/4},
+				{n.x + n.w/2 + layoutNodeSep, n.y + n.h/4},
+				{n.x + n.w/2, n.y + n.h/4},
+			}
+			continue
+		}
+
+		from, to := e.from, e.to
+		if e.reversed {
+			from, to = to, from
+		}
+
+		points := [][2]float64{{from.x, from.y + from.h/2}}
+
+		for _, d := range e.dummies {
+			points = append(points, [2]float64{d.x, d.y - d.h/2}, [2]float64{d.x, d.y + d.h/2})
+		}
+
+		points = append(points, [2]float64{to.x, to.y - to.h/2})
+
+		if e.reversed {
+			for i, j := 0, len(points)-1; i < j; i, j = i+1, j-1 {
+				points[i], points[j] = points[j], points[i]
+			}
+		}
+
+		e.points = points
+	}
+}
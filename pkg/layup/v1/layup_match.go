@@ -0,0 +1,280 @@
+package layupv1
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// Match is a set of bindings for the variables of a pattern, matched
+// against a graph.
+type Match struct {
+	// Nodes are the canonical URIs of the nodes bound to each node
+	// variable.
+	Nodes map[string]string `json:"nodes"`
+	// Links are the canonical URIs of the links bound to each link
+	// variable, in order, which is a single link unless the link pattern
+	// has a number of hops.
+	Links map[string][]string `json:"links"`
+}
+
+// MatchResult is the result of matching a pattern against a graph.
+type MatchResult struct {
+	// Variables are the names of the variables in the pattern, in order.
+	Variables []string `json:"variables"`
+	// Matches are the bindings for every match of the pattern.
+	Matches []Match `json:"matches"`
+}
+
+// Match returns the matches of the given pattern (see Pattern) within the
+// graph, such as:
+//
+//	(i:ingredients)-[:add_*]->(t:tools)-[:pour]->(:tools {material: "wood"})
+//
+// Only nodes within the model are matched. A link is never used more than
+// once within a match, which ensures variable-length link patterns end.
+//
+// WithLayers and link filters (such as WithLinkIDs) restrict the nodes and
+// links which can be matched, in addition to the pattern, and WithMaxPaths
+// limits the number of matches.
+func (g *Graph) Match(pattern string, opts ...TraversalOption) (*MatchResult, error) {
+	p, err := ParsePattern(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	return g.MatchPattern(p, opts...)
+}
+
+// MatchPattern returns the matches of the given parsed pattern within the
+// graph, like Match.
+func (g *Graph) MatchPattern(p *Pattern, opts ...TraversalOption) (*MatchResult, error) {
+	if len(p.Nodes) == 0 || len(p.Links) != len(p.Nodes)-1 {
+		return nil, fmt.Errorf("invalid pattern: must have one more node than links")
+	}
+
+	m := &matcher{
+		graph:   g,
+		pattern: p,
+		config:  newTraversalConfig(opts),
+		nodes:   map[string]string{},
+		links:   map[string][]string{},
+		used:    map[*Edge]struct{}{},
+		result: &MatchResult{
+			Variables: []string{},
+			Matches:   []Match{},
+		},
+	}
+
+	seen := map[string]struct{}{}
+	for i, n := range p.Nodes {
+		if i > 0 {
+			if v := p.Links[i-1].Variable; v != "" {
+				m.result.Variables = append(m.result.Variables, v)
+			}
+		}
+
+		if _, ok := seen[n.Variable]; n.Variable != "" && !ok {
+			seen[n.Variable] = struct{}{}
+			m.result.Variables = append(m.result.Variables, n.Variable)
+		}
+	}
+
+	for _, uri := range g.Nodes() {
+		if m.done() {
+			break
+		}
+		m.matchNode(0, uri)
+	}
+
+	return m.result, nil
+}
+
+// matcher is the state of a backtracking search for matches of a pattern.
+type matcher struct {
+	graph   *Graph
+	pattern *Pattern
+	config  *traversalConfig
+
+	// nodes and links are the current bindings of the variables.
+	nodes map[string]string
+	links map[string][]string
+	// used are the links within the current (partial) match.
+	used map[*Edge]struct{}
+
+	result *MatchResult
+}
+
+// done reports whether the maximum number of matches has been found.
+func (m *matcher) done() bool {
+	return m.config.maxPaths >= 0 && len(m.result.Matches) >= m.config.maxPaths
+}
+
+// matchNode matches the node with the given URI against the i-th node
+// pattern, and the rest of the pattern from there.
+func (m *matcher) matchNode(i int, uri string) {
+	np := m.pattern.Nodes[i]
+
+	if !m.nodeMatches(np, uri) {
+		return
+	}
+
+	if np.Variable != "" {
+		bound, ok := m.nodes[np.Variable]
+		if ok && bound != uri {
+			return
+		}
+
+		if !ok {
+			m.nodes[np.Variable] = uri
+			defer delete(m.nodes, np.Variable)
+		}
+	}
+
+	if i == len(m.pattern.Nodes)-1 {
+		m.record()
+		return
+	}
+
+	m.hop(i, uri, nil)
+}
+
+// hop follows the links matching the i-th link pattern from the node with
+// the given URI, having already followed the given path, matching the next
+// node pattern wherever the number of hops is within bounds.
+func (m *matcher) hop(i int, uri string, path []*Edge) {
+	lp := m.pattern.Links[i]
+
+	if len(path) >= lp.MinHops {
+		if lp.Variable != "" {
+			uris := make([]string, 0, len(path))
+			for _, e := range path {
+				uris = append(uris, e.URI)
+			}
+			m.links[lp.Variable] = uris
+		}
+
+		m.matchNode(i+1, uri)
+
+		if lp.Variable != "" {
+			delete(m.links, lp.Variable)
+		}
+	}
+
+	if lp.MaxHops >= 0 && len(path) >= lp.MaxHops {
+		return
+	}
+
+	for _, s := range m.steps(lp, uri) {
+		if m.done() {
+			return
+		}
+
+		if _, ok := m.used[s.edge]; ok {
+			continue
+		}
+
+		m.used[s.edge] = struct{}{}
+		m.hop(i, s.node, append(path[:len(path):len(path)], s.edge))
+		delete(m.used, s.edge)
+	}
+}
+
+// steps returns the links matching the given link pattern from the node
+// with the given URI, with the nodes they lead to.
+func (m *matcher) steps(lp *LinkPattern, uri string) []step {
+	var steps []step
+
+	add := func(e *Edge, node string) {
+		if e.External || !m.config.allowsLink(e) || !matchesGlobs(lp.IDs, e.Link.GetId()) || !attributesMatch(e.Link.GetAttributes(), lp.Attributes) {
+			return
+		}
+		steps = append(steps, step{edge: e, node: node})
+	}
+
+	if lp.Direction == Outgoing || lp.Direction == Both {
+		for _, e := range m.graph.OutLinks(uri) {
+			add(e, e.To)
+		}
+	}
+
+	if lp.Direction == Incoming || lp.Direction == Both {
+		for _, e := range m.graph.InLinks(uri) {
+			// Links to the same node were already added as outgoing.
+			if lp.Direction == Both && e.From == e.To {
+				continue
+			}
+			add(e, e.From)
+		}
+	}
+
+	return steps
+}
+
+// nodeMatches reports whether the node with the given URI matches the
+// given node pattern.
+func (m *matcher) nodeMatches(np *NodePattern, uri string) bool {
+	n, ok := m.graph.nodes[uri]
+	if !ok || !m.config.allowsNode(m.graph, uri) {
+		return false
+	}
+
+	return matchesGlobs(np.Layers, m.graph.nodeLayers[uri].GetId()) && attributesMatch(n.GetAttributes(), np.Attributes)
+}
+
+// record adds the current bindings as a match.
+func (m *matcher) record() {
+	match := Match{
+		Nodes: make(map[string]string, len(m.nodes)),
+		Links: make(map[string][]string, len(m.links)),
+	}
+
+	for k, v := range m.nodes {
+		match.Nodes[k] = v
+	}
+
+	for k, v := range m.links {
+		match.Links[k] = v
+	}
+
+	m.result.Matches = append(m.result.Matches, match)
+}
+
+// attributesMatch reports whether the given attributes have all of the
+// wanted attributes, with equal values.
+func attributesMatch(attrs, want map[string]*structpb.Value) bool {
+	for k, v := range want {
+		got, ok := attrs[k]
+		if !ok || !proto.Equal(got, v) {
+			return false
+		}
+	}
+	return true
+}
+
+// WriteMatchTable writes the matches as a table, with a column for each
+// variable, and a row for each match. Links bound to a variable with more
+// than one hop are separated by commas.
+func WriteMatchTable(w io.Writer, r *MatchResult) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(tw, strings.Join(r.Variables, "\t"))
+
+	for _, match := range r.Matches {
+		row := make([]string, 0, len(r.Variables))
+		for _, v := range r.Variables {
+			if uri, ok := match.Nodes[v]; ok {
+				row = append(row, uri)
+			} else {
+				row = append(row, strings.Join(match.Links[v], ","))
+			}
+		}
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+
+	return tw.Flush()
+}
@@ -0,0 +1,160 @@
+package layupv1_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	layupv1 "github.com/picatz/layup/pkg/layup/v1"
+)
+
+const testRecipe = `
+uri = "layup://recipe"
+
+layer "ingredients" {
+	node "flour" {}
+	node "sugar" {}
+	node "milk" {}
+
+	link "add_flour" {
+		from = node.flour
+		to   = layer.tools.node.bowl
+	}
+
+	link "add_sugar" {
+		from = node.sugar
+		to   = layer.tools.node.bowl
+	}
+
+	link "pour_milk" {
+		from = node.milk
+		to   = layer.tools.node.jug
+	}
+}
+
+layer "tools" {
+	node "bowl" {
+		material = "glass"
+	}
+
+	node "jug" {
+		material = "plastic"
+	}
+
+	node "pan" {
+		material = "steel"
+	}
+
+	node "spoon" {
+		material = "wood"
+	}
+
+	link "pour" {
+		from = node.bowl
+		to   = node.pan
+	}
+
+	link "pour_jug" {
+		from = node.jug
+		to   = node.bowl
+	}
+
+	link "stir" {
+		from = node.spoon
+		to   = node.pan
+	}
+}
+`
+
+func TestGraph_Match(t *testing.T) {
+	g := newTestGraph(t, testRecipe)
+
+	tests := []struct {
+		name    string
+		pattern string
+		want    []string
+	}{
+		{
+			name:    "chain",
+			pattern: `(i:ingredients)-[:add_*]->(t:tools)-[:pour]->(p:tools)`,
+			want:    []string{"flour bowl pan", "sugar bowl pan"},
+		},
+		{
+			name:    "attributes",
+			pattern: `(t:tools {material: "wood"})--(p)`,
+			want:    []string{"spoon pan"},
+		},
+		{
+			name:    "incoming",
+			pattern: `(p {material: "steel"})<-[:pour|stir]-(t)`,
+			want:    []string{"pan bowl", "pan spoon"},
+		},
+		{
+			name:    "variable length",
+			pattern: `(i:ingredients)-[*2..]->(p:tools {material: "steel"})`,
+			want:    []string{"flour pan", "sugar pan", "milk pan"},
+		},
+		{
+			name:    "same node",
+			pattern: `(a)-->(b)<--(a)`,
+			want:    nil,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r, err := g.Match(test.pattern)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got []string
+			for _, m := range r.Matches {
+				var ids []string
+				for _, v := range r.Variables {
+					if uri, ok := m.Nodes[v]; ok {
+						ids = append(ids, uri[strings.LastIndex(uri, "/")+1:])
+					}
+				}
+				got = append(got, strings.Join(ids, " "))
+			}
+
+			if strings.Join(got, ";") != strings.Join(test.want, ";") {
+				t.Fatalf("expected %q, got %q", test.want, got)
+			}
+		})
+	}
+}
+
+func TestGraph_Match_links(t *testing.T) {
+	g := newTestGraph(t, testRecipe)
+
+	r, err := g.Match(`(:ingredients {})-[r *]->(p {material: "steel"})`, layupv1.WithMaxPaths(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(r.Matches) != 1 {
+		t.Fatalf("unexpected matches: %v", r.Matches)
+	}
+
+	want := []string{
+		"layup://recipe/layers/ingredients/links/add_flour",
+		"layup://recipe/layers/tools/links/pour",
+	}
+
+	if got := r.Matches[0].Links["r"]; strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+
+	var buf bytes.Buffer
+
+	if err := layupv1.WriteMatchTable(&buf, r); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[0], "r ") || !strings.Contains(lines[1], strings.Join(want, ",")) {
+		t.Fatalf("unexpected table:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,427 @@
+package layupv1
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// MergeStrategy decides how conflicts are resolved when merging models,
+// which is when layers, nodes or links with the same IDs have different
+// values for the same field, such as an attribute.
+type MergeStrategy int
+
+const (
+	// MergeError fails the merge if there are any conflicts.
+	MergeError MergeStrategy = iota
+	// MergeLastWins resolves conflicts with the value from the last model
+	// being merged, for each conflicting field (and attribute).
+	MergeLastWins
+	// MergeDeep merges object attributes recursively, resolving conflicts
+	// within them with the value from the last model, like MergeLastWins.
+	MergeDeep
+)
+
+// String returns the name of the strategy.
+func (s MergeStrategy) String() string {
+	switch s {
+	case MergeError:
+		return "error"
+	case MergeLastWins:
+		return "last-wins"
+	case MergeDeep:
+		return "deep"
+	default:
+		return fmt.Sprintf("MergeStrategy(%d)", int(s))
+	}
+}
+
+// ParseMergeStrategy returns the strategy with the given name.
+func ParseMergeStrategy(s string) (MergeStrategy, error) {
+	for _, strategy := range []MergeStrategy{MergeError, MergeLastWins, MergeDeep} {
+		if strategy.String() == s {
+			return strategy, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown merge strategy %q, must be one of: error, last-wins, deep", s)
+}
+
+// MergeConflict is a field with different values in two of the models
+// being merged.
+type MergeConflict struct {
+	// URI is the canonical URI of the element within the merged model.
+	URI string `json:"uri"`
+	// Field is the name of the conflicting field, like the fields of a
+	// FieldChange, with nested attributes separated by dots when using
+	// MergeDeep.
+	Field string `json:"field"`
+	// Current is the value merged from the previous models.
+	Current MergeValue `json:"current"`
+	// Incoming is the value from the model being merged.
+	Incoming MergeValue `json:"incoming"`
+}
+
+// MergeValue is the value of a conflicting field, with the model it came
+// from.
+type MergeValue struct {
+	// Source is the name of the model the value came from.
+	Source string `json:"source"`
+	// Value is the value of the field.
+	Value any `json:"value"`
+}
+
+// String returns a description of the conflict.
+func (c MergeConflict) String() string {
+	return fmt.Sprintf("%s %s: %s (%s) != %s (%s)", c.URI, c.Field,
+		formatFieldValue(c.Current.Value), c.Current.Source,
+		formatFieldValue(c.Incoming.Value), c.Incoming.Source,
+	)
+}
+
+// MergeConflictError is returned when merging models with conflicts using
+// the MergeError strategy.
+type MergeConflictError struct {
+	// Conflicts are all of the conflicts between the models.
+	Conflicts []MergeConflict
+}
+
+// Error returns a description of the first conflict.
+func (e *MergeConflictError) Error() string {
+	if len(e.Conflicts) == 0 {
+		return "models have conflicts"
+	}
+
+	msg := "models have conflicts: " + e.Conflicts[0].String()
+	if len(e.Conflicts) > 1 {
+		msg += fmt.Sprintf(" (and %d more)", len(e.Conflicts)-1)
+	}
+
+	return msg
+}
+
+// MergeResult is the result of merging models.
+type MergeResult struct {
+	// Model is the merged model.
+	Model *Model `json:"-"`
+	// Conflicts are the conflicts which were resolved by the strategy.
+	Conflicts []MergeConflict `json:"conflicts"`
+}
+
+// Merger merges models describing overlapping parts of the same system,
+// such as models maintained by separate teams.
+//
+// The zero value merges models using the MergeError strategy.
+type Merger struct {
+	// Strategy decides how conflicts are resolved.
+	Strategy MergeStrategy
+	// Sources are the names of the models being merged, in order, which
+	// are used to report conflicts, such as their file paths. Models
+	// without a name are named by their index, such as "models[0]".
+	Sources []string
+}
+
+// Merge merges the given models using the MergeError strategy, returning
+// a *MergeConflictError if they have any conflicts.
+func Merge(models ...*Model) (*Model, error) {
+	result, err := (&Merger{}).Merge(models...)
+	if err != nil {
+		return nil, err
+	}
+	return result.Model, nil
+}
+
+// Merge merges the given models into a single model, in order.
+//
+// Layers with the same ID are merged, as are nodes and links with the same
+// IDs within them, by merging their attributes, so each model only needs
+// to describe its part of the system. Elements are kept in the order they
+// were first seen in. The merged model has the URI of the first model (or
+// the last with MergeLastWins and MergeDeep, if they differ), and links
+// using the canonical URIs of nodes within another model are rewritten to
+// use the merged model's URI.
+//
+// Fields with different values (including the "from" and "to" of links,
+// compared by canonical URI) are conflicts, which are resolved using the
+// configured strategy. The merged model is validated, so it can be used
+// like any other.
+func (mg *Merger) Merge(models ...*Model) (*MergeResult, error) {
+	if len(models) == 0 {
+		return nil, fmt.Errorf("no models to merge")
+	}
+
+	m := &merge{
+		merger: mg,
+		model:  &Model{},
+		layers: map[string]*Layer{},
+		nodes:  map[string]*Node{},
+		links:  map[string]*mergedLink{},
+		result: &MergeResult{Conflicts: []MergeConflict{}},
+	}
+
+	// The URI of the merged model is resolved first, so the canonical URIs
+	// of its elements don't change while merging.
+	m.model.Uri = models[0].GetUri()
+	m.setSources(m.model.GetUri(), mg.source(0), "uri")
+	for i, model := range models[1:] {
+		uri := m.model.GetUri()
+		if model.GetUri() != uri && m.conflict(uri, "uri", uri, model.GetUri(), mg.source(i+1)) {
+			m.model.Uri = model.GetUri()
+			m.setSources(m.model.GetUri(), mg.source(i+1), "uri")
+		}
+	}
+
+	for i, model := range models {
+		if err := m.add(i, model); err != nil {
+			return nil, err
+		}
+	}
+
+	if mg.Strategy == MergeError && len(m.result.Conflicts) > 0 {
+		return nil, &MergeConflictError{Conflicts: m.result.Conflicts}
+	}
+
+	if err := Validate(m.model); err != nil {
+		return nil, fmt.Errorf("failed to merge models: %w", err)
+	}
+
+	m.result.Model = m.model
+
+	return m.result, nil
+}
+
+// source returns the name of the i-th model being merged.
+func (mg *Merger) source(i int) string {
+	if i < len(mg.Sources) && mg.Sources[i] != "" {
+		return mg.Sources[i]
+	}
+	return fmt.Sprintf("models[%d]", i)
+}
+
+// merge is the state of a merge, with the merged elements by path.
+type merge struct {
+	merger *Merger
+	model  *Model
+	layers map[string]*Layer
+	nodes  map[string]*Node
+	links  map[string]*mergedLink
+
+	// sources are the names of the models each field of each element
+	// was last set by, to report conflicts, by element URI and field.
+	sources map[string]map[string]string
+
+	result *MergeResult
+}
+
+// mergedLink is a link within the merged model, with the canonical URI of
+// the node it is going to within the merged model.
+type mergedLink struct {
+	link *Link
+	to   string
+}
+
+// add merges the i-th model.
+func (m *merge) add(i int, model *Model) error {
+	g, err := NewGraph(model)
+	if err != nil {
+		return fmt.Errorf("failed to index %s: %w", m.merger.source(i), err)
+	}
+
+	source := m.merger.source(i)
+
+	m.model.Attributes = m.attributes(m.model.GetUri(), m.model.GetAttributes(), model.GetAttributes(), source)
+
+	for _, layer := range model.GetLayers() {
+		id := layer.GetId()
+
+		merged, ok := m.layers[id]
+		if !ok {
+			merged = &Layer{Id: id}
+			if layer.Dynamic != nil {
+				merged.Dynamic = proto.Bool(layer.GetDynamic())
+			}
+			m.layers[id] = merged
+			m.model.Layers = append(m.model.Layers, merged)
+			m.setSources(layerURI(m.model, id), source, "dynamic")
+		} else if layer.Dynamic != nil && merged.GetDynamic() != layer.GetDynamic() {
+			if merged.Dynamic == nil || m.conflict(layerURI(m.model, id), "dynamic", merged.GetDynamic(), layer.GetDynamic(), source) {
+				merged.Dynamic = proto.Bool(layer.GetDynamic())
+				m.setSources(layerURI(m.model, id), source, "dynamic")
+			}
+		}
+
+		merged.Attributes = m.attributes(layerURI(m.model, id), merged.GetAttributes(), layer.GetAttributes(), source)
+
+		for _, n := range layer.GetNodes() {
+			key := id + "/" + n.GetId()
+
+			node, ok := m.nodes[key]
+			if !ok {
+				node = &Node{Id: n.GetId()}
+				m.nodes[key] = node
+				merged.Nodes = append(merged.Nodes, node)
+			}
+
+			node.Attributes = m.attributes(nodeURI(m.model, id, n.GetId()), node.GetAttributes(), n.GetAttributes(), source)
+		}
+
+		for _, link := range layer.GetLinks() {
+			key := id + "/" + link.GetId()
+			uri := linkURI(m.model, id, link.GetId())
+
+			e := g.links[linkURI(model, id, link.GetId())]
+
+			to := rebaseURI(e.To, model, m.model)
+
+			incoming := proto.Clone(link).(*Link)
+			incoming.Attributes = nil
+			if !e.External && link.GetTo() != g.nodes[e.To].GetId() {
+				// Links to nodes in other layers use canonical URIs, which
+				// must be within the merged model.
+				incoming.To = to
+			}
+
+			existing, ok := m.links[key]
+			if !ok {
+				existing = &mergedLink{link: incoming, to: to}
+				m.links[key] = existing
+				merged.Links = append(merged.Links, incoming)
+				m.setSources(uri, source, "from", "to")
+			} else {
+				if incoming.GetFrom() != existing.link.GetFrom() {
+					if m.conflict(uri, "from", existing.link.GetFrom(), incoming.GetFrom(), source) {
+						existing.link.From = incoming.GetFrom()
+						m.setSources(uri, source, "from")
+					}
+				}
+
+				if to != existing.to {
+					if m.conflict(uri, "to", existing.to, to, source) {
+						existing.link.To = incoming.GetTo()
+						existing.to = to
+						m.setSources(uri, source, "to")
+					}
+				}
+			}
+
+			existing.link.Attributes = m.attributes(uri, existing.link.GetAttributes(), link.GetAttributes(), source)
+		}
+	}
+
+	return nil
+}
+
+// attributes returns the merged attributes of the element with the given
+// URI, merging the incoming attributes into the current ones.
+func (m *merge) attributes(uri string, current, incoming map[string]*structpb.Value, source string) map[string]*structpb.Value {
+	if len(incoming) == 0 {
+		return current
+	}
+
+	merged := cloneAttributes(current)
+	if merged == nil {
+		merged = map[string]*structpb.Value{}
+	}
+
+	keys := make([]string, 0, len(incoming))
+	for k := range incoming {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		merged[k] = m.value(uri, "attributes."+k, merged[k], incoming[k], source)
+	}
+
+	return merged
+}
+
+// value returns the merged value of the given field, reporting a conflict
+// if both values are set, and different.
+func (m *merge) value(uri, field string, current, incoming *structpb.Value, source string) *structpb.Value {
+	if current == nil {
+		m.setSources(uri, source, field)
+		return proto.Clone(incoming).(*structpb.Value)
+	}
+
+	if proto.Equal(current, incoming) {
+		return current
+	}
+
+	// Objects are merged recursively with MergeDeep, so only their fields
+	// with different values are conflicts.
+	cs, cok := current.GetKind().(*structpb.Value_StructValue)
+	is, iok := incoming.GetKind().(*structpb.Value_StructValue)
+
+	if m.merger.Strategy == MergeDeep && cok && iok {
+		fields := map[string]*structpb.Value{}
+		for k, v := range cs.StructValue.GetFields() {
+			fields[k] = v
+		}
+
+		keys := make([]string, 0, len(is.StructValue.GetFields()))
+		for k := range is.StructValue.GetFields() {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		for _, k := range keys {
+			fields[k] = m.value(uri, field+"."+k, fields[k], is.StructValue.GetFields()[k], source)
+		}
+
+		return structpb.NewStructValue(&structpb.Struct{Fields: fields})
+	}
+
+	if m.conflict(uri, field, current.AsInterface(), incoming.AsInterface(), source) {
+		m.setSources(uri, source, field)
+		return proto.Clone(incoming).(*structpb.Value)
+	}
+
+	return current
+}
+
+// conflict reports a conflict for the given field, and returns whether the
+// incoming value should replace the current one.
+func (m *merge) conflict(uri, field string, current, incoming any, source string) bool {
+	m.result.Conflicts = append(m.result.Conflicts, MergeConflict{
+		URI:      uri,
+		Field:    field,
+		Current:  MergeValue{Source: m.source(uri, field), Value: current},
+		Incoming: MergeValue{Source: source, Value: incoming},
+	})
+
+	return m.merger.Strategy != MergeError
+}
+
+// source returns the name of the model the given field of an element was
+// last set by, which may have set the whole object containing it.
+func (m *merge) source(uri, field string) string {
+	for {
+		if source, ok := m.sources[uri][field]; ok {
+			return source
+		}
+
+		i := strings.LastIndex(field, ".")
+		if i < 0 {
+			return ""
+		}
+		field = field[:i]
+	}
+}
+
+// setSources records the source of the given fields of an element.
+func (m *merge) setSources(uri, source string, fields ...string) {
+	if m.sources == nil {
+		m.sources = map[string]map[string]string{}
+	}
+
+	if m.sources[uri] == nil {
+		m.sources[uri] = map[string]string{}
+	}
+
+	for _, field := range fields {
+		m.sources[uri][field] = source
+	}
+}
@@ -0,0 +1,249 @@
+package layupv1_test
+
+import (
+	"errors"
+	"testing"
+
+	layupv1 "github.com/picatz/layup/pkg/layup/v1"
+)
+
+const testMergeKitchen = `
+uri = "layup://recipe"
+
+layer "tools" {
+	node "bowl" {
+		material = "glass"
+		size = {
+			litres = 2
+			shape  = "round"
+		}
+	}
+
+	node "oven" {}
+
+	link "bake" {
+		from = node.bowl
+		to   = node.oven
+	}
+}
+
+layer "staff" {
+	node "chef" {}
+
+	link "uses" {
+		from = node.chef
+		to   = layer.tools.node.oven
+	}
+}
+`
+
+func TestMerge(t *testing.T) {
+	m, err := layupv1.Merge(
+		newTestGraph(t, testRecipe).Model(),
+		newTestGraph(t, testMergeKitchen).Model(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	g, err := layupv1.NewGraph(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var layers []string
+	for _, layer := range m.GetLayers() {
+		layers = append(layers, layer.GetId())
+	}
+
+	if len(layers) != 3 || layers[0] != "ingredients" || layers[1] != "tools" || layers[2] != "staff" {
+		t.Fatalf("unexpected layers: %v", layers)
+	}
+
+	for _, uri := range []string{
+		"layup://recipe/layers/tools/nodes/pan",
+		"layup://recipe/layers/tools/nodes/oven",
+		"layup://recipe/layers/staff/nodes/chef",
+	} {
+		if _, ok := g.Node(uri); !ok {
+			t.Fatalf("expected node %q", uri)
+		}
+	}
+
+	bowl, _ := g.Node("layup://recipe/layers/tools/nodes/bowl")
+	if bowl.GetAttributes()["material"].GetStringValue() != "glass" || bowl.GetAttributes()["size"] == nil {
+		t.Fatalf("unexpected bowl attributes: %v", bowl.GetAttributes())
+	}
+
+	if len(g.OutLinks("layup://recipe/layers/tools/nodes/bowl")) != 2 {
+		t.Fatalf("expected the bowl to have the links of both models")
+	}
+}
+
+func TestMerge_conflicts(t *testing.T) {
+	a := newTestGraph(t, testMergeKitchen).Model()
+	b := newTestGraph(t, `
+uri = "layup://recipe"
+
+layer "tools" {
+	node "bowl" {
+		material = "steel"
+		size = {
+			litres = 3
+			shape  = "round"
+			colour = "silver"
+		}
+	}
+
+	node "oven" {}
+	node "hob" {}
+
+	link "bake" {
+		from = node.bowl
+		to   = node.hob
+	}
+}
+`).Model()
+
+	_, err := layupv1.Merge(a, b)
+
+	var conflictErr *layupv1.MergeConflictError
+	if !errors.As(err, &conflictErr) {
+		t.Fatalf("expected conflict error, got: %v", err)
+	}
+
+	if len(conflictErr.Conflicts) != 3 {
+		t.Fatalf("expected 3 conflicts, got: %v", conflictErr.Conflicts)
+	}
+
+	tests := []struct {
+		strategy  layupv1.MergeStrategy
+		conflicts []string
+		material  string
+		size      map[string]any
+	}{
+		{
+			strategy:  layupv1.MergeLastWins,
+			conflicts: []string{"attributes.material", "attributes.size", "to"},
+			material:  "steel",
+			size:      map[string]any{"litres": 3.0, "shape": "round", "colour": "silver"},
+		},
+		{
+			strategy:  layupv1.MergeDeep,
+			conflicts: []string{"attributes.material", "attributes.size.litres", "to"},
+			material:  "steel",
+			size:      map[string]any{"litres": 3.0, "shape": "round", "colour": "silver"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.strategy.String(), func(t *testing.T) {
+			mg := &layupv1.Merger{
+				Strategy: test.strategy,
+				Sources:  []string{"a.hcl", "b.hcl"},
+			}
+
+			result, err := mg.Merge(a, b)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(result.Conflicts) != len(test.conflicts) {
+				t.Fatalf("expected %d conflicts, got: %v", len(test.conflicts), result.Conflicts)
+			}
+
+			for i, c := range result.Conflicts {
+				if c.Field != test.conflicts[i] {
+					t.Fatalf("expected conflict on %q, got: %v", test.conflicts[i], c)
+				}
+
+				if c.Current.Source != "a.hcl" || c.Incoming.Source != "b.hcl" {
+					t.Fatalf("unexpected sources: %v", c)
+				}
+			}
+
+			g, err := layupv1.NewGraph(result.Model)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			bowl, _ := g.Node("layup://recipe/layers/tools/nodes/bowl")
+			if got := bowl.GetAttributes()["material"].GetStringValue(); got != test.material {
+				t.Fatalf("expected material %q, got %q", test.material, got)
+			}
+
+			size := bowl.GetAttributes()["size"].GetStructValue().AsMap()
+			if len(size) != len(test.size) {
+				t.Fatalf("expected size %v, got %v", test.size, size)
+			}
+			for k, v := range test.size {
+				if size[k] != v {
+					t.Fatalf("expected size %v, got %v", test.size, size)
+				}
+			}
+
+			bake, ok := g.Link("layup://recipe/layers/tools/links/bake")
+			if !ok || bake.To != "layup://recipe/layers/tools/nodes/hob" {
+				t.Fatalf("unexpected bake link: %v", bake)
+			}
+		})
+	}
+}
+
+func TestMerge_uri(t *testing.T) {
+	a := newTestGraph(t, testMergeKitchen).Model()
+	b := newTestGraph(t, `
+uri = "layup://kitchen"
+
+layer "staff" {
+	node "chef" {}
+
+	link "uses" {
+		from = node.chef
+		to   = layer.tools.node.oven
+	}
+}
+`).Model()
+
+	result, err := (&layupv1.Merger{Strategy: layupv1.MergeLastWins}).Merge(a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.Model.GetUri() != "layup://kitchen" {
+		t.Fatalf("unexpected uri: %q", result.Model.GetUri())
+	}
+
+	// Both models link the chef to the oven in their own model, which is
+	// the same node once merged.
+	if len(result.Conflicts) != 1 || result.Conflicts[0].Field != "uri" {
+		t.Fatalf("unexpected conflicts: %v", result.Conflicts)
+	}
+
+	if result.Conflicts[0].Current.Source != "models[0]" || result.Conflicts[0].Incoming.Source != "models[1]" {
+		t.Fatalf("unexpected sources: %v", result.Conflicts[0])
+	}
+
+	g, err := layupv1.NewGraph(result.Model)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	uses, ok := g.Link("layup://kitchen/layers/staff/links/uses")
+	if !ok || uses.External || uses.To != "layup://kitchen/layers/tools/nodes/oven" {
+		t.Fatalf("unexpected uses link: %v", uses)
+	}
+}
+
+func TestParseMergeStrategy(t *testing.T) {
+	for _, s := range []layupv1.MergeStrategy{layupv1.MergeError, layupv1.MergeLastWins, layupv1.MergeDeep} {
+		got, err := layupv1.ParseMergeStrategy(s.String())
+		if err != nil || got != s {
+			t.Fatalf("expected %v, got %v (%v)", s, got, err)
+		}
+	}
+
+	if _, err := layupv1.ParseMergeStrategy("first-wins"); err == nil {
+		t.Fatal("expected error")
+	}
+}
@@ -12,7 +12,16 @@ import (
 
 // WriteMermiad writes a Mermaid graph to the given writer using the
 // given Layup model's data.
-func WriteMermiad(w io.Writer, m *Model) error {
+func WriteMermiad(w io.Writer, m *Model, opts ...RenderOption) error {
+	c := newRenderConfig(opts)
+
+	// Mermaid styles are written after the graph, since links can only
+	// be styled by the order they were written in.
+	var (
+		styles    []string
+		linkIndex int
+	)
+
 	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
 	defer tw.Flush()
 
@@ -25,7 +34,11 @@ func WriteMermiad(w io.Writer, m *Model) error {
 		bw.WriteString("\tsubgraph " + layer.Id + "\n")
 
 		for _, n := range layer.Nodes {
-			bw.WriteString("\t\tsubgraph " + layer.Id + "_" + n.Id + "\n")
+			if label, ok := c.nodeLabels[nodeURI(m, layer.Id, n.Id)]; ok {
+				bw.WriteString("\t\tsubgraph " + layer.Id + "_" + n.Id + " [" + mermaidLabel(label) + "]\n")
+			} else {
+				bw.WriteString("\t\tsubgraph " + layer.Id + "_" + n.Id + "\n")
+			}
 			for k, v := range n.Attributes {
 				var attrStr string
 
@@ -41,33 +54,57 @@ func WriteMermiad(w io.Writer, m *Model) error {
 				bw.WriteString("\t\t\t" + attrStr + "\n")
 			}
 			bw.WriteString("\t\tend\n\n")
+
+			if color, ok := c.nodeColors[nodeURI(m, layer.Id, n.Id)]; ok {
+				styles = append(styles, fmt.Sprintf("style %s_%s fill:%s", layer.Id, n.Id, color))
+			}
 		}
 
 		for _, link := range layer.Links {
-			linkToID := link.To
+			linkToID, external := linkTargetID(m, layer, link, "_")
+			if external {
+				linkToID = mermaidExternalID(linkToID)
+			}
 
-			// If the link has an ID, use that as the label, otherwise
-			// if it's using a URI, we need to clean it up a bit to match
-			// mermiad's syntax. We control the ID of each node in the subgraph,
-			// so this is fairly safe.
-			if strings.Contains(linkToID, "://") {
-				linkToID = strings.TrimPrefix(linkToID, m.GetUri())
+			linkLabel := link.Id
+			if label, ok := c.linkLabels[linkURI(m, layer.Id, link.Id)]; ok {
+				linkLabel = mermaidLabel(label)
+			}
 
-				// The link is likely to be a URI to a different layer, so we need
-				// to remove the layer name from the URI.
-				linkToID = strings.TrimPrefix(linkToID, "/layers/")
+			bw.WriteString("\t\t" + layer.Id + "_" + link.From + "-->" + "|" + linkLabel + "|" + linkToID + "\n")
 
-				// Replace the "/nodes/" with _ to match the node ID.
-				linkToID = strings.Replace(linkToID, "/nodes/", "_", 1)
-			} else {
-				linkToID = layer.Id + "_" + link.To
+			if color, ok := c.linkColors[linkURI(m, layer.Id, link.Id)]; ok {
+				styles = append(styles, fmt.Sprintf("linkStyle %d stroke:%s", linkIndex, color))
 			}
-
-			bw.WriteString("\t\t" + layer.Id + "_" + link.From + "-->" + "|" + link.Id + "|" + linkToID + "\n")
+			linkIndex++
 		}
 
 		bw.WriteString("\tend\n\n")
 	}
 
+	for _, style := range styles {
+		bw.WriteString("\t" + style + "\n")
+	}
+
 	return nil
 }
+
+// mermaidExternalID returns a node for the given URI of a node outside of
+// the model, with an ID made of only the characters Mermaid allows, and
+// the URI as its label.
+func mermaidExternalID(uri string) string {
+	id := strings.Map(func(r rune) rune {
+		if ('a' <= r && r <= 'z') || ('A' <= r && r <= 'Z') || ('0' <= r && r <= '9') {
+			return r
+		}
+		return '_'
+	}, uri)
+
+	return "external_" + id + "[" + mermaidLabel(uri) + "]"
+}
+
+// mermaidLabel returns the given label as a quoted Mermaid string, with
+// line breaks and quotes escaped.
+func mermaidLabel(label string) string {
+	return `"` + strings.NewReplacer("\n", "<br/>", `"`, "#quot;").Replace(label) + `"`
+}
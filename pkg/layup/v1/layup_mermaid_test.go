@@ -22,3 +22,32 @@ func TestWriteMermaid(t *testing.T) {
 
 	fmt.Println(mermaidBuffer.String())
 }
+
+func TestWriteMermaid_external(t *testing.T) {
+	model, err := layupv1.ParseHCL(strings.NewReader(`
+uri = "layup://test"
+
+layer "1" {
+	node "a" {}
+
+	link "outside" {
+		from = node.a
+		to   = "github://picatz/layup"
+	}
+}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var sb strings.Builder
+
+	if err := layupv1.WriteMermiad(&sb, model); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `1_a-->|outside|external_github___picatz_layup["github://picatz/layup"]`
+	if !strings.Contains(sb.String(), want) {
+		t.Fatalf("expected external link %q in:\n%s", want, sb.String())
+	}
+}
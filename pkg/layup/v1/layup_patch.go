@@ -0,0 +1,481 @@
+package layupv1
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// PatchOp is the type of a patch operation.
+type PatchOp string
+
+const (
+	// PatchAdd adds an element, or a field which isn't set.
+	PatchAdd PatchOp = "add"
+	// PatchRemove removes an element (and everything within it), or a
+	// field which is set.
+	PatchRemove PatchOp = "remove"
+	// PatchReplace replaces the value of a field which is set.
+	PatchReplace PatchOp = "replace"
+)
+
+// Patch is a list of operations which incrementally change a model, like a
+// JSON Patch (RFC 6902), but addressing elements by canonical URI.
+type Patch []PatchOperation
+
+// PatchOperation is an operation on a model, or one of its layers, nodes
+// or links, or one of their fields.
+//
+// Operations without a field add or remove the element itself, where added
+// elements are empty, so their fields are added by the operations after
+// them, such as the "from" and "to" of a link. Operations with a field use
+// the same names as a FieldChange: "uri" (of the model), "dynamic" (of a
+// layer), "from" and "to" (of a link), and "attributes.<key>".
+//
+// Adding an element or field which already exists is an error, as is
+// removing or replacing one which doesn't, so a patch can't be applied to a
+// model which has drifted from the one it was made for.
+type PatchOperation struct {
+	// Op is the type of the operation.
+	Op PatchOp `json:"op"`
+	// URI is the canonical URI of the element, within the model as it is
+	// before the operation is applied.
+	URI string `json:"uri"`
+	// Field is the name of the field, or empty for the element itself.
+	Field string `json:"field,omitempty"`
+	// Value is the value of the field to add or replace. Attribute values
+	// can be a *structpb.Value, as they are in a FieldChange, which is how
+	// a null attribute value is given, and how they are parsed.
+	Value any `json:"value,omitempty"`
+}
+
+// MarshalJSON encodes the operation with its value as JSON, including a
+// null attribute value, which is only left out if there is no value.
+func (op PatchOperation) MarshalJSON() ([]byte, error) {
+	value, err := jsonFieldValue(op.Value)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(struct {
+		Op    PatchOp         `json:"op"`
+		URI   string          `json:"uri"`
+		Field string          `json:"field,omitempty"`
+		Value json.RawMessage `json:"value,omitempty"`
+	}{
+		Op:    op.Op,
+		URI:   op.URI,
+		Field: op.Field,
+		Value: value,
+	})
+}
+
+// UnmarshalJSON decodes the operation, with attribute values (including
+// null) as a *structpb.Value, and other values as JSON values.
+func (op *PatchOperation) UnmarshalJSON(b []byte) error {
+	var raw struct {
+		Op    PatchOp         `json:"op"`
+		URI   string          `json:"uri"`
+		Field string          `json:"field,omitempty"`
+		Value json.RawMessage `json:"value,omitempty"`
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+
+	if err := dec.Decode(&raw); err != nil {
+		return err
+	}
+
+	*op = PatchOperation{
+		Op:    raw.Op,
+		URI:   raw.URI,
+		Field: raw.Field,
+	}
+
+	if len(raw.Value) == 0 {
+		return nil
+	}
+
+	if strings.HasPrefix(raw.Field, "attributes.") {
+		v := &structpb.Value{}
+		if err := protojson.Unmarshal(raw.Value, v); err != nil {
+			return fmt.Errorf("invalid attribute value: %w", err)
+		}
+		op.Value = v
+		return nil
+	}
+
+	return json.Unmarshal(raw.Value, &op.Value)
+}
+
+// String returns a description of the operation.
+func (op PatchOperation) String() string {
+	s := string(op.Op) + " " + op.URI
+	if op.Field != "" {
+		s += " " + op.Field
+	}
+	return s
+}
+
+// ParsePatch parses a patch from its JSON representation, which is an
+// array of operations.
+func ParsePatch(r io.Reader) (Patch, error) {
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+
+	var p Patch
+	if err := dec.Decode(&p); err != nil {
+		return nil, fmt.Errorf("failed to parse patch: %w", err)
+	}
+
+	for i, op := range p {
+		switch op.Op {
+		case PatchAdd, PatchRemove, PatchReplace:
+		default:
+			return nil, fmt.Errorf("failed to parse patch: operation %d has unknown op %q", i, op.Op)
+		}
+	}
+
+	return p, nil
+}
+
+// ApplyPatch returns a copy of the given model with the patch applied.
+//
+// The patch is applied atomically: if any operation fails, or the patched
+// model isn't valid, an error is returned, and the model is unchanged.
+func ApplyPatch(m *Model, p Patch) (*Model, error) {
+	patched := proto.Clone(m).(*Model)
+
+	for i, op := range p {
+		if err := applyOperation(patched, op); err != nil {
+			return nil, fmt.Errorf("failed to apply operation %d (%s): %w", i, op, err)
+		}
+	}
+
+	if err := Validate(patched); err != nil {
+		return nil, fmt.Errorf("failed to apply patch: %w", err)
+	}
+
+	return patched, nil
+}
+
+// applyOperation applies a single operation to the model, in place.
+func applyOperation(m *Model, op PatchOperation) error {
+	u, err := ParseURI(op.URI)
+	if err != nil {
+		return err
+	}
+
+	if u.Model != m.GetUri() {
+		return fmt.Errorf("element is not within model %q", m.GetUri())
+	}
+
+	if op.Field != "" {
+		return applyFieldOperation(m, u, op)
+	}
+
+	if op.Value != nil {
+		return fmt.Errorf("elements can't have a value, their fields must be added separately")
+	}
+
+	if u.Layer == "" {
+		return fmt.Errorf("models can't be added or removed")
+	}
+
+	layerIndex := -1
+	for i, layer := range m.GetLayers() {
+		if layer.GetId() == u.Layer {
+			layerIndex = i
+		}
+	}
+
+	switch {
+	case u.Node == "" && u.Link == "":
+		switch op.Op {
+		case PatchAdd:
+			if layerIndex >= 0 {
+				return fmt.Errorf("layer already exists")
+			}
+			m.Layers = append(m.Layers, &Layer{Id: u.Layer})
+		case PatchRemove:
+			if layerIndex < 0 {
+				return fmt.Errorf("layer does not exist")
+			}
+			m.Layers = append(m.Layers[:layerIndex], m.Layers[layerIndex+1:]...)
+		default:
+			return fmt.Errorf("elements can't be replaced, only their fields")
+		}
+
+		return nil
+	case layerIndex < 0:
+		return fmt.Errorf("layer %q does not exist", u.Layer)
+	}
+
+	layer := m.Layers[layerIndex]
+
+	if u.Node != "" {
+		index := -1
+		for i, n := range layer.GetNodes() {
+			if n.GetId() == u.Node {
+				index = i
+			}
+		}
+
+		switch op.Op {
+		case PatchAdd:
+			if index >= 0 {
+				return fmt.Errorf("node already exists")
+			}
+			layer.Nodes = append(layer.Nodes, &Node{Id: u.Node})
+		case PatchRemove:
+			if index < 0 {
+				return fmt.Errorf("node does not exist")
+			}
+			layer.Nodes = append(layer.Nodes[:index], layer.Nodes[index+1:]...)
+		default:
+			return fmt.Errorf("elements can't be replaced, only their fields")
+		}
+
+		return nil
+	}
+
+	index := -1
+	for i, link := range layer.GetLinks() {
+		if link.GetId() == u.Link {
+			index = i
+		}
+	}
+
+	switch op.Op {
+	case PatchAdd:
+		if index >= 0 {
+			return fmt.Errorf("link already exists")
+		}
+		layer.Links = append(layer.Links, &Link{Id: u.Link})
+	case PatchRemove:
+		if index < 0 {
+			return fmt.Errorf("link does not exist")
+		}
+		layer.Links = append(layer.Links[:index], layer.Links[index+1:]...)
+	default:
+		return fmt.Errorf("elements can't be replaced, only their fields")
+	}
+
+	return nil
+}
+
+// applyFieldOperation applies an operation to a field of an element, in
+// place.
+func applyFieldOperation(m *Model, u URI, op PatchOperation) error {
+	var (
+		attrs *map[string]*structpb.Value
+		layer *Layer
+		link  *Link
+	)
+
+	if u.Layer == "" {
+		attrs = &m.Attributes
+	} else {
+		for _, l := range m.GetLayers() {
+			if l.GetId() == u.Layer {
+				layer = l
+			}
+		}
+		if layer == nil {
+			return fmt.Errorf("layer %q does not exist", u.Layer)
+		}
+
+		attrs = &layer.Attributes
+
+		switch {
+		case u.Node != "":
+			var node *Node
+			for _, n := range layer.GetNodes() {
+				if n.GetId() == u.Node {
+					node = n
+				}
+			}
+			if node == nil {
+				return fmt.Errorf("node does not exist")
+			}
+
+			attrs = &node.Attributes
+		case u.Link != "":
+			for _, l := range layer.GetLinks() {
+				if l.GetId() == u.Link {
+					link = l
+				}
+			}
+			if link == nil {
+				return fmt.Errorf("link does not exist")
+			}
+
+			attrs = &link.Attributes
+		}
+	}
+
+	if key, ok := strings.CutPrefix(op.Field, "attributes."); ok {
+		_, set := (*attrs)[key]
+		if err := checkFieldOperation(op, set); err != nil {
+			return err
+		}
+
+		if op.Op == PatchRemove {
+			delete(*attrs, key)
+			return nil
+		}
+
+		v, ok := op.Value.(*structpb.Value)
+		if ok {
+			v = proto.Clone(v).(*structpb.Value)
+		} else {
+			var err error
+			v, err = structpb.NewValue(op.Value)
+			if err != nil {
+				return fmt.Errorf("invalid attribute value: %w", err)
+			}
+		}
+
+		if *attrs == nil {
+			*attrs = map[string]*structpb.Value{}
+		}
+		(*attrs)[key] = v
+
+		return nil
+	}
+
+	switch {
+	case op.Field == "uri" && u.Layer == "":
+		if op.Op != PatchReplace {
+			return fmt.Errorf("the model URI can only be replaced")
+		}
+
+		s, ok := op.Value.(string)
+		if !ok {
+			return fmt.Errorf("invalid value %v, must be a string", op.Value)
+		}
+		m.Uri = s
+	case op.Field == "dynamic" && layer != nil && u.Node == "" && link == nil:
+		if err := checkFieldOperation(op, layer.Dynamic != nil); err != nil {
+			return err
+		}
+
+		if op.Op == PatchRemove {
+			layer.Dynamic = nil
+			return nil
+		}
+
+		b, ok := op.Value.(bool)
+		if !ok {
+			return fmt.Errorf("invalid value %v, must be a boolean", op.Value)
+		}
+		layer.Dynamic = proto.Bool(b)
+	case (op.Field == "from" || op.Field == "to") && link != nil:
+		field := &link.From
+		if op.Field == "to" {
+			field = &link.To
+		}
+
+		if op.Op == PatchRemove {
+			return fmt.Errorf("links must have a %q field, it can only be replaced", op.Field)
+		}
+
+		if err := checkFieldOperation(op, *field != ""); err != nil {
+			return err
+		}
+
+		s, ok := op.Value.(string)
+		if !ok {
+			return fmt.Errorf("invalid value %v, must be a string", op.Value)
+		}
+
+		// Links are always from a node within their own layer, using its
+		// ID, which can also be given as its canonical URI (as in a diff),
+		// as can the node it's going to.
+		if t, err := ParseURI(s); err == nil && t.Model == m.GetUri() && t.Layer == u.Layer && t.Node != "" {
+			s = t.Node
+		}
+
+		*field = s
+	default:
+		return fmt.Errorf("unknown field %q", op.Field)
+	}
+
+	return nil
+}
+
+// checkFieldOperation returns an error if the operation can't be applied
+// to a field, given whether it is set.
+func checkFieldOperation(op PatchOperation, set bool) error {
+	switch {
+	case op.Op == PatchAdd && set:
+		return fmt.Errorf("field is already set")
+	case op.Op != PatchAdd && !set:
+		return fmt.Errorf("field is not set")
+	case op.Op != PatchRemove && op.Value == nil:
+		return fmt.Errorf("missing value")
+	}
+	return nil
+}
+
+// Patch returns a patch which changes the old model into the new model,
+// which can be applied to the old model with ApplyPatch.
+//
+// Removed layers are removed with everything within them, so the nodes and
+// links within them aren't removed separately.
+func (d *ModelDiff) Patch() Patch {
+	p := Patch{}
+
+	from, to := &Model{Uri: d.From}, &Model{Uri: d.To}
+
+	removedLayers := map[string]bool{}
+
+	for _, c := range d.Changes {
+		// The model URI is replaced by the last operation on the model, since
+		// its fields are sorted by name, and every other operation uses the
+		// URI of the new model.
+		uri := rebaseURI(c.URI, from, to)
+		if c.Element == ElementModel {
+			uri = d.From
+		}
+
+		switch c.Type {
+		case ChangeAdded:
+			p = append(p, PatchOperation{Op: PatchAdd, URI: uri})
+			for _, f := range c.Fields {
+				p = append(p, PatchOperation{Op: PatchAdd, URI: uri, Field: f.Field, Value: f.New})
+			}
+		case ChangeRemoved:
+			u, _ := ParseURI(uri)
+			if removedLayers[layerURI(to, u.Layer)] {
+				continue
+			}
+			if c.Element == ElementLayer {
+				removedLayers[uri] = true
+			}
+
+			p = append(p, PatchOperation{Op: PatchRemove, URI: uri})
+		default:
+			for _, f := range c.Fields {
+				op := PatchOperation{Op: PatchReplace, URI: uri, Field: f.Field, Value: f.New}
+				switch {
+				case f.Old == nil:
+					op.Op = PatchAdd
+				case f.New == nil:
+					op.Op = PatchRemove
+				}
+
+				p = append(p, op)
+			}
+		}
+	}
+
+	return p
+}
@@ -0,0 +1,221 @@
+package layupv1_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	layupv1 "github.com/picatz/layup/pkg/layup/v1"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestApplyPatch(t *testing.T) {
+	m := newTestGraph(t, testRecipe).Model()
+
+	patched, err := layupv1.ApplyPatch(m, layupv1.Patch{
+		{Op: layupv1.PatchAdd, URI: "layup://recipe/layers/tools/nodes/whisk"},
+		{Op: layupv1.PatchAdd, URI: "layup://recipe/layers/tools/nodes/whisk", Field: "attributes.material", Value: "steel"},
+		{Op: layupv1.PatchAdd, URI: "layup://recipe/layers/tools/links/whisk"},
+		{Op: layupv1.PatchAdd, URI: "layup://recipe/layers/tools/links/whisk", Field: "from", Value: "whisk"},
+		{Op: layupv1.PatchAdd, URI: "layup://recipe/layers/tools/links/whisk", Field: "to", Value: "layup://recipe/layers/tools/nodes/bowl"},
+		{Op: layupv1.PatchReplace, URI: "layup://recipe/layers/tools/nodes/spoon", Field: "attributes.material", Value: "bamboo"},
+		{Op: layupv1.PatchRemove, URI: "layup://recipe/layers/tools/links/stir"},
+		{Op: layupv1.PatchReplace, URI: "layup://recipe", Field: "uri", Value: "layup://baking"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	g, err := layupv1.NewGraph(patched)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	whisk, ok := g.Link("layup://baking/layers/tools/links/whisk")
+	if !ok || whisk.Link.GetFrom() != "whisk" || whisk.Link.GetTo() != "bowl" {
+		t.Fatalf("unexpected whisk link: %v", whisk)
+	}
+
+	spoon, _ := g.Node("layup://baking/layers/tools/nodes/spoon")
+	if spoon.GetAttributes()["material"].GetStringValue() != "bamboo" {
+		t.Fatalf("unexpected spoon attributes: %v", spoon.GetAttributes())
+	}
+
+	if _, ok := g.Link("layup://baking/layers/tools/links/stir"); ok {
+		t.Fatal("expected stir link to be removed")
+	}
+
+	if m.GetUri() != "layup://recipe" {
+		t.Fatal("expected the original model to be unchanged")
+	}
+}
+
+func TestApplyPatch_errors(t *testing.T) {
+	m := newTestGraph(t, testRecipe).Model()
+
+	tests := []struct {
+		name  string
+		patch layupv1.Patch
+		err   string
+	}{
+		{
+			name:  "add existing node",
+			patch: layupv1.Patch{{Op: layupv1.PatchAdd, URI: "layup://recipe/layers/tools/nodes/pan"}},
+			err:   "node already exists",
+		},
+		{
+			name:  "replace unset attribute",
+			patch: layupv1.Patch{{Op: layupv1.PatchReplace, URI: "layup://recipe/layers/tools/nodes/pan", Field: "attributes.size", Value: 2}},
+			err:   "field is not set",
+		},
+		{
+			name:  "other model",
+			patch: layupv1.Patch{{Op: layupv1.PatchRemove, URI: "layup://kitchen/layers/tools/nodes/pan"}},
+			err:   "not within model",
+		},
+		{
+			name:  "unknown field",
+			patch: layupv1.Patch{{Op: layupv1.PatchAdd, URI: "layup://recipe/layers/tools/nodes/pan", Field: "colour", Value: "black"}},
+			err:   `unknown field "colour"`,
+		},
+		{
+			// Each operation succeeds, but the pan is still used by links, so
+			// the patched model isn't valid.
+			name:  "invalid model",
+			patch: layupv1.Patch{{Op: layupv1.PatchAdd, URI: "layup://recipe/layers/tools/links/wipe"}},
+			err:   "failed to apply patch",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := layupv1.ApplyPatch(m, test.patch)
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Fatalf("expected error containing %q, got: %v", test.err, err)
+			}
+		})
+	}
+}
+
+func TestModelDiff_Patch(t *testing.T) {
+	before := newTestGraph(t, testRecipe).Model()
+
+	after := newTestGraph(t, strings.NewReplacer(
+		`uri = "layup://recipe"`, `uri = "layup://baking"`,
+		`material = "wood"`, `material = "bamboo"`,
+		`node "pan" {`, `node "pot" {`,
+		`to   = node.pan`, `to   = node.pot`,
+		`layer "ingredients" {`, `layer "pantry" {`,
+		`to   = layer.tools.node.bowl`, `to   = node.flour`,
+		`to   = layer.tools.node.jug`, `to   = node.flour`,
+	).Replace(testRecipe)).Model()
+
+	d, err := layupv1.Diff(before, after)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Patches are serialized as JSON, so it's round-tripped to make sure
+	// they can be applied after being written.
+	b, err := json.Marshal(d.Patch())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := layupv1.ParsePatch(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, op := range p {
+		if op.Op == layupv1.PatchRemove && strings.Contains(op.URI, "/layers/ingredients/") {
+			t.Fatalf("expected the nodes and links of removed layers not to be removed separately: %s", op)
+		}
+	}
+
+	patched, err := layupv1.ApplyPatch(before, p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d, err = layupv1.Diff(patched, after)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !d.Empty() {
+		var buf bytes.Buffer
+		layupv1.WriteDiff(&buf, d)
+		t.Fatalf("expected patched model to equal the new model:\n%s", buf.String())
+	}
+}
+
+func TestModelDiff_Patch_null(t *testing.T) {
+	model := func(attrs map[string]*structpb.Value) *layupv1.Model {
+		return &layupv1.Model{
+			Uri: "layup://recipe",
+			Layers: []*layupv1.Layer{
+				{
+					Id:    "tools",
+					Nodes: []*layupv1.Node{{Id: "pan", Attributes: attrs}},
+				},
+			},
+		}
+	}
+
+	before := model(map[string]*structpb.Value{
+		"size":    structpb.NewNumberValue(1),
+		"handle":  structpb.NewNullValue(),
+		"lid":     structpb.NewNullValue(),
+		"coating": structpb.NewStringValue("none"),
+	})
+
+	after := model(map[string]*structpb.Value{
+		"size":    structpb.NewNullValue(),
+		"handle":  structpb.NewStringValue("wood"),
+		"coating": structpb.NewStringValue("none"),
+		"colour":  structpb.NewNullValue(),
+	})
+
+	d, err := layupv1.Diff(before, after)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := json.Marshal(d.Patch())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Contains(b, []byte(`"field":"attributes.colour","value":null`)) {
+		t.Fatalf("expected the null value to be written, got:\n%s", b)
+	}
+
+	p, err := layupv1.ParsePatch(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	patched, err := layupv1.ApplyPatch(before, p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !proto.Equal(patched, after) {
+		t.Fatalf("expected patched model to equal the new model:\n%v\n%v", patched, after)
+	}
+}
+
+func TestParsePatch(t *testing.T) {
+	_, err := layupv1.ParsePatch(strings.NewReader(`[{"op": "move", "uri": "layup://recipe/layers/tools"}]`))
+	if err == nil || !strings.Contains(err.Error(), `unknown op "move"`) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	_, err = layupv1.ParsePatch(strings.NewReader(`[{"op": "add", "path": "/layers/tools"}]`))
+	if err == nil {
+		t.Fatal("expected error for unknown field")
+	}
+}
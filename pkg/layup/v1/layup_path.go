@@ -0,0 +1,406 @@
+package layupv1
+
+import (
+	"container/heap"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// ErrNoPath is returned when there is no path between two nodes.
+var ErrNoPath = errors.New("no path")
+
+// Path is a sequence of nodes connected by links within a graph.
+type Path struct {
+	// Nodes are the canonical URIs of the nodes in the path, from the
+	// starting node to the ending node.
+	Nodes []string `json:"nodes"`
+	// Links are the canonical URIs of the links followed between each of
+	// the nodes, so there is always one less link than nodes.
+	Links []string `json:"links"`
+	// Cost is the total weight of the links in the path, which is the
+	// number of links when paths are unweighted.
+	Cost float64 `json:"cost"`
+}
+
+// Len returns the number of links in the path.
+func (p Path) Len() int {
+	return len(p.Links)
+}
+
+// String returns the nodes of the path, separated by arrows.
+func (p Path) String() string {
+	return strings.Join(p.Nodes, " -> ")
+}
+
+// Heuristic estimates the remaining cost from the node with the given
+// canonical URI to the goal of an A* search. To find the cheapest path, it
+// must never overestimate the remaining cost.
+type Heuristic func(node string) float64
+
+// checkEndpoints returns an error if there can't be a path between the
+// given nodes.
+func (g *Graph) checkEndpoints(from, to string) error {
+	if err := g.checkStart(from); err != nil {
+		return err
+	}
+	return g.checkStart(to)
+}
+
+// ShortestPath returns the path between the given nodes with the fewest
+// links, found using a breadth-first search. Link weights are ignored, so
+// the cost of the path is the number of links it contains.
+func (g *Graph) ShortestPath(from, to string, opts ...TraversalOption) (Path, error) {
+	if err := g.checkEndpoints(from, to); err != nil {
+		return Path{}, err
+	}
+
+	var (
+		visits = map[string]Visit{}
+		found  bool
+	)
+
+	err := g.BFS(from, func(v Visit) error {
+		visits[v.Node] = v
+		if v.Node == to {
+			found = true
+			return StopTraversal
+		}
+		return nil
+	}, opts...)
+	if err != nil {
+		return Path{}, err
+	}
+
+	if !found {
+		return Path{}, fmt.Errorf("%w from %q to %q", ErrNoPath, from, to)
+	}
+
+	var p Path
+	for v := visits[to]; ; v = visits[v.Parent] {
+		p.Nodes = append(p.Nodes, v.Node)
+		if v.Via == nil {
+			break
+		}
+		p.Links = append(p.Links, v.Via.URI)
+	}
+
+	slices.Reverse(p.Nodes)
+	slices.Reverse(p.Links)
+	p.Cost = float64(len(p.Links))
+
+	return p, nil
+}
+
+// Dijkstra returns the cheapest path between the given nodes, where the
+// cost of following each link is given by WithWeight.
+func (g *Graph) Dijkstra(from, to string, opts ...TraversalOption) (Path, error) {
+	return g.AStar(from, to, nil, opts...)
+}
+
+// AStar returns the cheapest path between the given nodes, like Dijkstra,
+// using the given heuristic to explore the nodes closest to the goal first.
+// A nil heuristic is equivalent to Dijkstra.
+func (g *Graph) AStar(from, to string, h Heuristic, opts ...TraversalOption) (Path, error) {
+	if err := g.checkEndpoints(from, to); err != nil {
+		return Path{}, err
+	}
+
+	return g.cheapestPath(from, to, h, newTraversalConfig(opts), nil, nil)
+}
+
+// KShortestPaths returns up to k of the cheapest loopless paths between the
+// given nodes, in order of increasing cost, using Yen's algorithm. The cost
+// of following each link is given by WithWeight.
+func (g *Graph) KShortestPaths(from, to string, k int, opts ...TraversalOption) ([]Path, error) {
+	if k < 1 {
+		return nil, fmt.Errorf("invalid number of paths %d, must be at least 1", k)
+	}
+
+	if err := g.checkEndpoints(from, to); err != nil {
+		return nil, err
+	}
+
+	c := newTraversalConfig(opts)
+
+	first, err := g.cheapestPath(from, to, nil, c, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		paths      = []Path{first}
+		candidates []Path
+		seen       = map[string]struct{}{pathKey(first): {}}
+	)
+
+	for len(paths) < k {
+		prev := paths[len(paths)-1]
+
+		for i := 0; i < len(prev.Links); i++ {
+			var (
+				spur          = prev.Nodes[i]
+				rootNodes     = prev.Nodes[:i+1]
+				rootLinks     = prev.Links[:i]
+				excludedNodes = map[string]struct{}{}
+				excludedLinks = map[string]struct{}{}
+			)
+
+			// Paths sharing the same root can't follow the same link
+			// from the spur node again.
+			for _, p := range paths {
+				if len(p.Links) > i && slices.Equal(p.Nodes[:i+1], rootNodes) {
+					excludedLinks[p.Links[i]] = struct{}{}
+				}
+			}
+
+			// The spur path can't loop back through the root.
+			for _, node := range rootNodes[:i] {
+				excludedNodes[node] = struct{}{}
+			}
+
+			spurPath, err := g.cheapestPath(spur, to, nil, c, excludedNodes, excludedLinks)
+			if errors.Is(err, ErrNoPath) {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+
+			var rootCost float64
+			for _, uri := range rootLinks {
+				w, err := c.linkWeight(g.links[uri])
+				if err != nil {
+					return nil, err
+				}
+				rootCost += w
+			}
+
+			p := Path{
+				Nodes: append(slices.Clone(rootNodes[:i]), spurPath.Nodes...),
+				Links: append(slices.Clone(rootLinks), spurPath.Links...),
+				Cost:  rootCost + spurPath.Cost,
+			}
+
+			key := pathKey(p)
+			if _, ok := seen[key]; ok {
+				continue
+			}
+			seen[key] = struct{}{}
+
+			candidates = append(candidates, p)
+		}
+
+		if len(candidates) == 0 {
+			break
+		}
+
+		sort.SliceStable(candidates, func(i, j int) bool {
+			if candidates[i].Cost != candidates[j].Cost {
+				return candidates[i].Cost < candidates[j].Cost
+			}
+			return len(candidates[i].Links) < len(candidates[j].Links)
+		})
+
+		paths = append(paths, candidates[0])
+		candidates = candidates[1:]
+	}
+
+	return paths, nil
+}
+
+// AllSimplePaths returns every path between the given nodes which doesn't
+// visit a node more than once, in depth-first order. Since there can be a
+// very large number of them, the paths can be limited by length with
+// WithMaxDepth, and by number with WithMaxPaths.
+func (g *Graph) AllSimplePaths(from, to string, opts ...TraversalOption) ([]Path, error) {
+	if err := g.checkEndpoints(from, to); err != nil {
+		return nil, err
+	}
+
+	c := newTraversalConfig(opts)
+
+	var (
+		paths   []Path
+		current = Path{Nodes: []string{from}}
+		onPath  = map[string]struct{}{from: {}}
+		done    = errors.New("done")
+	)
+
+	var walk func(node string) error
+	walk = func(node string) error {
+		if node == to {
+			paths = append(paths, Path{
+				Nodes: slices.Clone(current.Nodes),
+				Links: slices.Clone(current.Links),
+				Cost:  current.Cost,
+			})
+			if c.maxPaths >= 0 && len(paths) >= c.maxPaths {
+				return done
+			}
+			return nil
+		}
+
+		if c.maxDepth >= 0 && len(current.Links) >= c.maxDepth {
+			return nil
+		}
+
+		for _, s := range g.steps(node, c) {
+			if _, ok := onPath[s.node]; ok {
+				continue
+			}
+
+			w, err := c.linkWeight(s.edge)
+			if err != nil {
+				return err
+			}
+
+			onPath[s.node] = struct{}{}
+			current.Nodes = append(current.Nodes, s.node)
+			current.Links = append(current.Links, s.edge.URI)
+			current.Cost += w
+
+			if err := walk(s.node); err != nil {
+				return err
+			}
+
+			delete(onPath, s.node)
+			current.Nodes = current.Nodes[:len(current.Nodes)-1]
+			current.Links = current.Links[:len(current.Links)-1]
+			current.Cost -= w
+		}
+
+		return nil
+	}
+
+	if err := walk(from); err != nil && !errors.Is(err, done) {
+		return nil, err
+	}
+
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("%w from %q to %q", ErrNoPath, from, to)
+	}
+
+	return paths, nil
+}
+
+// pathKey returns a string uniquely identifying the given path.
+func pathKey(p Path) string {
+	return strings.Join(p.Nodes, " ") + "|" + strings.Join(p.Links, " ")
+}
+
+// cheapestPath returns the cheapest path between the given nodes using an
+// A* search, without visiting or following any of the excluded nodes or
+// links.
+func (g *Graph) cheapestPath(from, to string, h Heuristic, c *traversalConfig, excludedNodes, excludedLinks map[string]struct{}) (Path, error) {
+	if h == nil {
+		h = func(string) float64 { return 0 }
+	}
+
+	type parent struct {
+		node string
+		edge *Edge
+	}
+
+	var (
+		costs   = map[string]float64{from: 0}
+		parents = map[string]parent{}
+		closed  = map[string]struct{}{}
+		queue   = &pathQueue{}
+		seq     int
+	)
+
+	heap.Push(queue, &pathQueueItem{node: from, priority: h(from)})
+
+	for queue.Len() > 0 {
+		item := heap.Pop(queue).(*pathQueueItem)
+
+		if _, ok := closed[item.node]; ok {
+			continue
+		}
+		closed[item.node] = struct{}{}
+
+		if item.node == to {
+			var p Path
+			for node := to; ; {
+				p.Nodes = append(p.Nodes, node)
+				prev, ok := parents[node]
+				if !ok {
+					break
+				}
+				p.Links = append(p.Links, prev.edge.URI)
+				node = prev.node
+			}
+
+			slices.Reverse(p.Nodes)
+			slices.Reverse(p.Links)
+			p.Cost = costs[to]
+
+			return p, nil
+		}
+
+		for _, s := range g.steps(item.node, c) {
+			if _, ok := excludedNodes[s.node]; ok {
+				continue
+			}
+			if _, ok := excludedLinks[s.edge.URI]; ok {
+				continue
+			}
+			if _, ok := closed[s.node]; ok {
+				continue
+			}
+
+			w, err := c.linkWeight(s.edge)
+			if err != nil {
+				return Path{}, err
+			}
+
+			cost := costs[item.node] + w
+			if prev, ok := costs[s.node]; ok && prev <= cost {
+				continue
+			}
+
+			costs[s.node] = cost
+			parents[s.node] = parent{node: item.node, edge: s.edge}
+
+			seq++
+			heap.Push(queue, &pathQueueItem{node: s.node, priority: cost + h(s.node), seq: seq})
+		}
+	}
+
+	return Path{}, fmt.Errorf("%w from %q to %q", ErrNoPath, from, to)
+}
+
+// pathQueueItem is a node to explore during a path search.
+type pathQueueItem struct {
+	node     string
+	priority float64
+	seq      int
+}
+
+// pathQueue is a priority queue of nodes to explore, with the lowest
+// priority first, and ties broken by insertion order to keep searches
+// deterministic.
+type pathQueue []*pathQueueItem
+
+func (q pathQueue) Len() int { return len(q) }
+
+func (q pathQueue) Less(i, j int) bool {
+	if q[i].priority != q[j].priority {
+		return q[i].priority < q[j].priority
+	}
+	return q[i].seq < q[j].seq
+}
+
+func (q pathQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+
+func (q *pathQueue) Push(x any) { *q = append(*q, x.(*pathQueueItem)) }
+
+func (q *pathQueue) Pop() any {
+	old := *q
+	item := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return item
+}
@@ -0,0 +1,186 @@
+package layupv1_test
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	layupv1 "github.com/picatz/layup/pkg/layup/v1"
+)
+
+const testNetwork = `
+uri = "layup://network"
+
+layer "dc" {
+	node "a" {}
+	node "b" {}
+	node "c" {}
+	node "d" {}
+	node "e" {}
+
+	link "ab" {
+		from    = node.a
+		to      = node.b
+		latency = 1
+	}
+
+	link "bd" {
+		from    = node.b
+		to      = node.d
+		latency = 5
+	}
+
+	link "ac" {
+		from    = node.a
+		to      = node.c
+		latency = 2
+	}
+
+	link "cd" {
+		from    = node.c
+		to      = node.d
+		latency = 1
+	}
+
+	link "ad" {
+		from    = node.a
+		to      = node.d
+		latency = 10
+	}
+
+	link "de" {
+		from    = node.d
+		to      = node.e
+		latency = 1
+	}
+}
+`
+
+func networkNode(id string) string {
+	return "layup://network/layers/dc/nodes/" + id
+}
+
+func TestGraph_ShortestPath(t *testing.T) {
+	g := newTestGraph(t, testNetwork)
+
+	p, err := g.ShortestPath(networkNode("a"), networkNode("e"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !slices.Equal(p.Nodes, []string{networkNode("a"), networkNode("d"), networkNode("e")}) {
+		t.Fatalf("unexpected path: %v", p)
+	}
+
+	if p.Len() != 2 || p.Cost != 2 {
+		t.Fatalf("unexpected path length %d and cost %v", p.Len(), p.Cost)
+	}
+
+	t.Run("no path", func(t *testing.T) {
+		_, err := g.ShortestPath(networkNode("e"), networkNode("a"))
+		if !errors.Is(err, layupv1.ErrNoPath) {
+			t.Fatalf("expected no path, got %v", err)
+		}
+	})
+
+	t.Run("incoming", func(t *testing.T) {
+		p, err := g.ShortestPath(networkNode("e"), networkNode("a"), layupv1.WithDirection(layupv1.Incoming))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if p.Len() != 2 {
+			t.Fatalf("unexpected path: %v", p)
+		}
+	})
+}
+
+func TestGraph_Dijkstra(t *testing.T) {
+	g := newTestGraph(t, testNetwork)
+
+	p, err := g.Dijkstra(networkNode("a"), networkNode("e"), layupv1.WithWeight("latency"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !slices.Equal(p.Links, []string{
+		"layup://network/layers/dc/links/ac",
+		"layup://network/layers/dc/links/cd",
+		"layup://network/layers/dc/links/de",
+	}) {
+		t.Fatalf("unexpected path: %v", p.Links)
+	}
+
+	if p.Cost != 4 {
+		t.Fatalf("unexpected cost: %v", p.Cost)
+	}
+
+	t.Run("A*", func(t *testing.T) {
+		remaining := map[string]float64{networkNode("a"): 3, networkNode("b"): 2, networkNode("c"): 2, networkNode("d"): 1}
+
+		p2, err := g.AStar(networkNode("a"), networkNode("e"), func(n string) float64 { return remaining[n] }, layupv1.WithWeight("latency"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !slices.Equal(p.Nodes, p2.Nodes) || p.Cost != p2.Cost {
+			t.Fatalf("unexpected path: %v", p2)
+		}
+	})
+
+	t.Run("missing weight", func(t *testing.T) {
+		_, err := g.Dijkstra(networkNode("a"), networkNode("e"), layupv1.WithWeight("cost"))
+		if err == nil {
+			t.Fatal("expected error, got nil")
+		}
+	})
+}
+
+func TestGraph_KShortestPaths(t *testing.T) {
+	g := newTestGraph(t, testNetwork)
+
+	paths, err := g.KShortestPaths(networkNode("a"), networkNode("e"), 5, layupv1.WithWeight("latency"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var costs []float64
+	for _, p := range paths {
+		costs = append(costs, p.Cost)
+	}
+
+	if !slices.Equal(costs, []float64{4, 7, 11}) {
+		t.Fatalf("unexpected costs: %v", costs)
+	}
+}
+
+func TestGraph_AllSimplePaths(t *testing.T) {
+	g := newTestGraph(t, testNetwork)
+
+	paths, err := g.AllSimplePaths(networkNode("a"), networkNode("d"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(paths) != 3 {
+		t.Fatalf("unexpected paths: %v", paths)
+	}
+
+	paths, err = g.AllSimplePaths(networkNode("a"), networkNode("d"), layupv1.WithMaxDepth(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(paths) != 1 || paths[0].Len() != 1 {
+		t.Fatalf("unexpected paths: %v", paths)
+	}
+
+	paths, err = g.AllSimplePaths(networkNode("a"), networkNode("d"), layupv1.WithMaxPaths(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(paths) != 2 {
+		t.Fatalf("unexpected paths: %v", paths)
+	}
+}
@@ -0,0 +1,577 @@
+package layupv1
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// Pattern is a chain of node patterns connected by link patterns, which is
+// matched against a graph by Graph.Match, similar to a Cypher MATCH clause:
+//
+//	(i:ingredients)-[:add_*]->(t:tools)-[:pour]->(:tools {material: "wood"})
+//
+// A node pattern is written in parentheses, with an optional variable name,
+// an optional layer constraint after a colon, and optional attributes which
+// must be equal, in braces. Layers can be given as alternatives separated
+// by "|", and may contain glob wildcards, such as (t:tools|kitchen_*).
+//
+// A link pattern is written in brackets between dashes, with an arrow for
+// its direction ("-[...]->" for outgoing, "<-[...]-" for incoming, and
+// "-[...]-" for either), or just the dashes and arrow ("-->", "<--", "--")
+// to match any single link. It has an optional variable name, an optional
+// link ID constraint after a colon (with the same alternatives and wildcards
+// as layers), an optional number of hops, and optional attributes. Hops are
+// written as "*" for any number (one or more), "*n" for exactly n, or
+// "*min..max" with either bound left out, separated from the variable or
+// IDs by whitespace, such as -[:uses *1..3]->, since a "*" attached to an
+// ID is a wildcard.
+type Pattern struct {
+	// Nodes are the node patterns, in order.
+	Nodes []*NodePattern
+	// Links are the link patterns between each pair of consecutive node
+	// patterns, so there is one fewer than there are nodes.
+	Links []*LinkPattern
+}
+
+// NodePattern matches a node within a pattern.
+type NodePattern struct {
+	// Variable is the name the matching node is bound to, if any. Using the
+	// same variable more than once requires the same node at each place.
+	Variable string
+	// Layers are the globs (see path.Match) the ID of the node's layer must
+	// match one of, if any.
+	Layers []string
+	// Attributes are the attributes the node must have, with equal values.
+	Attributes map[string]*structpb.Value
+}
+
+// LinkPattern matches a link, or a chain of links, within a pattern.
+type LinkPattern struct {
+	// Variable is the name the matching links are bound to, if any.
+	Variable string
+	// IDs are the globs (see path.Match) the ID of each link must match
+	// one of, if any.
+	IDs []string
+	// Direction is the direction the links are followed in.
+	Direction Direction
+	// MinHops and MaxHops are the bounds of the number of links to follow,
+	// which are both 1 for a single link. MaxHops is -1 for no limit.
+	MinHops, MaxHops int
+	// Attributes are the attributes each link must have, with equal values.
+	Attributes map[string]*structpb.Value
+}
+
+// ParsePattern parses the given pattern, as described by Pattern.
+func ParsePattern(s string) (*Pattern, error) {
+	p := &patternParser{src: s}
+
+	pattern, err := p.parse()
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern %q: %w", s, err)
+	}
+
+	return pattern, nil
+}
+
+// String returns the pattern in the syntax parsed by ParsePattern.
+func (p *Pattern) String() string {
+	var sb strings.Builder
+
+	for i, n := range p.Nodes {
+		if i > 0 {
+			sb.WriteString(p.Links[i-1].String())
+		}
+		sb.WriteString(n.String())
+	}
+
+	return sb.String()
+}
+
+// String returns the node pattern in the syntax parsed by ParsePattern.
+func (n *NodePattern) String() string {
+	var sb strings.Builder
+
+	sb.WriteString("(")
+	sb.WriteString(n.Variable)
+	if len(n.Layers) > 0 {
+		sb.WriteString(":" + strings.Join(n.Layers, "|"))
+	}
+	writePatternAttributes(&sb, n.Attributes)
+	sb.WriteString(")")
+
+	return sb.String()
+}
+
+// String returns the link pattern in the syntax parsed by ParsePattern.
+func (l *LinkPattern) String() string {
+	var sb strings.Builder
+
+	if l.Direction == Incoming {
+		sb.WriteString("<")
+	}
+
+	sb.WriteString("-[")
+	sb.WriteString(l.Variable)
+	if len(l.IDs) > 0 {
+		sb.WriteString(":" + strings.Join(l.IDs, "|"))
+	}
+
+	if (l.MinHops != 1 || l.MaxHops != 1) && (l.Variable != "" || len(l.IDs) > 0) {
+		sb.WriteString(" ")
+	}
+
+	switch {
+	case l.MinHops == 1 && l.MaxHops == 1:
+	case l.MinHops == 1 && l.MaxHops == -1:
+		sb.WriteString("*")
+	case l.MinHops == l.MaxHops:
+		fmt.Fprintf(&sb, "*%d", l.MinHops)
+	case l.MaxHops == -1:
+		fmt.Fprintf(&sb, "*%d..", l.MinHops)
+	default:
+		fmt.Fprintf(&sb, "*%d..%d", l.MinHops, l.MaxHops)
+	}
+
+	writePatternAttributes(&sb, l.Attributes)
+	sb.WriteString("]-")
+
+	if l.Direction == Outgoing {
+		sb.WriteString(">")
+	}
+
+	return sb.String()
+}
+
+// writePatternAttributes writes the given attributes in braces, sorted by
+// key, if there are any.
+func writePatternAttributes(sb *strings.Builder, attrs map[string]*structpb.Value) {
+	if len(attrs) == 0 {
+		return
+	}
+
+	keys := make([]string, 0, len(attrs))
+	for k := range attrs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	sb.WriteString(" {")
+	for i, k := range keys {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		b, _ := protojson.Marshal(attrs[k])
+		fmt.Fprintf(sb, "%s: %s", k, b)
+	}
+	sb.WriteString("}")
+}
+
+// matchesGlobs reports whether the given ID matches any of the globs, or
+// true if there are none.
+func matchesGlobs(globs []string, id string) bool {
+	if len(globs) == 0 {
+		return true
+	}
+
+	for _, glob := range globs {
+		if ok, _ := path.Match(glob, id); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// patternParser is a recursive descent parser for patterns.
+type patternParser struct {
+	src string
+	pos int
+}
+
+func (p *patternParser) parse() (*Pattern, error) {
+	pattern := &Pattern{}
+
+	vars := map[string]string{}
+
+	n, err := p.node()
+	if err != nil {
+		return nil, err
+	}
+	pattern.Nodes = append(pattern.Nodes, n)
+
+	for {
+		p.skipSpace()
+		if p.done() {
+			break
+		}
+
+		l, err := p.link()
+		if err != nil {
+			return nil, err
+		}
+
+		n, err := p.node()
+		if err != nil {
+			return nil, err
+		}
+
+		pattern.Links = append(pattern.Links, l)
+		pattern.Nodes = append(pattern.Nodes, n)
+	}
+
+	// Node variables can be used more than once, to match the same node,
+	// but link variables can't since a link is only matched once.
+	for _, n := range pattern.Nodes {
+		if n.Variable != "" {
+			vars[n.Variable] = "node"
+		}
+	}
+
+	for _, l := range pattern.Links {
+		if l.Variable == "" {
+			continue
+		}
+		switch vars[l.Variable] {
+		case "node":
+			return nil, fmt.Errorf("variable %q is used for both a node and a link", l.Variable)
+		case "link":
+			return nil, fmt.Errorf("variable %q is used for more than one link", l.Variable)
+		}
+		vars[l.Variable] = "link"
+	}
+
+	return pattern, nil
+}
+
+func (p *patternParser) done() bool {
+	return p.pos >= len(p.src)
+}
+
+func (p *patternParser) peek() byte {
+	if p.done() {
+		return 0
+	}
+	return p.src[p.pos]
+}
+
+func (p *patternParser) skipSpace() {
+	for !p.done() && strings.IndexByte(" \t\r\n", p.peek()) >= 0 {
+		p.pos++
+	}
+}
+
+// consume skips any whitespace, and the given token if it is next.
+func (p *patternParser) consume(token string) bool {
+	p.skipSpace()
+	if strings.HasPrefix(p.src[p.pos:], token) {
+		p.pos += len(token)
+		return true
+	}
+	return false
+}
+
+// expect consumes the given token, or returns an error if it isn't next.
+func (p *patternParser) expect(token string) error {
+	if !p.consume(token) {
+		return p.errorf("expected %q", token)
+	}
+	return nil
+}
+
+func (p *patternParser) errorf(format string, args ...any) error {
+	found := "end of pattern"
+	if !p.done() {
+		found = strconv.Quote(p.src[p.pos : p.pos+1])
+	}
+	return fmt.Errorf("%s at offset %d, found %s", fmt.Sprintf(format, args...), p.pos, found)
+}
+
+// word returns the next run of bytes within the given set, which may be
+// empty.
+func (p *patternParser) word(chars func(c byte) bool) string {
+	p.skipSpace()
+	start := p.pos
+	for !p.done() && chars(p.peek()) {
+		p.pos++
+	}
+	return p.src[start:p.pos]
+}
+
+func isVariableChar(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+func isGlobChar(c byte) bool {
+	return isVariableChar(c) || c == '-' || c == '*' || c == '?'
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// globs parses the alternatives of a layer or link ID constraint, after the
+// colon.
+//
+// A "*" within an ID is always a wildcard, so the hops of a link must be
+// separated from its IDs by whitespace. Link IDs where a "*" is followed
+// by a number or "..", which look like hops, are rejected as ambiguous.
+func (p *patternParser) globs(link bool) ([]string, error) {
+	var globs []string
+
+	for {
+		p.skipSpace()
+		start := p.pos
+		glob := p.word(isGlobChar)
+		if glob == "" {
+			return nil, p.errorf("expected an ID")
+		}
+
+		if link {
+			ambiguous := strings.HasSuffix(glob, "*") && strings.HasPrefix(p.src[p.pos:], "..")
+			for i := 0; i < len(glob)-1; i++ {
+				if glob[i] == '*' && isDigit(glob[i+1]) {
+					ambiguous = true
+				}
+			}
+			if ambiguous {
+				p.pos = start
+				return nil, p.errorf("ambiguous link ID %q, hops must be separated from the ID by a space (like \"uses *2\")", glob)
+			}
+		}
+
+		if _, err := path.Match(glob, ""); err != nil {
+			return nil, fmt.Errorf("invalid glob %q: %w", glob, err)
+		}
+
+		globs = append(globs, glob)
+
+		if !p.consume("|") {
+			return globs, nil
+		}
+	}
+}
+
+func (p *patternParser) node() (*NodePattern, error) {
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+
+	n := &NodePattern{
+		Variable: p.word(isVariableChar),
+	}
+
+	if p.consume(":") {
+		layers, err := p.globs(false)
+		if err != nil {
+			return nil, err
+		}
+		n.Layers = layers
+	}
+
+	attrs, err := p.attributes()
+	if err != nil {
+		return nil, err
+	}
+	n.Attributes = attrs
+
+	if err := p.expect(")"); err != nil {
+		return nil, err
+	}
+
+	return n, nil
+}
+
+func (p *patternParser) link() (*LinkPattern, error) {
+	l := &LinkPattern{
+		Direction: Both,
+		MinHops:   1,
+		MaxHops:   1,
+	}
+
+	incoming := p.consume("<")
+
+	if err := p.expect("-"); err != nil {
+		return nil, err
+	}
+
+	if p.consume("[") {
+		l.Variable = p.word(isVariableChar)
+
+		// A "*" straight after the variable is likely meant to be a link
+		// ID glob, missing its colon, so it isn't silently read as hops.
+		if l.Variable != "" && p.peek() == '*' {
+			return nil, p.errorf("ambiguous %q after link variable %q, use \"[:%s*]\" for link IDs, or \"[%s *]\" for hops", "*", l.Variable, l.Variable, l.Variable)
+		}
+
+		if p.consume(":") {
+			ids, err := p.globs(true)
+			if err != nil {
+				return nil, err
+			}
+			l.IDs = ids
+		}
+
+		if p.consume("*") {
+			if err := p.hops(l); err != nil {
+				return nil, err
+			}
+		}
+
+		attrs, err := p.attributes()
+		if err != nil {
+			return nil, err
+		}
+		l.Attributes = attrs
+
+		if err := p.expect("]"); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := p.expect("-"); err != nil {
+		return nil, err
+	}
+
+	outgoing := p.consume(">")
+
+	switch {
+	case incoming && outgoing:
+		return nil, p.errorf("link can't be both incoming and outgoing")
+	case incoming:
+		l.Direction = Incoming
+	case outgoing:
+		l.Direction = Outgoing
+	}
+
+	return l, nil
+}
+
+// hops parses the number of hops of a link, after the "*".
+func (p *patternParser) hops(l *LinkPattern) error {
+	number := func() (int, bool, error) {
+		s := p.word(isDigit)
+		if s == "" {
+			return 0, false, nil
+		}
+		n, err := strconv.Atoi(s)
+		return n, true, err
+	}
+
+	min, ok, err := number()
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case p.consume(".."):
+		if !ok {
+			min = 1
+		}
+
+		max, ok, err := number()
+		if err != nil {
+			return err
+		}
+		if !ok {
+			max = -1
+		}
+
+		if max != -1 && max < min {
+			return p.errorf("maximum hops %d is less than the minimum %d", max, min)
+		}
+
+		l.MinHops, l.MaxHops = min, max
+	case ok:
+		l.MinHops, l.MaxHops = min, min
+	default:
+		l.MinHops, l.MaxHops = 1, -1
+	}
+
+	return nil
+}
+
+// attributes parses the optional attributes of a node or link, in braces.
+func (p *patternParser) attributes() (map[string]*structpb.Value, error) {
+	if !p.consume("{") {
+		return nil, nil
+	}
+
+	attrs := map[string]*structpb.Value{}
+
+	if p.consume("}") {
+		return attrs, nil
+	}
+
+	for {
+		key := p.word(func(c byte) bool { return isVariableChar(c) || c == '-' })
+		if key == "" {
+			return nil, p.errorf("expected an attribute name")
+		}
+
+		if err := p.expect(":"); err != nil {
+			return nil, err
+		}
+
+		v, err := p.literal()
+		if err != nil {
+			return nil, err
+		}
+
+		attrs[key] = v
+
+		if p.consume("}") {
+			return attrs, nil
+		}
+
+		if err := p.expect(","); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// literal parses a string, number or bool attribute value.
+func (p *patternParser) literal() (*structpb.Value, error) {
+	p.skipSpace()
+
+	switch c := p.peek(); {
+	case c == '"':
+		start := p.pos
+		for p.pos++; !p.done() && p.peek() != '"'; p.pos++ {
+			if p.peek() == '\\' {
+				p.pos++
+			}
+		}
+		if p.done() {
+			return nil, p.errorf("unterminated string")
+		}
+		p.pos++
+
+		s, err := strconv.Unquote(p.src[start:p.pos])
+		if err != nil {
+			return nil, fmt.Errorf("invalid string %s: %w", p.src[start:p.pos], err)
+		}
+
+		return structpb.NewStringValue(s), nil
+	case c == '-' || c == '.' || isDigit(c):
+		s := p.word(func(c byte) bool { return c == '-' || c == '+' || c == '.' || c == 'e' || c == 'E' || isDigit(c) })
+
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q", s)
+		}
+
+		return structpb.NewNumberValue(f), nil
+	case p.consume("true"):
+		return structpb.NewBoolValue(true), nil
+	case p.consume("false"):
+		return structpb.NewBoolValue(false), nil
+	default:
+		return nil, p.errorf("expected a string, number or bool")
+	}
+}
@@ -0,0 +1,129 @@
+package layupv1_test
+
+import (
+	"strings"
+	"testing"
+
+	layupv1 "github.com/picatz/layup/pkg/layup/v1"
+)
+
+func TestParsePattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		want    string
+	}{
+		{
+			pattern: `(a)`,
+			want:    `(a)`,
+		},
+		{
+			pattern: `(i:ingredients)-[:add_*]->(t:tools)`,
+			want:    `(i:ingredients)-[:add_*]->(t:tools)`,
+		},
+		{
+			pattern: ` ( a : tools|kitchen_* { material : "wood", size: 2 } ) <-- ( b ) `,
+			want:    `(a:tools|kitchen_* {material: "wood", size: 2})<-[]-(b)`,
+		},
+		{
+			pattern: `(a)-[r:uses *]-(b)`,
+			want:    `(a)-[r:uses *]-(b)`,
+		},
+		{
+			pattern: `(a)-[r *]-(b)-[r2  *2..3]-(c)`,
+			want:    `(a)-[r *]-(b)-[r2 *2..3]-(c)`,
+		},
+		{
+			pattern: `(a)-[*2]->(b)-[*..3]->(c)-[*0..]->(d)-[*2..4 {ok: true}]->(e)`,
+			want:    `(a)-[*2]->(b)-[*1..3]->(c)-[*0..]->(d)-[*2..4 {ok: true}]->(e)`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.pattern, func(t *testing.T) {
+			p, err := layupv1.ParsePattern(test.pattern)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := p.String(); got != test.want {
+				t.Fatalf("expected %s, got %s", test.want, got)
+			}
+		})
+	}
+}
+
+func TestParsePattern_links(t *testing.T) {
+	tests := []struct {
+		pattern string
+		ids     []string
+		min     int
+		max     int
+	}{
+		{pattern: `(a)-[:uses]->(b)`, ids: []string{"uses"}, min: 1, max: 1},
+		{pattern: `(a)-[:uses *]->(b)`, ids: []string{"uses"}, min: 1, max: -1},
+		{pattern: `(a)-[:uses *2]->(b)`, ids: []string{"uses"}, min: 2, max: 2},
+		{pattern: `(a)-[:uses *1..3]->(b)`, ids: []string{"uses"}, min: 1, max: 3},
+		{pattern: `(a)-[:uses *..3]->(b)`, ids: []string{"uses"}, min: 1, max: 3},
+		{pattern: `(a)-[:uses * {ok: true}]->(b)`, ids: []string{"uses"}, min: 1, max: -1},
+		{pattern: `(a)-[:add_*]->(b)`, ids: []string{"add_*"}, min: 1, max: 1},
+		{pattern: `(a)-[:add_* *2]->(b)`, ids: []string{"add_*"}, min: 2, max: 2},
+		{pattern: `(a)-[:add_*|stir]->(b)`, ids: []string{"add_*", "stir"}, min: 1, max: 1},
+		{pattern: `(a)-[:add_*{ok: true}]->(b)`, ids: []string{"add_*"}, min: 1, max: 1},
+		{pattern: `(a)-[:*]->(b)`, ids: []string{"*"}, min: 1, max: 1},
+		{pattern: `(a)-[:*_flour]->(b)`, ids: []string{"*_flour"}, min: 1, max: 1},
+		{pattern: `(a)-[r *1..3]->(b)`, min: 1, max: 3},
+		{pattern: `(a)-[*]->(b)`, min: 1, max: -1},
+	}
+
+	for _, test := range tests {
+		t.Run(test.pattern, func(t *testing.T) {
+			p, err := layupv1.ParsePattern(test.pattern)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			l := p.Links[0]
+			if strings.Join(l.IDs, "|") != strings.Join(test.ids, "|") || l.MinHops != test.min || l.MaxHops != test.max {
+				t.Fatalf("expected IDs %q with %d..%d hops, got %q with %d..%d hops", test.ids, test.min, test.max, l.IDs, l.MinHops, l.MaxHops)
+			}
+
+			// The pattern must round trip through its string form.
+			p2, err := layupv1.ParsePattern(p.String())
+			if err != nil {
+				t.Fatalf("failed to parse %s: %v", p, err)
+			}
+
+			l2 := p2.Links[0]
+			if strings.Join(l2.IDs, "|") != strings.Join(l.IDs, "|") || l2.MinHops != l.MinHops || l2.MaxHops != l.MaxHops {
+				t.Fatalf("expected %s to round trip, got %s", p, p2)
+			}
+		})
+	}
+}
+
+func TestParsePattern_errors(t *testing.T) {
+	for _, pattern := range []string{
+		``,
+		`a`,
+		`(a`,
+		`(a)-`,
+		`(a)-->`,
+		`(a)<-->(b)`,
+		`(a:)`,
+		`(a {x: nope})`,
+		`(a {x: "unterminated})`,
+		`(a)-[*3..2]->(b)`,
+		`(a)-[:uses*2]->(b)`,
+		`(a)-[:uses*1..3]->(b)`,
+		`(a)-[:uses*..3]->(b)`,
+		`(a)-[:stir|uses*2]->(b)`,
+		`(a)-[add_*]->(b)`,
+		`(a)-[r*2]->(b)`,
+		`(a)-[a]->(b)`,
+		`(a)-[r]->(b)-[r]->(c)`,
+	} {
+		if _, err := layupv1.ParsePattern(pattern); err == nil {
+			t.Fatalf("expected error for %q, got nil", pattern)
+		}
+	}
+}
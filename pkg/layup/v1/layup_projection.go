@@ -0,0 +1,336 @@
+package layupv1
+
+import (
+	"fmt"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// IDs of the layers in the models returned by CollapseLayers and Flatten.
+const (
+	CollapsedLayerID = "layers"
+	FlattenedLayerID = "flattened"
+)
+
+// NamespaceSeparator joins the IDs of a layer and one of its nodes (or
+// links) into a single ID, which is unique across layers, such as the IDs
+// of the nodes in a flattened model ("layer__node"), or the IDs of the
+// aggregated links between two nodes ("from__to").
+const NamespaceSeparator = "__"
+
+// Attributes set on the nodes and links of the models returned by
+// CollapseLayers, Flatten and Project.
+const (
+	// LayerIDAttribute is the ID of the layer a flattened node or link was
+	// originally in.
+	LayerIDAttribute = "layup_layer"
+	// NodeIDAttribute is the original ID of a flattened node.
+	NodeIDAttribute = "layup_node"
+	// LinkIDAttribute is the original ID of a flattened link.
+	LinkIDAttribute = "layup_link"
+	// NodeCountAttribute is the number of nodes within a collapsed layer.
+	NodeCountAttribute = "nodes"
+	// LinkCountAttribute is the number of links between the nodes within a
+	// collapsed layer, or the number of links an aggregated link represents.
+	LinkCountAttribute = "links"
+)
+
+// namespacedID returns the given IDs joined by the NamespaceSeparator.
+func namespacedID(a, b string) string {
+	return a + NamespaceSeparator + b
+}
+
+// aggregatedLinks counts links between pairs of node IDs, in the order
+// they were first added.
+type aggregatedLinks struct {
+	order  []*Link
+	counts map[string]*Link
+}
+
+func (a *aggregatedLinks) add(from, to string) {
+	id := namespacedID(from, to)
+
+	if link, ok := a.counts[id]; ok {
+		n := link.Attributes[LinkCountAttribute].GetNumberValue()
+		link.Attributes[LinkCountAttribute] = structpb.NewNumberValue(n + 1)
+		return
+	}
+
+	link := &Link{
+		Id:   id,
+		From: from,
+		To:   to,
+		Attributes: map[string]*structpb.Value{
+			LinkCountAttribute: structpb.NewNumberValue(1),
+		},
+	}
+
+	if a.counts == nil {
+		a.counts = map[string]*Link{}
+	}
+
+	a.counts[id] = link
+	a.order = append(a.order, link)
+}
+
+// CollapseLayers returns a new model with a single layer (CollapsedLayerID)
+// containing one node for each layer of the graph, with the layer's
+// attributes, and one link for each pair of layers with links between
+// them, which is the graph between the layers.
+//
+// The nodes have the number of nodes within the layer (NodeCountAttribute)
+// and links between them (LinkCountAttribute) as attributes, and the links
+// have the number of links they aggregate (LinkCountAttribute).
+//
+// Only the links allowed by the given options (such as WithLinkIDs) are
+// counted, and WithLayers restricts the layers which are collapsed. Links
+// to nodes outside of the model are ignored. The model isn't required to
+// be acyclic, since the graph between layers often isn't.
+func (g *Graph) CollapseLayers(opts ...TraversalOption) (*Model, error) {
+	c := newTraversalConfig(opts)
+
+	var (
+		nodes = map[string]*Node{}
+		order []*Node
+	)
+
+	for _, layer := range g.Layers() {
+		if c.layers != nil {
+			if _, ok := c.layers[layer.GetId()]; !ok {
+				continue
+			}
+		}
+
+		attrs := projectedAttributes(layer.GetAttributes())
+		if attrs == nil {
+			attrs = map[string]*structpb.Value{}
+		}
+
+		attrs[NodeCountAttribute] = structpb.NewNumberValue(float64(len(g.LayerNodes(layer.GetId()))))
+		attrs[LinkCountAttribute] = structpb.NewNumberValue(0)
+
+		n := &Node{
+			Id:         layer.GetId(),
+			Attributes: attrs,
+		}
+
+		nodes[layer.GetId()] = n
+		order = append(order, n)
+	}
+
+	var links aggregatedLinks
+
+	for _, e := range g.Links() {
+		if e.External || !c.allowsLink(e) {
+			continue
+		}
+
+		from, ok := nodes[g.nodeLayers[e.From].GetId()]
+		if !ok {
+			continue
+		}
+
+		to, ok := nodes[g.nodeLayers[e.To].GetId()]
+		if !ok {
+			continue
+		}
+
+		if from == to {
+			n := from.Attributes[LinkCountAttribute].GetNumberValue()
+			from.Attributes[LinkCountAttribute] = structpb.NewNumberValue(n + 1)
+			continue
+		}
+
+		links.add(from.GetId(), to.GetId())
+	}
+
+	m := &Model{
+		Uri:        g.model.GetUri(),
+		Attributes: projectedAttributes(g.model.GetAttributes()),
+		Layers: []*Layer{
+			{
+				Id:    CollapsedLayerID,
+				Nodes: order,
+				Links: links.order,
+			},
+		},
+	}
+
+	if err := Validate(m); err != nil {
+		return nil, fmt.Errorf("failed to collapse layers: %w", err)
+	}
+
+	return m, nil
+}
+
+// Flatten returns a new model with a single layer (FlattenedLayerID)
+// containing copies of every node and link of the graph, merged from all
+// of its layers.
+//
+// Node and link IDs are namespaced by their original layer ("layer__node"),
+// and the original IDs are kept as attributes (LayerIDAttribute, and
+// NodeIDAttribute or LinkIDAttribute). Links to nodes outside of the model
+// are kept as they are. An error is returned if namespacing the IDs isn't
+// enough to make them unique, such as layer "a" with node "b__c" and layer
+// "a__b" with node "c".
+func (g *Graph) Flatten() (*Model, error) {
+	flat := &Layer{
+		Id: FlattenedLayerID,
+	}
+
+	ids := map[string]string{}
+
+	for _, uri := range g.Nodes() {
+		layer := g.nodeLayers[uri]
+
+		n := proto.Clone(g.nodes[uri]).(*Node)
+		if n.Attributes == nil {
+			n.Attributes = map[string]*structpb.Value{}
+		}
+
+		n.Attributes[LayerIDAttribute] = structpb.NewStringValue(layer.GetId())
+		n.Attributes[NodeIDAttribute] = structpb.NewStringValue(n.GetId())
+		n.Id = namespacedID(layer.GetId(), n.GetId())
+
+		ids[uri] = n.GetId()
+		flat.Nodes = append(flat.Nodes, n)
+	}
+
+	for _, e := range g.Links() {
+		link := proto.Clone(e.Link).(*Link)
+		if link.Attributes == nil {
+			link.Attributes = map[string]*structpb.Value{}
+		}
+
+		link.Attributes[LayerIDAttribute] = structpb.NewStringValue(e.Layer.GetId())
+		link.Attributes[LinkIDAttribute] = structpb.NewStringValue(link.GetId())
+		link.Id = namespacedID(e.Layer.GetId(), link.GetId())
+		link.From = ids[e.From]
+
+		if e.External {
+			link.To = e.To
+		} else {
+			link.To = ids[e.To]
+		}
+
+		flat.Links = append(flat.Links, link)
+	}
+
+	m := &Model{
+		Uri:        g.model.GetUri(),
+		Attributes: cloneAttributes(g.model.GetAttributes()),
+		Layers:     []*Layer{flat},
+	}
+
+	if err := Validate(m); err != nil {
+		return nil, fmt.Errorf("failed to flatten model: %w", err)
+	}
+
+	return m, nil
+}
+
+// Project returns a new model with a single layer, containing copies of the
+// nodes of the onto layer, linked wherever the nodes of the from layer they
+// are linked to are linked, such as projecting the calls between services
+// onto the hosts running them.
+//
+// A node of the from layer maps onto every node of the onto layer it is
+// linked to, or linked from. Each link within the from layer becomes a link
+// between the nodes its ends map onto, aggregated into one link for each
+// pair of nodes with the number of links it represents (LinkCountAttribute).
+// Links between the nodes mapped onto the same node are ignored.
+//
+// The given options (such as WithLinkIDs) restrict the links of the from
+// layer which are projected, not the links used to map between the layers.
+func (g *Graph) Project(from, onto string, opts ...TraversalOption) (*Model, error) {
+	c := newTraversalConfig(opts)
+
+	if _, ok := g.layers[from]; !ok {
+		return nil, fmt.Errorf("layer %q not found in model", from)
+	}
+
+	ontoLayer, ok := g.layers[onto]
+	if !ok {
+		return nil, fmt.Errorf("layer %q not found in model", onto)
+	}
+
+	if from == onto {
+		return nil, fmt.Errorf("can't project layer %q onto itself", from)
+	}
+
+	// Map each node of the from layer to the IDs of the nodes of the
+	// onto layer it is linked with, in either direction.
+	mapping := map[string][]string{}
+
+	addMapping := func(node, target string) {
+		if g.nodeLayers[node].GetId() != from || g.nodeLayers[target].GetId() != onto {
+			return
+		}
+
+		id := g.nodes[target].GetId()
+		for _, existing := range mapping[node] {
+			if existing == id {
+				return
+			}
+		}
+
+		mapping[node] = append(mapping[node], id)
+	}
+
+	for _, e := range g.Links() {
+		if e.External {
+			continue
+		}
+
+		addMapping(e.From, e.To)
+		addMapping(e.To, e.From)
+	}
+
+	var links aggregatedLinks
+
+	for _, e := range g.Links() {
+		if e.External || e.Layer.GetId() != from || g.nodeLayers[e.To].GetId() != from || !c.allowsLink(e) {
+			continue
+		}
+
+		for _, x := range mapping[e.From] {
+			for _, y := range mapping[e.To] {
+				if x != y {
+					links.add(x, y)
+				}
+			}
+		}
+	}
+
+	layer := &Layer{
+		Id:         ontoLayer.GetId(),
+		Attributes: projectedAttributes(ontoLayer.GetAttributes()),
+		Links:      links.order,
+	}
+
+	for _, n := range ontoLayer.GetNodes() {
+		layer.Nodes = append(layer.Nodes, proto.Clone(n).(*Node))
+	}
+
+	m := &Model{
+		Uri:        g.model.GetUri(),
+		Attributes: projectedAttributes(g.model.GetAttributes()),
+		Layers:     []*Layer{layer},
+	}
+
+	if err := Validate(m); err != nil {
+		return nil, fmt.Errorf("failed to project layer %q onto %q: %w", from, onto, err)
+	}
+
+	return m, nil
+}
+
+// projectedAttributes returns a copy of the given attributes without the
+// AcyclicAttribute, since aggregating links can introduce cycles which
+// weren't in the original graph.
+func projectedAttributes(attrs map[string]*structpb.Value) map[string]*structpb.Value {
+	clone := cloneAttributes(attrs)
+	delete(clone, AcyclicAttribute)
+	return clone
+}
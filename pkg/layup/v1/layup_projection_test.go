@@ -0,0 +1,153 @@
+package layupv1_test
+
+import (
+	"testing"
+
+	layupv1 "github.com/picatz/layup/pkg/layup/v1"
+)
+
+const testDeployment = `
+uri     = "layup://deployment"
+acyclic = true
+
+layer "services" {
+	node "web" {}
+	node "api" {}
+	node "db" {}
+
+	link "web_api" {
+		from = node.web
+		to   = node.api
+	}
+
+	link "api_db" {
+		from = node.api
+		to   = node.db
+	}
+
+	link "runs_on_a" {
+		from = node.web
+		to   = layer.hosts.node.a
+	}
+
+	link "runs_on_b" {
+		from = node.api
+		to   = layer.hosts.node.b
+	}
+
+	link "runs_on_c" {
+		from = node.db
+		to   = layer.hosts.node.c
+	}
+}
+
+layer "hosts" {
+	node "a" {}
+	node "b" {}
+	node "c" {}
+
+	link "backup" {
+		from = node.c
+		to   = node.a
+	}
+}
+`
+
+func TestGraph_CollapseLayers(t *testing.T) {
+	g := newTestGraph(t, thisProject)
+
+	m, err := g.CollapseLayers()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	layer := m.GetLayers()[0]
+
+	if layer.GetId() != layupv1.CollapsedLayerID || len(layer.GetNodes()) != 4 {
+		t.Fatalf("unexpected layer: %v", layer)
+	}
+
+	counts := map[string]float64{}
+	for _, n := range layer.GetNodes() {
+		counts[n.GetId()] = n.GetAttributes()[layupv1.LinkCountAttribute].GetNumberValue()
+	}
+
+	if counts["go"] != 2 || counts["buf"] != 0 {
+		t.Fatalf("unexpected link counts: %v", counts)
+	}
+
+	if len(layer.GetLinks()) != 5 {
+		t.Fatalf("unexpected links: %v", layer.GetLinks())
+	}
+
+	m, err = g.CollapseLayers(layupv1.WithLinkIDs("uses"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	links := m.GetLayers()[0].GetLinks()
+	if len(links) != 2 || links[0].GetId() != "buf__go" {
+		t.Fatalf("unexpected links: %v", links)
+	}
+}
+
+func TestGraph_Flatten(t *testing.T) {
+	g := newTestGraph(t, thisProject)
+
+	m, err := g.Flatten()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	flat, err := layupv1.NewGraph(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(flat.Nodes()) != len(g.Nodes()) || len(flat.Links()) != len(g.Links()) {
+		t.Fatalf("unexpected model: %v", m)
+	}
+
+	n, ok := flat.Node("layup://example/layers/flattened/nodes/go__runtime")
+	if !ok {
+		t.Fatal("expected namespaced node")
+	}
+
+	if n.GetAttributes()[layupv1.LayerIDAttribute].GetStringValue() != "go" || n.GetAttributes()[layupv1.NodeIDAttribute].GetStringValue() != "runtime" {
+		t.Fatalf("unexpected attributes: %v", n.GetAttributes())
+	}
+
+	e, ok := flat.Link("layup://example/layers/flattened/links/buf__uses")
+	if !ok || e.External || e.To != "layup://example/layers/flattened/nodes/go__runtime" {
+		t.Fatalf("unexpected link: %+v", e)
+	}
+}
+
+func TestGraph_Project(t *testing.T) {
+	g := newTestGraph(t, testDeployment)
+
+	m, err := g.Project("services", "hosts")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	layer := m.GetLayers()[0]
+
+	if layer.GetId() != "hosts" || len(layer.GetNodes()) != 3 {
+		t.Fatalf("unexpected layer: %v", layer)
+	}
+
+	// The backup link between hosts isn't part of the projection.
+	var ids []string
+	for _, link := range layer.GetLinks() {
+		ids = append(ids, link.GetId())
+	}
+
+	if len(ids) != 2 || ids[0] != "a__b" || ids[1] != "b__c" {
+		t.Fatalf("unexpected links: %v", ids)
+	}
+
+	if _, err := g.Project("services", "nope"); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
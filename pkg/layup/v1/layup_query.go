@@ -0,0 +1,384 @@
+package layupv1
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+	"github.com/google/cel-go/common/types/traits"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// QueryTarget is the kind of element a query matches.
+type QueryTarget int
+
+const (
+	// QueryNodes matches nodes, using the "node" variable.
+	QueryNodes QueryTarget = iota
+	// QueryLinks matches links, using the "link" variable.
+	QueryLinks
+
+	// queryAnyTarget is the target of expressions using neither variable.
+	queryAnyTarget QueryTarget = -1
+)
+
+// String returns the name of the variable for the target.
+func (t QueryTarget) String() string {
+	switch t {
+	case QueryNodes:
+		return "node"
+	case QueryLinks:
+		return "link"
+	default:
+		return fmt.Sprintf("QueryTarget(%d)", int(t))
+	}
+}
+
+// QueryOption configures a query.
+type QueryOption func(*queryConfig)
+
+type queryConfig struct {
+	selection string
+}
+
+// WithSelect sets a CEL expression to project each match into a value,
+// such as `node.attributes.url` or `{"id": node.id, "uses": size(outgoing(node, "uses"))}`,
+// instead of the node or link itself.
+func WithSelect(expr string) QueryOption {
+	return func(c *queryConfig) {
+		c.selection = expr
+	}
+}
+
+// QueryMatch is a node or link matched by a query.
+type QueryMatch struct {
+	// URI is the canonical URI of the node or link.
+	URI string `json:"uri"`
+	// Value is the result of the selection (WithSelect) for the match, or
+	// the node or link itself, as it is given to the query.
+	Value any `json:"value"`
+}
+
+// QueryResult is the result of a query.
+type QueryResult struct {
+	// Target is the kind of element matched by the query.
+	Target QueryTarget `json:"-"`
+	// Matches are the matching nodes or links, in model order.
+	Matches []QueryMatch `json:"matches"`
+
+	graph *Graph
+}
+
+// URIs returns the canonical URIs of the matches.
+func (r *QueryResult) URIs() []string {
+	uris := make([]string, 0, len(r.Matches))
+	for _, m := range r.Matches {
+		uris = append(uris, m.URI)
+	}
+	return uris
+}
+
+// Model returns the matches as a subgraph of the queried model. For nodes,
+// this is the subgraph of the matching nodes. For links, it is the matching
+// links only, with the nodes they connect.
+func (r *QueryResult) Model(opts ...SubgraphOption) (*Model, error) {
+	if r.Target == QueryNodes {
+		return r.graph.Subgraph(r.URIs(), opts...)
+	}
+
+	var (
+		nodes []string
+		links = map[string]struct{}{}
+	)
+
+	for _, uri := range r.URIs() {
+		e := r.graph.links[uri]
+
+		nodes = append(nodes, e.From)
+		if !e.External {
+			nodes = append(nodes, e.To)
+		}
+
+		links[uri] = struct{}{}
+	}
+
+	m, err := r.graph.Subgraph(nodes, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	// The subgraph keeps the layer and link IDs, so the links keep their
+	// canonical URIs too.
+	for _, layer := range m.GetLayers() {
+		var matched []*Link
+		for _, link := range layer.GetLinks() {
+			if _, ok := links[linkURI(m, layer.GetId(), link.GetId())]; ok {
+				matched = append(matched, link)
+			}
+		}
+		layer.Links = matched
+	}
+
+	return m, nil
+}
+
+// Query returns the nodes, or links, of the graph matching the given CEL
+// expression, which must evaluate to a bool.
+//
+// Expressions using the "node" variable match nodes, and those using the
+// "link" variable match links. Nodes have an id, uri, layer, external and
+// attributes field, such as `node.layer == "tools" && node.attributes.material == "wood"`.
+// Links have the same fields, as well as from and to, which are the
+// canonical URIs of the nodes they connect.
+//
+// The following functions are available to follow links from a node,
+// returning a list of nodes, with an optional link ID to follow:
+//
+//	outgoing(node) / outgoing(node, "link_id")
+//	incoming(node) / incoming(node, "link_id")
+//
+// Nodes and links the expression fails to evaluate for, such as when an
+// attribute is missing, don't match. Use has() to check for attributes.
+func (g *Graph) Query(expr string, opts ...QueryOption) (*QueryResult, error) {
+	c := &queryConfig{}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	q := &query{graph: g, nodes: map[string]map[string]any{}}
+
+	env, err := q.env()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create query environment: %w", err)
+	}
+
+	where, target, err := q.compile(env, expr, cel.BoolType)
+	if err != nil {
+		return nil, err
+	}
+
+	var selection cel.Program
+	if c.selection != "" {
+		var selectionTarget QueryTarget
+
+		selection, selectionTarget, err = q.compile(env, c.selection, cel.DynType)
+		if err != nil {
+			return nil, fmt.Errorf("invalid selection: %w", err)
+		}
+
+		if selectionTarget != target && selectionTarget != queryAnyTarget {
+			return nil, fmt.Errorf("selection uses %q, but the query matches %ss", selectionTarget, target)
+		}
+	}
+
+	if target == queryAnyTarget {
+		target = QueryNodes
+	}
+
+	result := &QueryResult{
+		Target:  target,
+		Matches: []QueryMatch{},
+		graph:   g,
+	}
+
+	var elements []queryElement
+
+	switch target {
+	case QueryNodes:
+		for _, uri := range g.Nodes() {
+			elements = append(elements, queryElement{uri, q.node(uri)})
+		}
+	case QueryLinks:
+		for _, e := range g.Links() {
+			elements = append(elements, queryElement{e.URI, q.link(e)})
+		}
+	}
+
+	for _, elem := range elements {
+		vars := map[string]any{target.String(): elem.value}
+
+		out, _, err := where.Eval(vars)
+		if err != nil || out != types.True {
+			continue
+		}
+
+		match := QueryMatch{URI: elem.uri, Value: elem.value}
+
+		if selection != nil {
+			out, _, err := selection.Eval(vars)
+			if err != nil {
+				return nil, fmt.Errorf("failed to evaluate selection for %q: %w", elem.uri, err)
+			}
+
+			match.Value, err = nativeValue(out)
+			if err != nil {
+				return nil, fmt.Errorf("failed to evaluate selection for %q: %w", elem.uri, err)
+			}
+		}
+
+		result.Matches = append(result.Matches, match)
+	}
+
+	return result, nil
+}
+
+// queryElement is a node or link given to a query.
+type queryElement struct {
+	uri   string
+	value map[string]any
+}
+
+// query holds the state of a query against a graph.
+type query struct {
+	graph *Graph
+	// nodes are the values of the nodes given to the query, by URI, which
+	// are cached since they're created again for every link followed.
+	nodes map[string]map[string]any
+}
+
+// env returns the CEL environment for queries against the graph.
+func (q *query) env() (*cel.Env, error) {
+	element := cel.MapType(cel.StringType, cel.DynType)
+	elements := cel.ListType(element)
+
+	follow := func(name string, links func(uri string) []*Edge, other func(e *Edge) string) cel.EnvOption {
+		nodes := func(node ref.Val, id string) ref.Val {
+			m, ok := node.(traits.Mapper)
+			if !ok {
+				return types.NewErr("%s: expected a node, got %s", name, node.Type())
+			}
+
+			uri, ok := m.Get(types.String("uri")).(types.String)
+			if !ok {
+				return types.NewErr("%s: expected a node, got a map without a uri", name)
+			}
+
+			list := []any{}
+			for _, e := range links(string(uri)) {
+				if id == "" || e.Link.GetId() == id {
+					list = append(list, q.node(other(e)))
+				}
+			}
+
+			return types.DefaultTypeAdapter.NativeToValue(list)
+		}
+
+		return cel.Function(name,
+			cel.Overload(name+"_node", []*cel.Type{element}, elements,
+				cel.UnaryBinding(func(node ref.Val) ref.Val {
+					return nodes(node, "")
+				}),
+			),
+			cel.Overload(name+"_node_string", []*cel.Type{element, cel.StringType}, elements,
+				cel.BinaryBinding(func(node, id ref.Val) ref.Val {
+					s, ok := id.(types.String)
+					if !ok {
+						return types.MaybeNoSuchOverloadErr(id)
+					}
+					return nodes(node, string(s))
+				}),
+			),
+		)
+	}
+
+	return cel.NewEnv(
+		cel.CrossTypeNumericComparisons(true),
+		cel.Variable(QueryNodes.String(), element),
+		cel.Variable(QueryLinks.String(), element),
+		follow("outgoing", q.graph.OutLinks, func(e *Edge) string { return e.To }),
+		follow("incoming", q.graph.InLinks, func(e *Edge) string { return e.From }),
+	)
+}
+
+// compile returns the program for the given expression, which must have the
+// given output type, and the target of the variable it uses (or
+// queryAnyTarget if it doesn't use either).
+func (q *query) compile(env *cel.Env, expr string, output *cel.Type) (cel.Program, QueryTarget, error) {
+	ast, iss := env.Compile(expr)
+	if iss.Err() != nil {
+		return nil, 0, fmt.Errorf("failed to compile query: %w", iss.Err())
+	}
+
+	if output != cel.DynType && !ast.OutputType().IsExactType(output) && ast.OutputType() != cel.DynType {
+		return nil, 0, fmt.Errorf("query must evaluate to a %s, got %s", output, ast.OutputType())
+	}
+
+	uses := map[string]struct{}{}
+	for _, r := range ast.NativeRep().ReferenceMap() {
+		uses[r.Name] = struct{}{}
+	}
+
+	target := queryAnyTarget
+
+	_, node := uses[QueryNodes.String()]
+	_, link := uses[QueryLinks.String()]
+
+	switch {
+	case node && link:
+		return nil, 0, fmt.Errorf("query can't use both %q and %q", QueryNodes, QueryLinks)
+	case node:
+		target = QueryNodes
+	case link:
+		target = QueryLinks
+	}
+
+	prg, err := env.Program(ast)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to create query program: %w", err)
+	}
+
+	return prg, target, nil
+}
+
+// node returns the value of the node with the given URI, which is given to
+// queries as the "node" variable, and returned by outgoing and incoming.
+func (q *query) node(uri string) map[string]any {
+	if v, ok := q.nodes[uri]; ok {
+		return v
+	}
+
+	v := map[string]any{
+		"id":         "",
+		"uri":        uri,
+		"layer":      "",
+		"external":   true,
+		"attributes": map[string]any{},
+	}
+
+	if n, ok := q.graph.nodes[uri]; ok {
+		v["id"] = n.GetId()
+		v["layer"] = q.graph.nodeLayers[uri].GetId()
+		v["external"] = false
+		v["attributes"] = (&structpb.Struct{Fields: n.GetAttributes()}).AsMap()
+	}
+
+	q.nodes[uri] = v
+
+	return v
+}
+
+// link returns the value of the given link, which is given to queries as
+// the "link" variable.
+func (q *query) link(e *Edge) map[string]any {
+	return map[string]any{
+		"id":         e.Link.GetId(),
+		"uri":        e.URI,
+		"layer":      e.Layer.GetId(),
+		"from":       e.From,
+		"to":         e.To,
+		"external":   e.External,
+		"attributes": (&structpb.Struct{Fields: e.Link.GetAttributes()}).AsMap(),
+	}
+}
+
+// nativeValue converts a CEL value into a JSON compatible Go value.
+func nativeValue(v ref.Val) (any, error) {
+	native, err := v.ConvertToNative(reflect.TypeOf(&structpb.Value{}))
+	if err != nil {
+		return nil, err
+	}
+
+	return native.(*structpb.Value).AsInterface(), nil
+}
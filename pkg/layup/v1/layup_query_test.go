@@ -0,0 +1,148 @@
+package layupv1_test
+
+import (
+	"testing"
+
+	layupv1 "github.com/picatz/layup/pkg/layup/v1"
+)
+
+func TestGraph_Query(t *testing.T) {
+	g := newTestGraph(t, thisProject)
+
+	tests := []struct {
+		name   string
+		expr   string
+		target layupv1.QueryTarget
+		want   []string
+	}{
+		{
+			name:   "node layer",
+			expr:   `node.layer == "buf"`,
+			target: layupv1.QueryNodes,
+			want:   []string{"layup://example/layers/buf/nodes/cli"},
+		},
+		{
+			name:   "node attribute",
+			expr:   `has(node.attributes.url) && node.attributes.url.startsWith("https://golang.org")`,
+			target: layupv1.QueryNodes,
+			want: []string{
+				"layup://example/layers/go/nodes/language",
+				"layup://example/layers/go/nodes/runtime",
+			},
+		},
+		{
+			name:   "missing attribute",
+			expr:   `node.attributes.url == "https://google.com"`,
+			target: layupv1.QueryNodes,
+			want:   []string{"layup://example/layers/go/nodes/owner"},
+		},
+		{
+			name:   "outgoing",
+			expr:   `outgoing(node, "uses").exists(n, n.layer == "go")`,
+			target: layupv1.QueryNodes,
+			want: []string{
+				"layup://example/layers/buf/nodes/cli",
+				"layup://example/layers/layup/nodes/cli",
+			},
+		},
+		{
+			name:   "incoming",
+			expr:   `size(incoming(node)) > 2`,
+			target: layupv1.QueryNodes,
+			want:   []string{"layup://example/layers/go/nodes/runtime"},
+		},
+		{
+			name:   "links",
+			expr:   `link.id == "uses"`,
+			target: layupv1.QueryLinks,
+			want: []string{
+				"layup://example/layers/buf/links/uses",
+				"layup://example/layers/layup/links/uses",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := g.Query(test.expr)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if result.Target != test.target {
+				t.Fatalf("unexpected target: %v", result.Target)
+			}
+
+			got := result.URIs()
+			if len(got) != len(test.want) {
+				t.Fatalf("expected %v, got %v", test.want, got)
+			}
+
+			for i := range got {
+				if got[i] != test.want[i] {
+					t.Fatalf("expected %v, got %v", test.want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestGraph_Query_errors(t *testing.T) {
+	g := newTestGraph(t, thisProject)
+
+	for _, expr := range []string{
+		`node.id ==`,
+		`size(node)`,
+		`node.id == link.id`,
+		`nope(node)`,
+	} {
+		if _, err := g.Query(expr); err == nil {
+			t.Fatalf("expected error for %q, got nil", expr)
+		}
+	}
+
+	if _, err := g.Query(`link.id == "uses"`, layupv1.WithSelect("node.id")); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
+
+func TestGraph_Query_select(t *testing.T) {
+	g := newTestGraph(t, thisProject)
+
+	result, err := g.Query(`node.layer == "go"`, layupv1.WithSelect(`{"id": node.id, "users": size(incoming(node, "uses"))}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(result.Matches) != 3 {
+		t.Fatalf("unexpected matches: %v", result.Matches)
+	}
+
+	v, ok := result.Matches[2].Value.(map[string]any)
+	if !ok || v["id"] != "runtime" || v["users"] != float64(2) {
+		t.Fatalf("unexpected value: %#v", result.Matches[2].Value)
+	}
+}
+
+func TestQueryResult_Model(t *testing.T) {
+	g := newTestGraph(t, thisProject)
+
+	result, err := g.Query(`link.id == "uses"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := result.Model()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sub, err := layupv1.NewGraph(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(sub.Nodes()) != 3 || len(sub.Links()) != 2 {
+		t.Fatalf("unexpected model: %v", m)
+	}
+}
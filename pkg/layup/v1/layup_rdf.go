@@ -0,0 +1,410 @@
+package layupv1
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// DefaultRDFVocabulary is the vocabulary IRI used for the classes and
+// properties of RDF documents written from a Layup model, unless another
+// one is given with WithRDFVocabulary.
+const DefaultRDFVocabulary = "https://github.com/picatz/layup/vocab#"
+
+// Well-known vocabularies used when writing RDF.
+const (
+	rdfNamespace = "http://www.w3.org/1999/02/22-rdf-syntax-ns#"
+	xsdNamespace = "http://www.w3.org/2001/XMLSchema#"
+)
+
+// RDFOption configures how RDF documents are written.
+type RDFOption func(*rdfConfig)
+
+type rdfConfig struct {
+	prefix string
+	vocab  string
+}
+
+// WithRDFVocabulary sets the vocabulary IRI, and the prefix used for it in
+// Turtle and JSON-LD documents. The IRI should end with a "#" or "/" so
+// terms can be appended to it.
+func WithRDFVocabulary(prefix, iri string) RDFOption {
+	return func(c *rdfConfig) {
+		c.prefix = prefix
+		c.vocab = iri
+	}
+}
+
+func newRDFConfig(opts []RDFOption) *rdfConfig {
+	c := &rdfConfig{
+		prefix: "layup",
+		vocab:  DefaultRDFVocabulary,
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// term returns the IRI of the given term in the configured vocabulary.
+func (c *rdfConfig) term(name string) string {
+	return c.vocab + name
+}
+
+// linkPredicate returns the IRI of the predicate used to relate the nodes
+// of links with the given ID.
+func (c *rdfConfig) linkPredicate(id string) string {
+	return c.vocab + "links/" + url.PathEscape(id)
+}
+
+// attributePredicate returns the IRI of the predicate used for attributes
+// with the given key.
+func (c *rdfConfig) attributePredicate(key string) string {
+	return c.vocab + "attributes/" + url.PathEscape(key)
+}
+
+// rdfTerm is either an IRI or a literal value with a datatype.
+type rdfTerm struct {
+	iri      string
+	value    string
+	datatype string
+}
+
+func rdfIRI(iri string) rdfTerm {
+	return rdfTerm{iri: iri}
+}
+
+func rdfLiteral(value, datatype string) rdfTerm {
+	return rdfTerm{value: value, datatype: datatype}
+}
+
+type rdfTriple struct {
+	subject   string
+	predicate string
+	object    rdfTerm
+}
+
+// rdfAttributeLiteral converts an attribute value into a typed literal,
+// returning false for null values which have no RDF representation.
+func rdfAttributeLiteral(v *structpb.Value) (rdfTerm, bool) {
+	switch kind := v.GetKind().(type) {
+	case *structpb.Value_StringValue:
+		return rdfLiteral(kind.StringValue, xsdNamespace+"string"), true
+	case *structpb.Value_BoolValue:
+		return rdfLiteral(strconv.FormatBool(kind.BoolValue), xsdNamespace+"boolean"), true
+	case *structpb.Value_NumberValue:
+		n := kind.NumberValue
+		if n == math.Trunc(n) && math.Abs(n) < 1e15 {
+			return rdfLiteral(strconv.FormatFloat(n, 'f', 0, 64), xsdNamespace+"integer"), true
+		}
+		return rdfLiteral(strconv.FormatFloat(n, 'E', -1, 64), xsdNamespace+"double"), true
+	case *structpb.Value_ListValue, *structpb.Value_StructValue:
+		b, err := json.Marshal(v.AsInterface())
+		if err != nil {
+			return rdfTerm{}, false
+		}
+		return rdfLiteral(string(b), rdfNamespace+"JSON"), true
+	default:
+		return rdfTerm{}, false
+	}
+}
+
+// rdfTriples returns the triples describing the given model.
+//
+// Every layer, node and link is a resource identified by its canonical URI.
+// Links are also written as reified statements, so their IDs and attributes
+// are kept alongside the (asserted) statement relating the two nodes.
+func rdfTriples(m *Model, c *rdfConfig) []rdfTriple {
+	var triples []rdfTriple
+
+	add := func(s, p string, o rdfTerm) {
+		triples = append(triples, rdfTriple{subject: s, predicate: p, object: o})
+	}
+
+	addAttributes := func(s string, attrs map[string]*structpb.Value) {
+		for _, k := range sortedKeys(attrs) {
+			if lit, ok := rdfAttributeLiteral(attrs[k]); ok {
+				add(s, c.attributePredicate(k), lit)
+			}
+		}
+	}
+
+	add(m.GetUri(), rdfNamespace+"type", rdfIRI(c.term("Model")))
+	addAttributes(m.GetUri(), m.GetAttributes())
+
+	for _, layer := range m.GetLayers() {
+		lu := layerURI(m, layer.GetId())
+
+		add(lu, rdfNamespace+"type", rdfIRI(c.term("Layer")))
+		add(lu, c.term("id"), rdfLiteral(layer.GetId(), xsdNamespace+"string"))
+		add(lu, c.term("model"), rdfIRI(m.GetUri()))
+		addAttributes(lu, layer.GetAttributes())
+
+		for _, n := range layer.GetNodes() {
+			nu := nodeURI(m, layer.GetId(), n.GetId())
+
+			add(nu, rdfNamespace+"type", rdfIRI(c.term("Node")))
+			add(nu, c.term("id"), rdfLiteral(n.GetId(), xsdNamespace+"string"))
+			add(nu, c.term("layer"), rdfIRI(lu))
+			addAttributes(nu, n.GetAttributes())
+		}
+
+		for _, link := range layer.GetLinks() {
+			from := nodeURI(m, layer.GetId(), link.GetFrom())
+			to := linkTargetURI(m, layer, link)
+			pred := c.linkPredicate(link.GetId())
+
+			add(from, pred, rdfIRI(to))
+
+			lu := linkURI(m, layer.GetId(), link.GetId())
+
+			add(lu, rdfNamespace+"type", rdfIRI(c.term("Link")))
+			add(lu, rdfNamespace+"type", rdfIRI(rdfNamespace+"Statement"))
+			add(lu, rdfNamespace+"subject", rdfIRI(from))
+			add(lu, rdfNamespace+"predicate", rdfIRI(pred))
+			add(lu, rdfNamespace+"object", rdfIRI(to))
+			add(lu, c.term("id"), rdfLiteral(link.GetId(), xsdNamespace+"string"))
+			add(lu, c.term("layer"), rdfIRI(layerURI(m, layer.GetId())))
+			addAttributes(lu, link.GetAttributes())
+		}
+	}
+
+	return triples
+}
+
+// rdfEscapeIRI percent-encodes characters which are not allowed within an
+// IRI reference in N-Triples and Turtle documents.
+func rdfEscapeIRI(iri string) string {
+	var sb strings.Builder
+	for _, r := range iri {
+		switch {
+		case r <= 0x20, strings.ContainsRune("<>\"{}|^`\\", r):
+			fmt.Fprintf(&sb, "%%%02X", r)
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
+
+// rdfEscapeString escapes a string for use within a quoted literal.
+func rdfEscapeString(s string) string {
+	var sb strings.Builder
+	for _, r := range s {
+		switch r {
+		case '\\':
+			sb.WriteString(`\\`)
+		case '"':
+			sb.WriteString(`\"`)
+		case '\n':
+			sb.WriteString(`\n`)
+		case '\r':
+			sb.WriteString(`\r`)
+		case '\t':
+			sb.WriteString(`\t`)
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
+
+// WriteNTriples writes an RDF N-Triples document to the given writer using
+// the given Layup model's data.
+func WriteNTriples(w io.Writer, m *Model, opts ...RDFOption) error {
+	c := newRDFConfig(opts)
+
+	bw := bufio.NewWriter(w)
+
+	for _, t := range rdfTriples(m, c) {
+		bw.WriteString("<" + rdfEscapeIRI(t.subject) + "> <" + rdfEscapeIRI(t.predicate) + "> ")
+
+		if t.object.iri != "" {
+			bw.WriteString("<" + rdfEscapeIRI(t.object.iri) + ">")
+		} else {
+			bw.WriteString(`"` + rdfEscapeString(t.object.value) + `"^^<` + t.object.datatype + ">")
+		}
+
+		bw.WriteString(" .\n")
+	}
+
+	return bw.Flush()
+}
+
+// turtlePrefixes returns the prefixes used in Turtle documents, in the
+// order they should be declared.
+func turtlePrefixes(c *rdfConfig) [][2]string {
+	return [][2]string{
+		{"rdf", rdfNamespace},
+		{"xsd", xsdNamespace},
+		{c.prefix, c.vocab},
+		{"link", c.vocab + "links/"},
+		{"attr", c.vocab + "attributes/"},
+	}
+}
+
+// isTurtleLocalName reports whether the given string can be used as the
+// local part of a prefixed name without any escaping.
+func isTurtleLocalName(s string) bool {
+	if s == "" || s[0] == '-' || s[0] == '.' || s[len(s)-1] == '.' {
+		return false
+	}
+	for _, r := range s {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '_', r == '-':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// turtleIRI returns the given IRI as a prefixed name if possible, using
+// the longest matching prefix, otherwise as an IRI reference.
+func turtleIRI(iri string, prefixes [][2]string) string {
+	best := -1
+	for i, p := range prefixes {
+		if strings.HasPrefix(iri, p[1]) && isTurtleLocalName(iri[len(p[1]):]) {
+			if best == -1 || len(p[1]) > len(prefixes[best][1]) {
+				best = i
+			}
+		}
+	}
+
+	if best == -1 {
+		return "<" + rdfEscapeIRI(iri) + ">"
+	}
+
+	return prefixes[best][0] + ":" + iri[len(prefixes[best][1]):]
+}
+
+// WriteTurtle writes an RDF Turtle document to the given writer using the
+// given Layup model's data.
+func WriteTurtle(w io.Writer, m *Model, opts ...RDFOption) error {
+	c := newRDFConfig(opts)
+	prefixes := turtlePrefixes(c)
+
+	bw := bufio.NewWriter(w)
+
+	for _, p := range prefixes {
+		bw.WriteString("@prefix " + p[0] + ": <" + rdfEscapeIRI(p[1]) + "> .\n")
+	}
+
+	var (
+		subject   string
+		predicate string
+	)
+
+	for _, t := range rdfTriples(m, c) {
+		switch {
+		case t.subject != subject:
+			if subject != "" {
+				bw.WriteString(" .\n")
+			}
+			bw.WriteString("\n" + turtleIRI(t.subject, prefixes) + "\n\t")
+			if t.predicate == rdfNamespace+"type" {
+				bw.WriteString("a ")
+			} else {
+				bw.WriteString(turtleIRI(t.predicate, prefixes) + " ")
+			}
+		case t.predicate != predicate:
+			bw.WriteString(" ;\n\t" + turtleIRI(t.predicate, prefixes) + " ")
+		default:
+			bw.WriteString(", ")
+		}
+
+		subject, predicate = t.subject, t.predicate
+
+		switch {
+		case t.object.iri != "":
+			bw.WriteString(turtleIRI(t.object.iri, prefixes))
+		case t.object.datatype == xsdNamespace+"string":
+			bw.WriteString(`"` + rdfEscapeString(t.object.value) + `"`)
+		case t.object.datatype == xsdNamespace+"integer", t.object.datatype == xsdNamespace+"boolean":
+			bw.WriteString(t.object.value)
+		default:
+			bw.WriteString(`"` + rdfEscapeString(t.object.value) + `"^^` + turtleIRI(t.object.datatype, prefixes))
+		}
+	}
+
+	if subject != "" {
+		bw.WriteString(" .\n")
+	}
+
+	return bw.Flush()
+}
+
+// WriteJSONLD writes a JSON-LD document to the given writer using the
+// given Layup model's data.
+func WriteJSONLD(w io.Writer, m *Model, opts ...RDFOption) error {
+	c := newRDFConfig(opts)
+
+	prefixes := [][2]string{
+		{"rdf", rdfNamespace},
+		{"xsd", xsdNamespace},
+		{c.prefix, c.vocab},
+	}
+
+	compact := func(iri string) string {
+		for _, p := range prefixes {
+			if rest, ok := strings.CutPrefix(iri, p[1]); ok && rest != "" && !strings.HasPrefix(rest, "//") {
+				return p[0] + ":" + rest
+			}
+		}
+		return iri
+	}
+
+	context := map[string]any{}
+	for _, p := range prefixes {
+		context[p[0]] = p[1]
+	}
+
+	var (
+		graph    []map[string]any
+		subjects = map[string]map[string]any{}
+	)
+
+	for _, t := range rdfTriples(m, c) {
+		obj, ok := subjects[t.subject]
+		if !ok {
+			obj = map[string]any{"@id": t.subject}
+			subjects[t.subject] = obj
+			graph = append(graph, obj)
+		}
+
+		if t.predicate == rdfNamespace+"type" {
+			types, _ := obj["@type"].([]string)
+			obj["@type"] = append(types, compact(t.object.iri))
+			continue
+		}
+
+		var value map[string]any
+		if t.object.iri != "" {
+			value = map[string]any{"@id": t.object.iri}
+		} else {
+			value = map[string]any{"@value": t.object.value, "@type": compact(t.object.datatype)}
+		}
+
+		key := compact(t.predicate)
+		values, _ := obj[key].([]map[string]any)
+		obj[key] = append(values, value)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(map[string]any{
+		"@context": context,
+		"@graph":   graph,
+	})
+}
@@ -0,0 +1,112 @@
+package layupv1_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	layupv1 "github.com/picatz/layup/pkg/layup/v1"
+)
+
+func TestWriteNTriples(t *testing.T) {
+	model, err := layupv1.ParseHCL(strings.NewReader(thisProject))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ntBuffer := strings.Builder{}
+
+	if err := layupv1.WriteNTriples(&ntBuffer, model); err != nil {
+		t.Fatal(err)
+	}
+
+	out := ntBuffer.String()
+
+	for _, want := range []string{
+		`<layup://example/layers/go/nodes/runtime> <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <https://github.com/picatz/layup/vocab#Node> .`,
+		`<layup://example/layers/go/nodes/runtime> <https://github.com/picatz/layup/vocab#attributes/url> "https://golang.org/pkg/runtime"^^<http://www.w3.org/2001/XMLSchema#string> .`,
+		`<layup://example/layers/buf/nodes/cli> <https://github.com/picatz/layup/vocab#links/uses> <layup://example/layers/go/nodes/runtime> .`,
+		`<layup://example/layers/buf/links/uses> <http://www.w3.org/1999/02/22-rdf-syntax-ns#subject> <layup://example/layers/buf/nodes/cli> .`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("expected output to contain:\n%s\n\ngot:\n%s", want, out)
+		}
+	}
+
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		if !strings.HasSuffix(line, " .") {
+			t.Fatalf("invalid triple: %s", line)
+		}
+	}
+}
+
+func TestWriteTurtle(t *testing.T) {
+	model, err := layupv1.ParseHCL(strings.NewReader(thisProject))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ttlBuffer := strings.Builder{}
+
+	err = layupv1.WriteTurtle(&ttlBuffer, model, layupv1.WithRDFVocabulary("ex", "https://example.com/vocab#"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out := ttlBuffer.String()
+
+	for _, want := range []string{
+		"@prefix ex: <https://example.com/vocab#> .",
+		"<layup://example/layers/go/nodes/runtime>\n\ta ex:Node ;",
+		`attr:url "https://golang.org/pkg/runtime"`,
+		"a ex:Link, rdf:Statement ;",
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("expected output to contain:\n%s\n\ngot:\n%s", want, out)
+		}
+	}
+}
+
+func TestWriteJSONLD(t *testing.T) {
+	model, err := layupv1.ParseHCL(strings.NewReader(thisProject))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jsonldBuffer := strings.Builder{}
+
+	if err := layupv1.WriteJSONLD(&jsonldBuffer, model); err != nil {
+		t.Fatal(err)
+	}
+
+	var doc struct {
+		Context map[string]string `json:"@context"`
+		Graph   []map[string]any  `json:"@graph"`
+	}
+
+	if err := json.Unmarshal([]byte(jsonldBuffer.String()), &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	if doc.Context["layup"] != layupv1.DefaultRDFVocabulary {
+		t.Fatalf("unexpected context: %v", doc.Context)
+	}
+
+	var found bool
+	for _, obj := range doc.Graph {
+		if obj["@id"] != "layup://example/layers/layup/links/conversion" {
+			continue
+		}
+
+		found = true
+
+		subject, _ := obj["rdf:subject"].([]any)
+		if len(subject) != 1 || subject[0].(map[string]any)["@id"] != "layup://example/layers/layup/nodes/hcl" {
+			t.Fatalf("unexpected subject: %v", obj["rdf:subject"])
+		}
+	}
+
+	if !found {
+		t.Fatal("expected link resource in graph")
+	}
+}
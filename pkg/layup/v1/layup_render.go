@@ -0,0 +1,111 @@
+package layupv1
+
+// DefaultPalette is a set of distinct, light colors used to tell groups of
+// nodes apart when rendering, such as the components of a graph.
+var DefaultPalette = []string{
+	"#8dd3c7", "#ffffb3", "#bebada", "#fb8072", "#80b1d3", "#fdb462",
+	"#b3de69", "#fccde5", "#d9d9d9", "#bc80bd", "#ccebc5", "#ffed6f",
+}
+
+// RenderOption configures how a model is rendered by WriteDOT, WriteMermiad,
+// WriteD2 and WriteSVG, such as to highlight some of its nodes and links.
+type RenderOption func(*renderConfig)
+
+type renderConfig struct {
+	nodeColors map[string]string
+	linkColors map[string]string
+	nodeLabels map[string]string
+	linkLabels map[string]string
+}
+
+// WithNodeColors sets the fill color (e.g. "#fb8072" or "red") of the nodes
+// with the given canonical URIs. If given multiple times, the colors are
+// merged, with later colors taking precedence.
+func WithNodeColors(colors map[string]string) RenderOption {
+	return func(c *renderConfig) {
+		for uri, color := range colors {
+			c.nodeColors[uri] = color
+		}
+	}
+}
+
+// WithLinkColors sets the stroke color of the links with the given canonical
+// URIs. If given multiple times, the colors are merged, with later colors
+// taking precedence.
+func WithLinkColors(colors map[string]string) RenderOption {
+	return func(c *renderConfig) {
+		for uri, color := range colors {
+			c.linkColors[uri] = color
+		}
+	}
+}
+
+// WithNodeLabels sets the label of the nodes with the given canonical URIs,
+// instead of their ID, which may span multiple lines. Labels are used by
+// WriteDOT, WriteMermiad, WriteD2 and WriteSVG.
+func WithNodeLabels(labels map[string]string) RenderOption {
+	return func(c *renderConfig) {
+		for uri, label := range labels {
+			c.nodeLabels[uri] = label
+		}
+	}
+}
+
+// WithLinkLabels sets the label of the links with the given canonical URIs,
+// instead of their ID, like WithNodeLabels.
+func WithLinkLabels(labels map[string]string) RenderOption {
+	return func(c *renderConfig) {
+		for uri, label := range labels {
+			c.linkLabels[uri] = label
+		}
+	}
+}
+
+func newRenderConfig(opts []RenderOption) *renderConfig {
+	c := &renderConfig{
+		nodeColors: map[string]string{},
+		linkColors: map[string]string{},
+		nodeLabels: map[string]string{},
+		linkLabels: map[string]string{},
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// nodeLabel returns the label of the given node, which is its ID unless set
+// by WithNodeLabels.
+func (c *renderConfig) nodeLabel(m *Model, layer *Layer, n *Node) string {
+	if label, ok := c.nodeLabels[nodeURI(m, layer.GetId(), n.GetId())]; ok {
+		return label
+	}
+	return n.GetId()
+}
+
+// linkLabel returns the label of the given link, which is its ID unless set
+// by WithLinkLabels.
+func (c *renderConfig) linkLabel(m *Model, layer *Layer, link *Link) string {
+	if label, ok := c.linkLabels[linkURI(m, layer.GetId(), link.GetId())]; ok {
+		return label
+	}
+	return link.GetId()
+}
+
+// GroupColors returns the color of each node within the given groups of
+// canonical node URIs (such as connected components), using a color from
+// the DefaultPalette for each group, which repeat if there are more groups
+// than colors. The result can be given to WithNodeColors.
+func GroupColors(groups [][]string) map[string]string {
+	colors := map[string]string{}
+
+	for i, group := range groups {
+		for _, uri := range group {
+			colors[uri] = DefaultPalette[i%len(DefaultPalette)]
+		}
+	}
+
+	return colors
+}
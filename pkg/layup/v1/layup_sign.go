@@ -0,0 +1,154 @@
+package layupv1
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// SignatureAttribute is the model attribute containing a signature
+// embedded by EmbedSignature, which is excluded from the signed content,
+// so a model can carry its own signature.
+const SignatureAttribute = "layup_signature"
+
+// SignatureAlgorithm is the algorithm of signatures created by Sign.
+const SignatureAlgorithm = "ed25519"
+
+// ErrInvalidSignature is returned when a signature doesn't match a model,
+// such as when the model was changed after being signed.
+var ErrInvalidSignature = errors.New("invalid signature")
+
+// Signature is a detached signature of a model.
+type Signature struct {
+	// Algorithm is the signature algorithm, which is SignatureAlgorithm.
+	Algorithm string `json:"algorithm"`
+	// KeyID identifies the public key which verifies the signature (see
+	// KeyID).
+	KeyID string `json:"key_id"`
+	// Hash is the content hash of the signed model (see Hash), without
+	// its embedded signature, to show which model was signed.
+	Hash string `json:"hash"`
+	// Value is the signature of the model's canonical serialization (see
+	// CanonicalJSON), without its embedded signature.
+	Value []byte `json:"value"`
+}
+
+// KeyID returns the ID of the given public key, which is the SHA-256 hash
+// of the key, prefixed by HashPrefix.
+func KeyID(pub ed25519.PublicKey) string {
+	sum := sha256.Sum256(pub)
+	return HashPrefix + hex.EncodeToString(sum[:])
+}
+
+// Sign returns an Ed25519 signature of the model, which signs its canonical
+// serialization, so it remains valid if the model is reformatted (or
+// converted between HCL and JSON), but not if its content changes.
+func Sign(m *Model, priv ed25519.PrivateKey) (*Signature, error) {
+	if len(priv) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("invalid private key size %d", len(priv))
+	}
+
+	content, err := signedContent(m)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign model: %w", err)
+	}
+
+	return &Signature{
+		Algorithm: SignatureAlgorithm,
+		KeyID:     KeyID(priv.Public().(ed25519.PublicKey)),
+		Hash:      hashBytes(content),
+		Value:     ed25519.Sign(priv, content),
+	}, nil
+}
+
+// Verify verifies the signature of the model using the given public key,
+// returning ErrInvalidSignature if it doesn't match the model.
+func Verify(m *Model, sig *Signature, pub ed25519.PublicKey) error {
+	if sig.Algorithm != SignatureAlgorithm {
+		return fmt.Errorf("unsupported signature algorithm %q", sig.Algorithm)
+	}
+
+	if len(pub) != ed25519.PublicKeySize {
+		return fmt.Errorf("invalid public key size %d", len(pub))
+	}
+
+	if id := KeyID(pub); sig.KeyID != id {
+		return fmt.Errorf("model was signed by key %s, not %s", sig.KeyID, id)
+	}
+
+	content, err := signedContent(m)
+	if err != nil {
+		return fmt.Errorf("failed to verify model: %w", err)
+	}
+
+	if !ed25519.Verify(pub, content, sig.Value) {
+		return ErrInvalidSignature
+	}
+
+	return nil
+}
+
+// EmbedSignature returns a copy of the model with the signature embedded
+// in its SignatureAttribute, replacing any existing signature.
+func EmbedSignature(m *Model, sig *Signature) (*Model, error) {
+	b, err := json.Marshal(sig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed signature: %w", err)
+	}
+
+	var fields map[string]any
+	if err := json.Unmarshal(b, &fields); err != nil {
+		return nil, fmt.Errorf("failed to embed signature: %w", err)
+	}
+
+	v, err := structpb.NewValue(fields)
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed signature: %w", err)
+	}
+
+	signed := proto.Clone(m).(*Model)
+	if signed.Attributes == nil {
+		signed.Attributes = map[string]*structpb.Value{}
+	}
+	signed.Attributes[SignatureAttribute] = v
+
+	return signed, nil
+}
+
+// EmbeddedSignature returns the signature embedded in the model by
+// EmbedSignature, if any.
+func EmbeddedSignature(m *Model) (*Signature, bool, error) {
+	v, ok := m.GetAttributes()[SignatureAttribute]
+	if !ok {
+		return nil, false, nil
+	}
+
+	b, err := json.Marshal(v.AsInterface())
+	if err != nil {
+		return nil, true, fmt.Errorf("invalid embedded signature: %w", err)
+	}
+
+	sig := &Signature{}
+	if err := json.Unmarshal(b, sig); err != nil {
+		return nil, true, fmt.Errorf("invalid embedded signature: %w", err)
+	}
+
+	return sig, true, nil
+}
+
+// signedContent returns the content of the model which is signed, which
+// is its canonical serialization, without its embedded signature.
+func signedContent(m *Model) ([]byte, error) {
+	if _, ok := m.GetAttributes()[SignatureAttribute]; ok {
+		m = proto.Clone(m).(*Model)
+		delete(m.Attributes, SignatureAttribute)
+	}
+
+	return CanonicalJSON(m)
+}
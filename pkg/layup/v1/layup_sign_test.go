@@ -0,0 +1,119 @@
+package layupv1_test
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"strings"
+	"testing"
+
+	layupv1 "github.com/picatz/layup/pkg/layup/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+func TestSign(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := newTestGraph(t, testRecipe).Model()
+
+	sig, err := layupv1.Sign(m, priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if sig.KeyID != layupv1.KeyID(pub) {
+		t.Fatalf("unexpected key ID: %s", sig.KeyID)
+	}
+
+	hash, err := layupv1.Hash(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if sig.Hash != hash {
+		t.Fatalf("expected signature of %s, got %s", hash, sig.Hash)
+	}
+
+	if err := layupv1.Verify(m, sig, pub); err != nil {
+		t.Fatal(err)
+	}
+
+	// The signature is of the model's content, so it is still valid after
+	// being converted to JSON.
+	b, err := protojson.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	converted := &layupv1.Model{}
+	if err := protojson.Unmarshal(b, converted); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := layupv1.Verify(converted, sig, pub); err != nil {
+		t.Fatal(err)
+	}
+
+	tampered := newTestGraph(t, strings.Replace(testRecipe, `material = "wood"`, `material = "plastic"`, 1)).Model()
+
+	if err := layupv1.Verify(tampered, sig, pub); !errors.Is(err, layupv1.ErrInvalidSignature) {
+		t.Fatalf("expected invalid signature, got: %v", err)
+	}
+
+	other, _, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := layupv1.Verify(m, sig, other); err == nil || !strings.Contains(err.Error(), "signed by key") {
+		t.Fatalf("expected key mismatch, got: %v", err)
+	}
+}
+
+func TestEmbedSignature(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := newTestGraph(t, testRecipe).Model()
+
+	if _, ok, err := layupv1.EmbeddedSignature(m); ok || err != nil {
+		t.Fatalf("expected no embedded signature, got: %v, %v", ok, err)
+	}
+
+	sig, err := layupv1.Sign(m, priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signed, err := layupv1.EmbedSignature(m, sig)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := m.GetAttributes()[layupv1.SignatureAttribute]; ok {
+		t.Fatal("expected the original model to be unchanged")
+	}
+
+	embedded, ok, err := layupv1.EmbeddedSignature(signed)
+	if !ok || err != nil {
+		t.Fatalf("expected embedded signature, got: %v, %v", ok, err)
+	}
+
+	if err := layupv1.Verify(signed, embedded, pub); err != nil {
+		t.Fatal(err)
+	}
+
+	// Signing a signed model ignores its existing signature.
+	resigned, err := layupv1.Sign(signed, priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if resigned.Hash != sig.Hash {
+		t.Fatalf("expected the same signed content, got %s and %s", resigned.Hash, sig.Hash)
+	}
+}
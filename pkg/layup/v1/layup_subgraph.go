@@ -0,0 +1,266 @@
+package layupv1
+
+import (
+	"fmt"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// StubAttribute is the boolean attribute set on the placeholder nodes added
+// to a subgraph by StubDangling.
+const StubAttribute = "stub"
+
+// DanglingPolicy decides what happens to the links of a subgraph which are
+// going to nodes that aren't part of the subgraph.
+type DanglingPolicy int
+
+const (
+	// DropDangling removes the links going to nodes outside of the subgraph.
+	DropDangling DanglingPolicy = iota
+	// KeepDangling keeps the links going to nodes outside of the subgraph,
+	// rewriting their "to" into the canonical URI of the original node, so
+	// they are links to another system, like the original model.
+	KeepDangling
+	// StubDangling keeps the links going to nodes outside of the subgraph,
+	// adding a placeholder node without any of the original attributes (other
+	// than the StubAttribute) for each of them.
+	StubDangling
+)
+
+// String returns the name of the policy.
+func (p DanglingPolicy) String() string {
+	switch p {
+	case DropDangling:
+		return "drop"
+	case KeepDangling:
+		return "keep"
+	case StubDangling:
+		return "stub"
+	default:
+		return fmt.Sprintf("DanglingPolicy(%d)", int(p))
+	}
+}
+
+// ParseDanglingPolicy returns the policy with the given name.
+func ParseDanglingPolicy(s string) (DanglingPolicy, error) {
+	for _, p := range []DanglingPolicy{DropDangling, KeepDangling, StubDangling} {
+		if p.String() == s {
+			return p, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown dangling link policy %q, must be one of: drop, keep, stub", s)
+}
+
+// SubgraphOption configures how a subgraph is extracted from a model.
+type SubgraphOption func(*subgraphConfig)
+
+type subgraphConfig struct {
+	dangling  DanglingPolicy
+	traversal []TraversalOption
+}
+
+// WithDangling sets the policy for links going to nodes outside of the
+// subgraph, which are dropped by default.
+func WithDangling(policy DanglingPolicy) SubgraphOption {
+	return func(c *subgraphConfig) {
+		c.dangling = policy
+	}
+}
+
+// WithTraversal sets the options used to traverse the graph when selecting
+// the nodes of a subgraph, such as the links followed by Neighborhood.
+func WithTraversal(opts ...TraversalOption) SubgraphOption {
+	return func(c *subgraphConfig) {
+		c.traversal = append(c.traversal, opts...)
+	}
+}
+
+func newSubgraphConfig(opts []SubgraphOption) *subgraphConfig {
+	c := &subgraphConfig{
+		dangling: DropDangling,
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// NodeFilter reports whether the node with the given canonical URI should
+// be selected.
+type NodeFilter func(uri string, n *Node) bool
+
+// NodeAttributeEquals returns a NodeFilter selecting the nodes with the given
+// attribute set to the given value, which must be a value supported by
+// structpb.NewValue (e.g. a string, number or bool).
+func NodeAttributeEquals(key string, value any) NodeFilter {
+	want, err := structpb.NewValue(value)
+
+	return func(_ string, n *Node) bool {
+		got, ok := n.GetAttributes()[key]
+		return ok && err == nil && proto.Equal(got, want)
+	}
+}
+
+// Subgraph returns a new model containing copies of the nodes with the given
+// canonical URIs, the layers containing them, and the links between them.
+// Links going to nodes which aren't selected are handled according to the
+// configured DanglingPolicy, so the result is always a valid model.
+func (g *Graph) Subgraph(nodes []string, opts ...SubgraphOption) (*Model, error) {
+	c := newSubgraphConfig(opts)
+
+	selected := map[string]struct{}{}
+	for _, uri := range nodes {
+		if _, ok := g.nodes[uri]; !ok {
+			return nil, fmt.Errorf("node %q not found in model", uri)
+		}
+		selected[uri] = struct{}{}
+	}
+
+	// Find the links to keep, and the stubs needed for them, before
+	// copying the layers, since links can go to any other layer.
+	var (
+		links = map[*Link]*Link{}
+		stubs = map[string]struct{}{}
+	)
+
+	for _, e := range g.linkOrder {
+		if _, ok := selected[e.From]; !ok {
+			continue
+		}
+
+		link := proto.Clone(e.Link).(*Link)
+
+		if _, ok := selected[e.To]; !ok && !e.External {
+			switch c.dangling {
+			case DropDangling:
+				continue
+			case KeepDangling:
+				link.To = e.To
+			case StubDangling:
+				stubs[e.To] = struct{}{}
+			}
+		}
+
+		links[e.Link] = link
+	}
+
+	m := &Model{
+		Uri:        g.model.GetUri(),
+		Attributes: cloneAttributes(g.model.GetAttributes()),
+	}
+
+	for _, layer := range g.model.GetLayers() {
+		sub := &Layer{
+			Id:         layer.GetId(),
+			Attributes: cloneAttributes(layer.GetAttributes()),
+		}
+
+		if layer.Dynamic != nil {
+			sub.Dynamic = proto.Bool(layer.GetDynamic())
+		}
+
+		for _, n := range layer.GetNodes() {
+			uri := nodeURI(g.model, layer.GetId(), n.GetId())
+
+			if _, ok := selected[uri]; ok {
+				sub.Nodes = append(sub.Nodes, proto.Clone(n).(*Node))
+				continue
+			}
+
+			if _, ok := stubs[uri]; ok {
+				sub.Nodes = append(sub.Nodes, &Node{
+					Id: n.GetId(),
+					Attributes: map[string]*structpb.Value{
+						StubAttribute: structpb.NewBoolValue(true),
+					},
+				})
+			}
+		}
+
+		for _, link := range layer.GetLinks() {
+			if sl, ok := links[link]; ok {
+				sub.Links = append(sub.Links, sl)
+			}
+		}
+
+		if len(sub.Nodes) > 0 {
+			m.Layers = append(m.Layers, sub)
+		}
+	}
+
+	if err := Validate(m); err != nil {
+		return nil, fmt.Errorf("failed to extract a valid subgraph: %w", err)
+	}
+
+	return m, nil
+}
+
+// Neighborhood returns the subgraph of the nodes within k links of the node
+// with the given canonical URI, following links in both directions unless
+// configured otherwise using WithTraversal.
+func (g *Graph) Neighborhood(node string, k int, opts ...SubgraphOption) (*Model, error) {
+	c := newSubgraphConfig(opts)
+
+	traversal := append([]TraversalOption{WithDirection(Both)}, c.traversal...)
+	traversal = append(traversal, WithMaxDepth(k))
+
+	nodes := []string{}
+
+	err := g.BFS(node, func(v Visit) error {
+		if !v.External {
+			nodes = append(nodes, v.Node)
+		}
+		return nil
+	}, traversal...)
+	if err != nil {
+		return nil, err
+	}
+
+	return g.Subgraph(nodes, opts...)
+}
+
+// LayerSubgraph returns the subgraph of the nodes within the layers with
+// the given IDs.
+func (g *Graph) LayerSubgraph(layerIDs []string, opts ...SubgraphOption) (*Model, error) {
+	var nodes []string
+
+	for _, id := range layerIDs {
+		if _, ok := g.layers[id]; !ok {
+			return nil, fmt.Errorf("layer %q not found in model", id)
+		}
+		nodes = append(nodes, g.layerNodes[id]...)
+	}
+
+	return g.Subgraph(nodes, opts...)
+}
+
+// FilterSubgraph returns the subgraph of the nodes selected by the given
+// filter, such as NodeAttributeEquals.
+func (g *Graph) FilterSubgraph(filter NodeFilter, opts ...SubgraphOption) (*Model, error) {
+	var nodes []string
+
+	for _, uri := range g.nodeOrder {
+		if filter(uri, g.nodes[uri]) {
+			nodes = append(nodes, uri)
+		}
+	}
+
+	return g.Subgraph(nodes, opts...)
+}
+
+// cloneAttributes returns a deep copy of the given attributes.
+func cloneAttributes(attrs map[string]*structpb.Value) map[string]*structpb.Value {
+	if attrs == nil {
+		return nil
+	}
+
+	clone := make(map[string]*structpb.Value, len(attrs))
+	for k, v := range attrs {
+		clone[k] = proto.Clone(v).(*structpb.Value)
+	}
+
+	return clone
+}
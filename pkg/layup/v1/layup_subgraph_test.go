@@ -0,0 +1,150 @@
+package layupv1_test
+
+import (
+	"testing"
+
+	layupv1 "github.com/picatz/layup/pkg/layup/v1"
+)
+
+func TestGraph_Subgraph(t *testing.T) {
+	g := newTestGraph(t, thisProject)
+
+	nodes := []string{
+		"layup://example/layers/layup/nodes/schema",
+		"layup://example/layers/buf/nodes/cli",
+	}
+
+	countLinks := func(m *layupv1.Model) (n int) {
+		for _, layer := range m.GetLayers() {
+			n += len(layer.GetLinks())
+		}
+		return n
+	}
+
+	t.Run("drop", func(t *testing.T) {
+		m, err := g.Subgraph(nodes)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(m.GetLayers()) != 2 {
+			t.Fatalf("unexpected layers: %v", m.GetLayers())
+		}
+
+		// Only the link from the schema to the buf CLI remains.
+		if countLinks(m) != 1 {
+			t.Fatalf("unexpected links: %v", m.GetLayers())
+		}
+	})
+
+	t.Run("keep", func(t *testing.T) {
+		m, err := g.Subgraph(nodes, layupv1.WithDangling(layupv1.KeepDangling))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if countLinks(m) != 4 {
+			t.Fatalf("unexpected links: %v", m.GetLayers())
+		}
+
+		sub, err := layupv1.NewGraph(m)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		e, ok := sub.Link("layup://example/layers/buf/links/uses")
+		if !ok || !e.External || e.To != "layup://example/layers/go/nodes/runtime" {
+			t.Fatalf("unexpected link: %+v", e)
+		}
+	})
+
+	t.Run("stub", func(t *testing.T) {
+		m, err := g.Subgraph(nodes, layupv1.WithDangling(layupv1.StubDangling))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		sub, err := layupv1.NewGraph(m)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		n, ok := sub.Node("layup://example/layers/go/nodes/runtime")
+		if !ok || !n.GetAttributes()[layupv1.StubAttribute].GetBoolValue() {
+			t.Fatalf("expected stub node, got %v", n)
+		}
+
+		if len(sub.Nodes()) != 5 {
+			t.Fatalf("unexpected nodes: %v", sub.Nodes())
+		}
+	})
+
+	t.Run("unknown node", func(t *testing.T) {
+		if _, err := g.Subgraph([]string{"layup://example/layers/go/nodes/nope"}); err == nil {
+			t.Fatal("expected error, got nil")
+		}
+	})
+}
+
+func TestGraph_Neighborhood(t *testing.T) {
+	g := newTestGraph(t, thisProject)
+
+	m, err := g.Neighborhood("layup://example/layers/go/nodes/runtime", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sub, err := layupv1.NewGraph(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The runtime, the language implementing it, and the CLIs using it.
+	if len(sub.Nodes()) != 4 {
+		t.Fatalf("unexpected nodes: %v", sub.Nodes())
+	}
+
+	m, err = g.Neighborhood("layup://example/layers/go/nodes/runtime", 1,
+		layupv1.WithTraversal(layupv1.WithLinkIDs("implementation")),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(m.GetLayers()) != 1 || len(m.GetLayers()[0].GetNodes()) != 2 {
+		t.Fatalf("unexpected model: %v", m)
+	}
+}
+
+func TestGraph_LayerSubgraph(t *testing.T) {
+	g := newTestGraph(t, thisProject)
+
+	m, err := g.LayerSubgraph([]string{"go", "github"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(m.GetLayers()) != 2 {
+		t.Fatalf("unexpected layers: %v", m.GetLayers())
+	}
+
+	if _, err := g.LayerSubgraph([]string{"nope"}); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
+
+func TestGraph_FilterSubgraph(t *testing.T) {
+	g := newTestGraph(t, testCycles)
+
+	m, err := g.FilterSubgraph(func(uri string, n *layupv1.Node) bool {
+		return n.GetId() != "b"
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the feedback links remain, since b is gone.
+	if len(m.GetLayers()[0].GetLinks()) != 2 {
+		t.Fatalf("unexpected links: %v", m.GetLayers()[0].GetLinks())
+	}
+}
@@ -0,0 +1,141 @@
+package layupv1
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// svgStyle is the stylesheet embedded in SVG documents, which can be
+// overridden by the page the document is embedded in.
+const svgStyle = `
+    .layup-cluster rect { fill: #f6f8fa; stroke: #8c959f; stroke-width: 1; }
+    .layup-cluster text { font: bold 12px sans-serif; fill: #24292f; }
+    .layup-node rect { fill: #ffffff; stroke: #24292f; stroke-width: 1.5; }
+    .layup-node.external rect { fill: #ffffff; stroke-dasharray: 4 2; }
+    .layup-node text { font: 12px sans-serif; fill: #24292f; text-anchor: middle; dominant-baseline: central; }
+    .layup-link path { fill: none; stroke: #57606a; stroke-width: 1.2; }
+    .layup-link text { font: 10px sans-serif; fill: #57606a; text-anchor: middle; }
+`
+
+// svgEscape escapes the given string for use in SVG text and attributes.
+func svgEscape(s string) string {
+	var sb strings.Builder
+	xml.EscapeText(&sb, []byte(s))
+	return sb.String()
+}
+
+// svgColorStyle returns a style attribute setting the given property to the
+// given color, or nothing if the color is empty.
+func svgColorStyle(property, color string) string {
+	if color == "" {
+		return ""
+	}
+	return fmt.Sprintf(" style=\"%s: %s\"", property, svgEscape(color))
+}
+
+// svgText returns a text element for the given label, centered on the
+// given point, with a tspan for each line if it spans multiple lines.
+func svgText(x, y float64, label string) string {
+	lines := strings.Split(label, "\n")
+	if len(lines) == 1 {
+		return fmt.Sprintf("<text x=\"%.1f\" y=\"%.1f\">%s</text>", x, y, svgEscape(label))
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "<text x=\"%.1f\" y=\"%.1f\">", x, y-float64(len(lines)-1)*layoutLineHeight/2)
+	for i, line := range lines {
+		dy := 0.0
+		if i > 0 {
+			dy = layoutLineHeight
+		}
+		fmt.Fprintf(&sb, "<tspan x=\"%.1f\" dy=\"%.1f\">%s</tspan>", x, dy, svgEscape(line))
+	}
+	sb.WriteString("</text>")
+	return sb.String()
+}
+
+// svgPath returns the SVG path data for the given points, using straight
+// segments between each point.
+func svgPath(points [][2]float64) string {
+	var sb strings.Builder
+	for i, p := range points {
+		if i == 0 {
+			sb.WriteString(fmt.Sprintf("M%.1f,%.1f", p[0], p[1]))
+			continue
+		}
+		sb.WriteString(fmt.Sprintf(" L%.1f,%.1f", p[0], p[1]))
+	}
+	return sb.String()
+}
+
+// WriteSVG writes an SVG image to the given writer using the given Layup
+// model's data.
+//
+// Unlike the other renderers, this doesn't require any external tools. The
+// model is positioned by a built-in hierarchical (Sugiyama-style) layout
+// engine, which draws layers as clusters, nodes as boxes, and links as
+// edges routed between them.
+func WriteSVG(w io.Writer, m *Model, opts ...RenderOption) error {
+	c := newRenderConfig(opts)
+
+	l := newLayout(m, c)
+
+	bw := bufio.NewWriter(w)
+
+	bw.WriteString(fmt.Sprintf(
+		"<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"%.0f\" height=\"%.0f\" viewBox=\"0 0 %.0f %.0f\">\n",
+		l.width, l.height, l.width, l.height,
+	))
+	bw.WriteString("  <title>" + svgEscape(m.GetUri()) + "</title>\n")
+	bw.WriteString("  <style>" + svgStyle + "  </style>\n")
+	bw.WriteString("  <defs>\n")
+	bw.WriteString("    <marker id=\"layup-arrow\" viewBox=\"0 0 10 10\" refX=\"10\" refY=\"5\" markerWidth=\"8\" markerHeight=\"8\" orient=\"auto-start-reverse\">\n")
+	bw.WriteString("      <path d=\"M0,0 L10,5 L0,10 z\" fill=\"#57606a\"/>\n")
+	bw.WriteString("    </marker>\n")
+	bw.WriteString("  </defs>\n")
+
+	for _, cluster := range l.clusters {
+		bw.WriteString(fmt.Sprintf("  <g class=\"layup-cluster\" id=%q>\n", svgEscape(cluster.uri)))
+		bw.WriteString(fmt.Sprintf("    <rect x=\"%.1f\" y=\"%.1f\" width=\"%.1f\" height=\"%.1f\" rx=\"6\"/>\n", cluster.x, cluster.y, cluster.w, cluster.h))
+		bw.WriteString(fmt.Sprintf("    <text x=\"%.1f\" y=\"%.1f\">%s</text>\n", cluster.x+8, cluster.y+16, svgEscape(cluster.label)))
+		bw.WriteString("  </g>\n")
+	}
+
+	for _, e := range l.edges {
+		bw.WriteString(fmt.Sprintf("  <g class=\"layup-link\" id=%q>\n", svgEscape(e.uri)))
+		bw.WriteString(fmt.Sprintf("    <path d=\"%s\" marker-end=\"url(#layup-arrow)\"%s/>\n", svgPath(e.points), svgColorStyle("stroke", c.linkColors[e.uri])))
+
+		// Place the label at the middle of the edge's route.
+		mid := len(e.points) / 2
+		a, b := e.points[mid-1], e.points[mid]
+		if e.loop {
+			a, b = e.points[1], e.points[2]
+		}
+		bw.WriteString("    " + svgText((a[0]+b[0])/2+4, (a[1]+b[1])/2, e.label) + "\n")
+		bw.WriteString("  </g>\n")
+	}
+
+	for _, n := range l.nodes {
+		if n.dummy {
+			continue
+		}
+
+		class := "layup-node"
+		if n.group >= len(l.clusters) {
+			class += " external"
+		}
+
+		bw.WriteString(fmt.Sprintf("  <g class=%q id=%q>\n", class, svgEscape(n.uri)))
+		bw.WriteString("    <title>" + svgEscape(n.uri) + "</title>\n")
+		bw.WriteString(fmt.Sprintf("    <rect x=\"%.1f\" y=\"%.1f\" width=\"%.1f\" height=\"%.1f\" rx=\"4\"%s/>\n", n.x-n.w/2, n.y-n.h/2, n.w, n.h, svgColorStyle("fill", c.nodeColors[n.uri])))
+		bw.WriteString("    " + svgText(n.x, n.y, n.label) + "\n")
+		bw.WriteString("  </g>\n")
+	}
+
+	bw.WriteString("</svg>\n")
+
+	return bw.Flush()
+}
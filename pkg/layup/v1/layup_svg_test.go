@@ -0,0 +1,214 @@
+package layupv1_test
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	layupv1 "github.com/picatz/layup/pkg/layup/v1"
+)
+
+func TestWriteSVG(t *testing.T) {
+	model, err := layupv1.ParseHCL(strings.NewReader(thisProject))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	svgBuffer := strings.Builder{}
+
+	if err := layupv1.WriteSVG(&svgBuffer, model); err != nil {
+		t.Fatal(err)
+	}
+
+	type rect struct {
+		X      float64 `xml:"x,attr"`
+		Y      float64 `xml:"y,attr"`
+		Width  float64 `xml:"width,attr"`
+		Height float64 `xml:"height,attr"`
+	}
+
+	var doc struct {
+		XMLName xml.Name `xml:"svg"`
+		Groups  []struct {
+			Class string `xml:"class,attr"`
+			ID    string `xml:"id,attr"`
+			Rect  rect   `xml:"rect"`
+			Path  struct {
+				D string `xml:"d,attr"`
+			} `xml:"path"`
+		} `xml:"g"`
+	}
+
+	if err := xml.Unmarshal([]byte(svgBuffer.String()), &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		clusters = map[string]rect{}
+		nodes    = map[string]rect{}
+		links    int
+	)
+
+	for _, g := range doc.Groups {
+		switch g.Class {
+		case "layup-cluster":
+			clusters[g.ID] = g.Rect
+		case "layup-node":
+			nodes[g.ID] = g.Rect
+		case "layup-link":
+			if !strings.HasPrefix(g.Path.D, "M") {
+				t.Fatalf("link %q has no route: %q", g.ID, g.Path.D)
+			}
+			links++
+		}
+	}
+
+	if len(clusters) != 4 || len(nodes) != 10 || links != 9 {
+		t.Fatalf("unexpected number of clusters (%d), nodes (%d) or links (%d)", len(clusters), len(nodes), links)
+	}
+
+	// Every node should be drawn inside of its layer's cluster.
+	for id, n := range nodes {
+		layer := id[:strings.Index(id, "/nodes/")]
+
+		c, ok := clusters[layer]
+		if !ok {
+			t.Fatalf("missing cluster for node %q", id)
+		}
+
+		if n.X < c.X || n.Y < c.Y || n.X+n.Width > c.X+c.Width || n.Y+n.Height > c.Y+c.Height {
+			t.Fatalf("node %q (%v) is outside of its cluster (%v)", id, n, c)
+		}
+	}
+
+	// Clusters should never overlap.
+	for a, ca := range clusters {
+		for b, cb := range clusters {
+			if a == b {
+				continue
+			}
+
+			if ca.X < cb.X+cb.Width && cb.X < ca.X+ca.Width && ca.Y < cb.Y+cb.Height && cb.Y < ca.Y+ca.Height {
+				t.Fatalf("clusters %q and %q overlap", a, b)
+			}
+		}
+	}
+}
+
+func TestWriteSVG_cycle(t *testing.T) {
+	model, err := layupv1.ParseHCL(strings.NewReader(`
+uri = "layup://test"
+
+layer "1" {
+	node "a" {}
+	node "b" {}
+	node "c" {}
+
+	link "ab" {
+		from = node.a
+		to = node.b
+	}
+
+	link "bc" {
+		from = node.b
+		to = node.c
+	}
+
+	link "ca" {
+		from = node.c
+		to = node.a
+	}
+
+	link "loop" {
+		from = node.a
+		to = node.a
+	}
+
+	link "outside" {
+		from = node.a
+		to = "github://picatz/layup"
+	}
+}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	svgBuffer := strings.Builder{}
+
+	if err := layupv1.WriteSVG(&svgBuffer, model); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(svgBuffer.String(), `class="layup-node external" id="github://picatz/layup"`) {
+		t.Fatalf("expected external node:\n%s", svgBuffer.String())
+	}
+}
+
+func TestWriteSVG_labels(t *testing.T) {
+	model, err := layupv1.ParseHCL(strings.NewReader(`
+uri = "layup://test"
+
+layer "1" {
+	node "a" {}
+	node "b" {}
+
+	link "ab" {
+		from = node.a
+		to = node.b
+	}
+}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	svgBuffer := strings.Builder{}
+
+	err = layupv1.WriteSVG(&svgBuffer, model,
+		layupv1.WithNodeLabels(map[string]string{"layup://test/layers/1/nodes/a": "a\nsize: 1 → 2"}),
+		layupv1.WithLinkLabels(map[string]string{"layup://test/layers/1/links/ab": "mixes"}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var doc struct {
+		Groups []struct {
+			ID   string `xml:"id,attr"`
+			Rect struct {
+				Height float64 `xml:"height,attr"`
+			} `xml:"rect"`
+			Text struct {
+				Value string   `xml:",chardata"`
+				Lines []string `xml:"tspan"`
+			} `xml:"text"`
+		} `xml:"g"`
+	}
+
+	if err := xml.Unmarshal([]byte(svgBuffer.String()), &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	texts := map[string][]string{}
+	heights := map[string]float64{}
+	for _, g := range doc.Groups {
+		texts[g.ID] = g.Text.Lines
+		if len(g.Text.Lines) == 0 {
+			texts[g.ID] = []string{g.Text.Value}
+		}
+		heights[g.ID] = g.Rect.Height
+	}
+
+	if got := strings.Join(texts["layup://test/layers/1/nodes/a"], "\n"); got != "a\nsize: 1 → 2" {
+		t.Fatalf("unexpected node label: %q", got)
+	}
+
+	if got := strings.Join(texts["layup://test/layers/1/links/ab"], "\n"); got != "mixes" {
+		t.Fatalf("unexpected link label: %q", got)
+	}
+
+	if a, b := heights["layup://test/layers/1/nodes/a"], heights["layup://test/layers/1/nodes/b"]; a <= b {
+		t.Fatalf("expected the two line node (%v) to be taller than the one line node (%v)", a, b)
+	}
+}
@@ -0,0 +1,290 @@
+package layupv1
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// DefaultTextWidth is the width, in columns, of text rendered by WriteText
+// and WriteTree, unless another is given with WithTextWidth.
+const DefaultTextWidth = 80
+
+// TextOption configures how text is rendered.
+type TextOption func(*textConfig)
+
+type textConfig struct {
+	width int
+	chars textChars
+}
+
+// WithTextWidth sets the maximum width, in columns, of rendered text. Lines
+// which are too long are wrapped.
+func WithTextWidth(width int) TextOption {
+	return func(c *textConfig) {
+		c.width = width
+	}
+}
+
+// WithASCII renders text using only ASCII characters, instead of Unicode
+// box-drawing characters, for terminals which don't support them.
+func WithASCII() TextOption {
+	return func(c *textConfig) {
+		c.chars = asciiTextChars
+	}
+}
+
+// textChars are the characters used to draw boxes, trees and arrows.
+type textChars struct {
+	topLeft, topRight, bottomLeft, bottomRight string
+	horizontal, vertical                       string
+	bullet, arrow                              string
+	branch, lastBranch, pipe                   string
+}
+
+var (
+	unicodeTextChars = textChars{
+		topLeft: "┌", topRight: "┐", bottomLeft: "└", bottomRight: "┘",
+		horizontal: "─", vertical: "│",
+		bullet: "●", arrow: "▶",
+		branch: "├── ", lastBranch: "└── ", pipe: "│   ",
+	}
+
+	asciiTextChars = textChars{
+		topLeft: "+", topRight: "+", bottomLeft: "+", bottomRight: "+",
+		horizontal: "-", vertical: "|",
+		bullet: "*", arrow: ">",
+		branch: "|-- ", lastBranch: "`-- ", pipe: "|   ",
+	}
+)
+
+func newTextConfig(opts []TextOption) *textConfig {
+	c := &textConfig{
+		width: DefaultTextWidth,
+		chars: unicodeTextChars,
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	// Boxes need some room for their borders and content.
+	c.width = max(c.width, 20)
+
+	return c
+}
+
+// wrapText wraps the given line to the given width, breaking on spaces
+// where possible. Continuation lines are prefixed with the given indent.
+func wrapText(line string, width int, indent string) []string {
+	var lines []string
+
+	// Very deep indentation would leave no room for the text itself.
+	if len(indent) >= width/2 {
+		indent = ""
+	}
+
+	for utf8.RuneCountInString(line) > width {
+		runes := []rune(line)
+
+		// Break at the last space that fits, or hard break if there isn't one
+		// after the indentation.
+		cut := width
+		for i := width; i > len(indent); i-- {
+			if runes[i] == ' ' {
+				cut = i
+				break
+			}
+		}
+
+		lines = append(lines, strings.TrimRight(string(runes[:cut]), " "))
+		line = indent + strings.TrimLeft(string(runes[cut:]), " ")
+	}
+
+	return append(lines, line)
+}
+
+// formatTextValue returns a short, human readable form of an attribute.
+func formatTextValue(v *structpb.Value) string {
+	switch kind := v.GetKind().(type) {
+	case *structpb.Value_StringValue:
+		return kind.StringValue
+	case *structpb.Value_NumberValue:
+		return fmt.Sprint(kind.NumberValue)
+	case *structpb.Value_BoolValue:
+		return fmt.Sprint(kind.BoolValue)
+	case *structpb.Value_NullValue:
+		return "null"
+	default:
+		b, err := json.Marshal(v.AsInterface())
+		if err != nil {
+			return "?"
+		}
+		return string(b)
+	}
+}
+
+// textNodeLabel returns a short label for the node with the given URI,
+// relative to the model (e.g. "tools/bowl"), or the URI itself if the
+// node is outside of the model.
+func textNodeLabel(m *Model, uri string) string {
+	u, err := ParseURI(uri)
+	if err != nil || !u.IsNode() || u.Model != m.GetUri() {
+		return uri
+	}
+
+	return u.Layer + "/" + u.Node
+}
+
+// writeTextBox writes the given lines within a box with the given title.
+func writeTextBox(bw *bufio.Writer, c *textConfig, title string, lines []string) {
+	inner := c.width - 4
+
+	// ┌─ title ───┐
+	title = " " + title + " "
+	if n := utf8.RuneCountInString(title); n > inner {
+		title = string([]rune(title)[:inner-1]) + " "
+	}
+	fill := c.width - 3 - utf8.RuneCountInString(title)
+	bw.WriteString(c.chars.topLeft + c.chars.horizontal + title + strings.Repeat(c.chars.horizontal, fill) + c.chars.topRight + "\n")
+
+	for _, line := range lines {
+		indent := strings.Repeat(" ", len(line)-len(strings.TrimLeft(line, " "))+2)
+
+		for _, l := range wrapText(line, inner, indent) {
+			pad := inner - utf8.RuneCountInString(l)
+			bw.WriteString(c.chars.vertical + " " + l + strings.Repeat(" ", pad) + " " + c.chars.vertical + "\n")
+		}
+	}
+
+	bw.WriteString(c.chars.bottomLeft + strings.Repeat(c.chars.horizontal, c.width-2) + c.chars.bottomRight + "\n")
+}
+
+// WriteText writes a plain text rendering of the given Layup model to the
+// given writer, suitable for viewing in a terminal. Each layer is drawn as
+// a box containing its nodes (with their attributes) and links.
+func WriteText(w io.Writer, m *Model, opts ...TextOption) error {
+	c := newTextConfig(opts)
+
+	bw := bufio.NewWriter(w)
+
+	for _, line := range wrapText(m.GetUri(), c.width, "  ") {
+		bw.WriteString(line + "\n")
+	}
+
+	for _, k := range sortedKeys(m.GetAttributes()) {
+		for _, line := range wrapText("  "+k+": "+formatTextValue(m.GetAttributes()[k]), c.width, "    ") {
+			bw.WriteString(line + "\n")
+		}
+	}
+
+	for _, layer := range m.GetLayers() {
+		var lines []string
+
+		for _, k := range sortedKeys(layer.GetAttributes()) {
+			lines = append(lines, k+": "+formatTextValue(layer.GetAttributes()[k]))
+		}
+
+		if len(layer.GetAttributes()) > 0 && (len(layer.GetNodes()) > 0 || len(layer.GetLinks()) > 0) {
+			lines = append(lines, "")
+		}
+
+		for _, n := range layer.GetNodes() {
+			lines = append(lines, c.chars.bullet+" "+n.GetId())
+
+			for _, k := range sortedKeys(n.GetAttributes()) {
+				lines = append(lines, "    "+k+": "+formatTextValue(n.GetAttributes()[k]))
+			}
+		}
+
+		if len(layer.GetNodes()) > 0 && len(layer.GetLinks()) > 0 {
+			lines = append(lines, "")
+		}
+
+		for _, link := range layer.GetLinks() {
+			to := textNodeLabel(m, linkTargetURI(m, layer, link))
+
+			// Links within the layer are shown with their local ID.
+			if _, err := ParseURI(link.GetTo()); err != nil {
+				to = link.GetTo()
+			}
+
+			lines = append(lines, link.GetFrom()+" "+c.chars.horizontal+c.chars.horizontal+" "+link.GetId()+" "+c.chars.horizontal+c.chars.arrow+" "+to)
+
+			for _, k := range sortedKeys(link.GetAttributes()) {
+				lines = append(lines, "    "+k+": "+formatTextValue(link.GetAttributes()[k]))
+			}
+		}
+
+		bw.WriteString("\n")
+		writeTextBox(bw, c, layer.GetId(), lines)
+	}
+
+	return bw.Flush()
+}
+
+// WriteTree writes a plain text tree to the given writer, rooted at the
+// given node, following each of its outgoing links (across all layers).
+//
+// The root may be a canonical node URI, or a "layer/node" path relative to
+// the model. Nodes which have already been written are not expanded again,
+// which keeps the tree finite when the graph contains cycles.
+func WriteTree(w io.Writer, m *Model, root string, opts ...TextOption) error {
+	c := newTextConfig(opts)
+
+	g, err := NewGraph(m)
+	if err != nil {
+		return err
+	}
+
+	root, err = g.FindNode(root)
+	if err != nil {
+		return fmt.Errorf("invalid root node: %w", err)
+	}
+
+	bw := bufio.NewWriter(w)
+
+	writeLine := func(prefix, line string) {
+		// Continuation lines line up with the start of the line's text.
+		indent := strings.Repeat(" ", utf8.RuneCountInString(prefix))
+		for _, l := range wrapText(prefix+line, c.width, indent+"  ") {
+			bw.WriteString(l + "\n")
+		}
+	}
+
+	writeLine("", textNodeLabel(m, root))
+
+	seen := map[string]bool{root: true}
+
+	var walk func(uri, prefix string)
+	walk = func(uri, prefix string) {
+		edges := g.OutLinks(uri)
+		for i, e := range edges {
+			branch, next := c.chars.branch, c.chars.pipe
+			if i == len(edges)-1 {
+				branch, next = c.chars.lastBranch, "    "
+			}
+
+			line := e.Link.GetId() + " " + c.chars.horizontal + c.chars.arrow + " " + textNodeLabel(m, e.To)
+
+			if seen[e.To] {
+				writeLine(prefix+branch, line+" (...)")
+				continue
+			}
+
+			writeLine(prefix+branch, line)
+
+			seen[e.To] = true
+			walk(e.To, prefix+next)
+		}
+	}
+
+	walk(root, "")
+
+	return bw.Flush()
+}
@@ -0,0 +1,88 @@
+package layupv1_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	layupv1 "github.com/picatz/layup/pkg/layup/v1"
+)
+
+func TestWriteText(t *testing.T) {
+	model, err := layupv1.ParseHCL(strings.NewReader(thisProject))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, width := range []int{80, 40} {
+		t.Run(fmt.Sprintf("width %d", width), func(t *testing.T) {
+			textBuffer := strings.Builder{}
+
+			if err := layupv1.WriteText(&textBuffer, model, layupv1.WithTextWidth(width)); err != nil {
+				t.Fatal(err)
+			}
+
+			out := textBuffer.String()
+
+			for _, line := range strings.Split(out, "\n") {
+				if n := utf8.RuneCountInString(line); n > width {
+					t.Fatalf("line is %d columns wide, expected at most %d: %q", n, width, line)
+				}
+			}
+
+			for _, want := range []string{"┌─ github ─", "● runtime", "cli ── uses ─▶ go/runtime"} {
+				if !strings.Contains(out, want) {
+					t.Fatalf("expected output to contain %q:\n%s", want, out)
+				}
+			}
+
+			fmt.Println(out)
+		})
+	}
+
+	t.Run("ascii", func(t *testing.T) {
+		textBuffer := strings.Builder{}
+
+		if err := layupv1.WriteText(&textBuffer, model, layupv1.WithASCII()); err != nil {
+			t.Fatal(err)
+		}
+
+		for _, r := range textBuffer.String() {
+			if r > utf8.RuneSelf {
+				t.Fatalf("unexpected non-ASCII character %q", r)
+			}
+		}
+	})
+}
+
+func TestWriteTree(t *testing.T) {
+	model, err := layupv1.ParseHCL(strings.NewReader(thisProject))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	treeBuffer := strings.Builder{}
+
+	if err := layupv1.WriteTree(&treeBuffer, model, "layup/schema"); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `layup/schema
+├── schmea_source_code_genration ─▶ buf/cli
+│   ├── maintenance ─▶ github/buf_organization
+│   └── uses ─▶ go/runtime
+└── schema_source_code ─▶ github/this_repository
+`
+
+	if got := treeBuffer.String(); got != want {
+		t.Fatalf("unexpected tree:\n%s\nwant:\n%s", got, want)
+	}
+
+	t.Run("unknown root", func(t *testing.T) {
+		err := layupv1.WriteTree(&strings.Builder{}, model, "layup://example/layers/layup/nodes/nope")
+		if err == nil {
+			t.Fatal("expected error, got nil")
+		}
+	})
+}
@@ -0,0 +1,424 @@
+package layupv1
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// Direction is the direction links are followed in when traversing a graph.
+type Direction int
+
+const (
+	// Outgoing follows links from the node they are coming from, to the
+	// node they are going to (downstream).
+	Outgoing Direction = iota
+	// Incoming follows links in reverse, from the node they are going to,
+	// back to the node they are coming from (upstream).
+	Incoming
+	// Both follows links in either direction.
+	Both
+)
+
+// String returns the name of the direction.
+func (d Direction) String() string {
+	switch d {
+	case Outgoing:
+		return "outgoing"
+	case Incoming:
+		return "incoming"
+	case Both:
+		return "both"
+	default:
+		return fmt.Sprintf("Direction(%d)", int(d))
+	}
+}
+
+// ParseDirection returns the direction with the given name.
+func ParseDirection(s string) (Direction, error) {
+	for _, d := range []Direction{Outgoing, Incoming, Both} {
+		if d.String() == s {
+			return d, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown direction %q, must be one of: outgoing, incoming, both", s)
+}
+
+// TraversalOption configures how a graph is traversed, and which nodes
+// and links are considered by the algorithms built on top of traversals.
+type TraversalOption func(*traversalConfig)
+
+type traversalConfig struct {
+	direction   Direction
+	maxDepth    int
+	layers      map[string]struct{}
+	linkFilters []func(*Edge) bool
+	weight      string
+	maxPaths    int
+}
+
+// WithDirection sets the direction links are followed in, which is
+// Outgoing by default.
+func WithDirection(d Direction) TraversalOption {
+	return func(c *traversalConfig) {
+		c.direction = d
+	}
+}
+
+// WithMaxDepth limits the number of links followed from the starting node.
+// A depth of zero only visits the starting node. By default, there is no
+// limit.
+func WithMaxDepth(depth int) TraversalOption {
+	return func(c *traversalConfig) {
+		c.maxDepth = depth
+	}
+}
+
+// WithLayers restricts a traversal to the nodes within the layers with the
+// given IDs. Nodes outside of the model are excluded too.
+func WithLayers(layerIDs ...string) TraversalOption {
+	return func(c *traversalConfig) {
+		if c.layers == nil {
+			c.layers = map[string]struct{}{}
+		}
+		for _, id := range layerIDs {
+			c.layers[id] = struct{}{}
+		}
+	}
+}
+
+// WithLinkFilter restricts a traversal to the links for which the given
+// predicate returns true. If given multiple times, links must match every
+// predicate.
+func WithLinkFilter(fn func(e *Edge) bool) TraversalOption {
+	return func(c *traversalConfig) {
+		c.linkFilters = append(c.linkFilters, fn)
+	}
+}
+
+// WithLinkIDs restricts a traversal to the links with any of the given IDs.
+func WithLinkIDs(ids ...string) TraversalOption {
+	set := map[string]struct{}{}
+	for _, id := range ids {
+		set[id] = struct{}{}
+	}
+
+	return WithLinkFilter(func(e *Edge) bool {
+		_, ok := set[e.Link.GetId()]
+		return ok
+	})
+}
+
+// WithLinkAttribute restricts a traversal to the links with the given
+// attribute set to the given value, which must be a value supported by
+// structpb.NewValue (e.g. a string, number or bool).
+func WithLinkAttribute(key string, value any) TraversalOption {
+	want, err := structpb.NewValue(value)
+
+	return WithLinkFilter(func(e *Edge) bool {
+		got, ok := e.Link.GetAttributes()[key]
+		return ok && err == nil && proto.Equal(got, want)
+	})
+}
+
+// WithWeight sets the link attribute containing the numeric weight (e.g.
+// latency or cost) of following a link, for weighted path finding. By
+// default, every link has a weight of one.
+func WithWeight(attribute string) TraversalOption {
+	return func(c *traversalConfig) {
+		c.weight = attribute
+	}
+}
+
+// WithMaxPaths limits the number of paths returned when finding many paths
+// between two nodes. By default, there is no limit.
+func WithMaxPaths(n int) TraversalOption {
+	return func(c *traversalConfig) {
+		c.maxPaths = n
+	}
+}
+
+func newTraversalConfig(opts []TraversalOption) *traversalConfig {
+	c := &traversalConfig{
+		direction: Outgoing,
+		maxDepth:  -1,
+		maxPaths:  -1,
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// allowsLink reports whether the given link can be followed.
+func (c *traversalConfig) allowsLink(e *Edge) bool {
+	for _, fn := range c.linkFilters {
+		if !fn(e) {
+			return false
+		}
+	}
+	return true
+}
+
+// linkWeight returns the weight of following the given link.
+func (c *traversalConfig) linkWeight(e *Edge) (float64, error) {
+	if c.weight == "" {
+		return 1, nil
+	}
+
+	v, ok := e.Link.GetAttributes()[c.weight]
+	if !ok {
+		return 0, fmt.Errorf("link %q has no %q attribute", e.URI, c.weight)
+	}
+
+	n, ok := v.GetKind().(*structpb.Value_NumberValue)
+	if !ok {
+		return 0, fmt.Errorf("link %q attribute %q is not a number", e.URI, c.weight)
+	}
+
+	if n.NumberValue < 0 || math.IsNaN(n.NumberValue) {
+		return 0, fmt.Errorf("link %q attribute %q must be a non-negative number", e.URI, c.weight)
+	}
+
+	return n.NumberValue, nil
+}
+
+// allowsNode reports whether the given node can be visited.
+func (c *traversalConfig) allowsNode(g *Graph, uri string) bool {
+	if c.layers == nil {
+		return true
+	}
+
+	layer, ok := g.NodeLayer(uri)
+	if !ok {
+		return false
+	}
+
+	_, ok = c.layers[layer.GetId()]
+
+	return ok
+}
+
+// allowedNodes returns the canonical URIs of the nodes within the model
+// which can be visited, in model order, and the index of each of them.
+func (g *Graph) allowedNodes(c *traversalConfig) ([]string, map[string]int) {
+	var nodes []string
+	for _, uri := range g.nodeOrder {
+		if c.allowsNode(g, uri) {
+			nodes = append(nodes, uri)
+		}
+	}
+
+	index := make(map[string]int, len(nodes))
+	for i, uri := range nodes {
+		index[uri] = i
+	}
+
+	return nodes, index
+}
+
+// step is a link which can be followed from a node during a traversal,
+// and the node it leads to.
+type step struct {
+	edge *Edge
+	node string
+}
+
+// steps returns the links that can be followed from the given node, in
+// model order, following the configured direction and filters.
+func (g *Graph) steps(uri string, c *traversalConfig) []step {
+	var steps []step
+
+	if c.direction == Outgoing || c.direction == Both {
+		for _, e := range g.OutLinks(uri) {
+			if c.allowsLink(e) && c.allowsNode(g, e.To) {
+				steps = append(steps, step{edge: e, node: e.To})
+			}
+		}
+	}
+
+	if c.direction == Incoming || c.direction == Both {
+		for _, e := range g.InLinks(uri) {
+			if c.allowsLink(e) && c.allowsNode(g, e.From) {
+				steps = append(steps, step{edge: e, node: e.From})
+			}
+		}
+	}
+
+	return steps
+}
+
+// Visit is a node reached during a traversal.
+type Visit struct {
+	// Node is the canonical URI of the node.
+	Node string
+	// Depth is the number of links followed from the starting node.
+	Depth int
+	// Via is the link followed to reach the node, which is nil for the
+	// starting node.
+	Via *Edge
+	// Parent is the canonical URI of the node the link was followed from,
+	// which is empty for the starting node.
+	Parent string
+	// External is true if the node is outside of the model.
+	External bool
+}
+
+// VisitFunc is called for each node reached during a traversal. It may
+// return SkipNode to avoid following the links of the node, StopTraversal
+// to end the traversal early, or any other error to abort the traversal
+// and return the error.
+type VisitFunc func(v Visit) error
+
+var (
+	// SkipNode is returned by a VisitFunc to prune the traversal, so the
+	// links of the visited node are not followed.
+	SkipNode = errors.New("skip node")
+
+	// StopTraversal is returned by a VisitFunc to end a traversal early,
+	// without an error being returned by the traversal itself.
+	StopTraversal = errors.New("stop traversal")
+)
+
+// checkStart returns an error if the given node can't be traversed from.
+func (g *Graph) checkStart(uri string) error {
+	if _, ok := g.Node(uri); ok {
+		return nil
+	}
+
+	if len(g.InLinks(uri)) > 0 {
+		return nil
+	}
+
+	return fmt.Errorf("node %q not found", uri)
+}
+
+// newVisit returns the visit of the given node.
+func (g *Graph) newVisit(uri string, depth int, via *Edge, parent string) Visit {
+	_, ok := g.Node(uri)
+
+	return Visit{
+		Node:     uri,
+		Depth:    depth,
+		Via:      via,
+		Parent:   parent,
+		External: !ok,
+	}
+}
+
+// BFS traverses the graph breadth-first from the node with the given
+// canonical URI, calling fn for each node reached (including the start)
+// exactly once, in order of increasing depth.
+func (g *Graph) BFS(start string, fn VisitFunc, opts ...TraversalOption) error {
+	if err := g.checkStart(start); err != nil {
+		return err
+	}
+
+	c := newTraversalConfig(opts)
+
+	var (
+		visited = map[string]struct{}{start: {}}
+		queue   = []Visit{g.newVisit(start, 0, nil, "")}
+	)
+
+	for len(queue) > 0 {
+		v := queue[0]
+		queue = queue[1:]
+
+		switch err := fn(v); {
+		case errors.Is(err, SkipNode):
+			continue
+		case errors.Is(err, StopTraversal):
+			return nil
+		case err != nil:
+			return err
+		}
+
+		if c.maxDepth >= 0 && v.Depth >= c.maxDepth {
+			continue
+		}
+
+		for _, s := range g.steps(v.Node, c) {
+			if _, ok := visited[s.node]; ok {
+				continue
+			}
+			visited[s.node] = struct{}{}
+
+			queue = append(queue, g.newVisit(s.node, v.Depth+1, s.edge, v.Node))
+		}
+	}
+
+	return nil
+}
+
+// DFS traverses the graph depth-first from the node with the given
+// canonical URI, calling fn for each node reached (including the start)
+// exactly once, before any of the nodes reached through it.
+func (g *Graph) DFS(start string, fn VisitFunc, opts ...TraversalOption) error {
+	if err := g.checkStart(start); err != nil {
+		return err
+	}
+
+	c := newTraversalConfig(opts)
+
+	visited := map[string]struct{}{}
+
+	var walk func(v Visit) error
+	walk = func(v Visit) error {
+		visited[v.Node] = struct{}{}
+
+		switch err := fn(v); {
+		case errors.Is(err, SkipNode):
+			return nil
+		case err != nil:
+			return err
+		}
+
+		if c.maxDepth >= 0 && v.Depth >= c.maxDepth {
+			return nil
+		}
+
+		for _, s := range g.steps(v.Node, c) {
+			if _, ok := visited[s.node]; ok {
+				continue
+			}
+
+			if err := walk(g.newVisit(s.node, v.Depth+1, s.edge, v.Node)); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	err := walk(g.newVisit(start, 0, nil, ""))
+	if errors.Is(err, StopTraversal) {
+		return nil
+	}
+
+	return err
+}
+
+// Reachable returns the canonical URIs of the nodes reachable from the
+// node with the given canonical URI, in breadth-first order, not including
+// the starting node itself.
+func (g *Graph) Reachable(start string, opts ...TraversalOption) ([]string, error) {
+	var nodes []string
+
+	err := g.BFS(start, func(v Visit) error {
+		if v.Depth > 0 {
+			nodes = append(nodes, v.Node)
+		}
+		return nil
+	}, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return nodes, nil
+}
@@ -0,0 +1,222 @@
+package layupv1_test
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	layupv1 "github.com/picatz/layup/pkg/layup/v1"
+)
+
+func newTestGraph(t *testing.T, hcl string) *layupv1.Graph {
+	t.Helper()
+
+	m, err := layupv1.ParseHCL(strings.NewReader(hcl))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	g, err := layupv1.NewGraph(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return g
+}
+
+func TestGraph_BFS(t *testing.T) {
+	g := newTestGraph(t, thisProject)
+
+	var (
+		visited []string
+		depths  []int
+	)
+
+	err := g.BFS("layup://example/layers/layup/nodes/schema", func(v layupv1.Visit) error {
+		visited = append(visited, v.Node)
+		depths = append(depths, v.Depth)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"layup://example/layers/layup/nodes/schema",
+		"layup://example/layers/buf/nodes/cli",
+		"layup://example/layers/github/nodes/this_repository",
+		"layup://example/layers/github/nodes/buf_organization",
+		"layup://example/layers/go/nodes/runtime",
+	}
+
+	if !slices.Equal(visited, want) {
+		t.Fatalf("unexpected visits: %v", visited)
+	}
+
+	if !slices.Equal(depths, []int{0, 1, 1, 2, 2}) {
+		t.Fatalf("unexpected depths: %v", depths)
+	}
+
+	t.Run("max depth", func(t *testing.T) {
+		nodes, err := g.Reachable("layup://example/layers/layup/nodes/schema", layupv1.WithMaxDepth(1))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(nodes) != 2 {
+			t.Fatalf("unexpected nodes: %v", nodes)
+		}
+	})
+
+	t.Run("incoming", func(t *testing.T) {
+		nodes, err := g.Reachable("layup://example/layers/go/nodes/runtime", layupv1.WithDirection(layupv1.Incoming))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want := []string{
+			"layup://example/layers/go/nodes/language",
+			"layup://example/layers/buf/nodes/cli",
+			"layup://example/layers/layup/nodes/cli",
+			"layup://example/layers/go/nodes/owner",
+			"layup://example/layers/layup/nodes/schema",
+			"layup://example/layers/layup/nodes/hcl",
+		}
+
+		if !slices.Equal(nodes, want) {
+			t.Fatalf("unexpected nodes: %v", nodes)
+		}
+	})
+
+	t.Run("layers", func(t *testing.T) {
+		nodes, err := g.Reachable("layup://example/layers/go/nodes/runtime",
+			layupv1.WithDirection(layupv1.Both),
+			layupv1.WithLayers("go"),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !slices.Equal(nodes, []string{
+			"layup://example/layers/go/nodes/language",
+			"layup://example/layers/go/nodes/owner",
+		}) {
+			t.Fatalf("unexpected nodes: %v", nodes)
+		}
+	})
+
+	t.Run("link IDs", func(t *testing.T) {
+		nodes, err := g.Reachable("layup://example/layers/go/nodes/runtime",
+			layupv1.WithDirection(layupv1.Incoming),
+			layupv1.WithLinkIDs("uses"),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(nodes) != 2 {
+			t.Fatalf("unexpected nodes: %v", nodes)
+		}
+	})
+
+	t.Run("skip and stop", func(t *testing.T) {
+		var visited []string
+
+		err := g.BFS("layup://example/layers/layup/nodes/schema", func(v layupv1.Visit) error {
+			visited = append(visited, v.Node)
+
+			switch v.Node {
+			case "layup://example/layers/buf/nodes/cli":
+				return layupv1.SkipNode
+			case "layup://example/layers/github/nodes/this_repository":
+				return layupv1.StopTraversal
+			}
+
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(visited) != 3 {
+			t.Fatalf("unexpected visits: %v", visited)
+		}
+	})
+
+	t.Run("unknown node", func(t *testing.T) {
+		err := g.BFS("layup://example/layers/layup/nodes/nope", func(v layupv1.Visit) error { return nil })
+		if err == nil {
+			t.Fatal("expected error, got nil")
+		}
+	})
+}
+
+func TestGraph_DFS(t *testing.T) {
+	g := newTestGraph(t, `
+uri = "layup://test"
+
+layer "1" {
+	node "a" {}
+	node "b" {}
+	node "c" {}
+	node "d" {}
+
+	link "ab" {
+		from = node.a
+		to = node.b
+	}
+
+	link "bc" {
+		from = node.b
+		to = node.c
+	}
+
+	link "ca" {
+		from = node.c
+		to = node.a
+	}
+
+	link "ad" {
+		from = node.a
+		to = node.d
+	}
+
+	link "outside" {
+		from = node.d
+		to = "github://picatz/layup"
+	}
+}
+`)
+
+	var visited []string
+
+	err := g.DFS("layup://test/layers/1/nodes/a", func(v layupv1.Visit) error {
+		visited = append(visited, strings.TrimPrefix(v.Node, "layup://test/layers/1/nodes/"))
+		if v.External != (v.Node == "github://picatz/layup") {
+			t.Fatalf("unexpected external flag for %q", v.Node)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !slices.Equal(visited, []string{"a", "b", "c", "d", "github://picatz/layup"}) {
+		t.Fatalf("unexpected visits: %v", visited)
+	}
+
+	t.Run("error", func(t *testing.T) {
+		want := errors.New("boom")
+
+		err := g.DFS("layup://test/layers/1/nodes/a", func(v layupv1.Visit) error {
+			if v.Depth == 2 {
+				return want
+			}
+			return nil
+		})
+		if !errors.Is(err, want) {
+			t.Fatalf("expected %v, got %v", want, err)
+		}
+	})
+}
@@ -0,0 +1,205 @@
+package layupv1
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// URI is a canonical Layup URI, which identifies a model, or a layer, node
+// or link within a model, rooted at the model's URI:
+//
+//	<model>
+//	<model>/layers/<layer>
+//	<model>/layers/<layer>/nodes/<node>
+//	<model>/layers/<layer>/links/<link>
+//
+// URIs which don't identify anything within a model, like the URI of
+// another system a link is going to, are parsed as a model URI.
+//
+// URI values are comparable, so they can be used as map keys.
+type URI struct {
+	Model string
+	Layer string
+	Node  string
+	Link  string
+}
+
+// NewModelURI returns the URI of the model with the given URI.
+func NewModelURI(model string) URI {
+	return URI{Model: model}
+}
+
+// NewLayerURI returns the URI of the given layer within the given model.
+func NewLayerURI(model, layer string) URI {
+	return URI{Model: model, Layer: layer}
+}
+
+// NewNodeURI returns the URI of the given node within the given layer.
+func NewNodeURI(model, layer, node string) URI {
+	return URI{Model: model, Layer: layer, Node: node}
+}
+
+// NewLinkURI returns the URI of the given link within the given layer.
+func NewLinkURI(model, layer, link string) URI {
+	return URI{Model: model, Layer: layer, Link: link}
+}
+
+// ParseURI parses the given absolute URI (following the rules of RFC 3986)
+// into its model, layer, node and link components.
+func ParseURI(s string) (URI, error) {
+	u, err := url.Parse(s)
+	if err != nil {
+		return URI{}, err
+	}
+
+	if !u.IsAbs() {
+		return URI{}, fmt.Errorf("invalid URI %q: must be absolute", s)
+	}
+
+	segments := strings.Split(u.EscapedPath(), "/")
+	n := len(segments)
+
+	unescape := func(segment string) (string, error) {
+		v, err := url.PathUnescape(segment)
+		if err != nil {
+			return "", fmt.Errorf("invalid URI %q: %w", s, err)
+		}
+		if v == "" {
+			return "", fmt.Errorf("invalid URI %q: empty path segment", s)
+		}
+		return v, nil
+	}
+
+	var (
+		uri  URI
+		rest int
+	)
+
+	switch {
+	case n >= 5 && segments[n-4] == "layers" && (segments[n-2] == "nodes" || segments[n-2] == "links"):
+		if uri.Layer, err = unescape(segments[n-3]); err != nil {
+			return URI{}, err
+		}
+
+		id, err := unescape(segments[n-1])
+		if err != nil {
+			return URI{}, err
+		}
+
+		if segments[n-2] == "nodes" {
+			uri.Node = id
+		} else {
+			uri.Link = id
+		}
+
+		rest = n - 4
+	case n >= 3 && segments[n-2] == "layers":
+		if uri.Layer, err = unescape(segments[n-1]); err != nil {
+			return URI{}, err
+		}
+
+		rest = n - 2
+	default:
+		uri.Model = s
+		return uri, nil
+	}
+
+	if u.RawQuery != "" || u.Fragment != "" {
+		return URI{}, fmt.Errorf("invalid URI %q: layers, nodes and links can't have a query or fragment", s)
+	}
+
+	// The model is everything before the layer's path segments.
+	u.RawPath = ""
+	u.Path, _ = url.PathUnescape(strings.Join(segments[:rest], "/"))
+	uri.Model = u.String()
+
+	return uri, nil
+}
+
+// String returns the URI as a string.
+func (u URI) String() string {
+	var sb strings.Builder
+
+	sb.WriteString(u.Model)
+
+	if u.Layer == "" {
+		return sb.String()
+	}
+
+	sb.WriteString("/layers/" + url.PathEscape(u.Layer))
+
+	switch {
+	case u.Node != "":
+		sb.WriteString("/nodes/" + url.PathEscape(u.Node))
+	case u.Link != "":
+		sb.WriteString("/links/" + url.PathEscape(u.Link))
+	}
+
+	return sb.String()
+}
+
+// IsModel reports whether the URI identifies a model (or something outside
+// of a model).
+func (u URI) IsModel() bool {
+	return u.Layer == ""
+}
+
+// IsLayer reports whether the URI identifies a layer.
+func (u URI) IsLayer() bool {
+	return u.Layer != "" && u.Node == "" && u.Link == ""
+}
+
+// IsNode reports whether the URI identifies a node.
+func (u URI) IsNode() bool {
+	return u.Layer != "" && u.Node != ""
+}
+
+// IsLink reports whether the URI identifies a link.
+func (u URI) IsLink() bool {
+	return u.Layer != "" && u.Link != ""
+}
+
+// ModelURI returns the URI of the model containing the URI.
+func (u URI) ModelURI() URI {
+	return NewModelURI(u.Model)
+}
+
+// LayerURI returns the URI of the layer containing the URI, which is the
+// URI itself for layers, or the zero URI for models.
+func (u URI) LayerURI() URI {
+	if u.Layer == "" {
+		return URI{}
+	}
+	return NewLayerURI(u.Model, u.Layer)
+}
+
+// Resolve resolves the given URI reference relative to the URI, following
+// the rules of RFC 3986 section 5. For example, relative to the node URI
+// "layup://example/layers/go/nodes/runtime":
+//
+//	"language"             -> "layup://example/layers/go/nodes/language"
+//	"../../github/nodes/a" -> "layup://example/layers/github/nodes/a"
+//	"github://picatz"      -> "github://picatz"
+//
+// This is how a link's "to" is resolved relative to the node it's from,
+// which allows local node IDs to be used within a layer.
+func (u URI) Resolve(ref string) (URI, error) {
+	return resolveReference(u.String(), ref)
+}
+
+// resolveReference resolves the given URI reference relative to the given
+// base URI, following the rules of RFC 3986 section 5.
+func resolveReference(base, ref string) (URI, error) {
+	b, err := url.Parse(base)
+	if err != nil {
+		return URI{}, err
+	}
+
+	r, err := url.Parse(ref)
+	if err != nil {
+		return URI{}, err
+	}
+
+	return ParseURI(b.ResolveReference(r).String())
+}
@@ -0,0 +1,117 @@
+package layupv1_test
+
+import (
+	"strings"
+	"testing"
+
+	layupv1 "github.com/picatz/layup/pkg/layup/v1"
+)
+
+func TestParseURI(t *testing.T) {
+	tests := []struct {
+		uri  string
+		want layupv1.URI
+	}{
+		{
+			uri:  "layup://example",
+			want: layupv1.NewModelURI("layup://example"),
+		},
+		{
+			uri:  "layup://cake/very_simple/layers/tools",
+			want: layupv1.NewLayerURI("layup://cake/very_simple", "tools"),
+		},
+		{
+			uri:  "layup://example/layers/go/nodes/runtime",
+			want: layupv1.NewNodeURI("layup://example", "go", "runtime"),
+		},
+		{
+			uri:  "layup://example/layers/go/links/implementation",
+			want: layupv1.NewLinkURI("layup://example", "go", "implementation"),
+		},
+		{
+			uri:  "github://picatz/layup",
+			want: layupv1.NewModelURI("github://picatz/layup"),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.uri, func(t *testing.T) {
+			got, err := layupv1.ParseURI(test.uri)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got != test.want {
+				t.Fatalf("got %#v, want %#v", got, test.want)
+			}
+
+			if got.String() != test.uri {
+				t.Fatalf("got %q, want %q", got.String(), test.uri)
+			}
+		})
+	}
+
+	for _, uri := range []string{
+		"",
+		"node",
+		"layup://example/layers//nodes/a",
+		"layup://example/layers/go/nodes/runtime?x=1",
+	} {
+		t.Run("invalid "+uri, func(t *testing.T) {
+			if _, err := layupv1.ParseURI(uri); err == nil {
+				t.Fatal("expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestURI(t *testing.T) {
+	u := layupv1.NewNodeURI("layup://example", "go", "runtime")
+
+	if !u.IsNode() || u.IsLink() || u.IsLayer() || u.IsModel() {
+		t.Fatalf("unexpected kind for %v", u)
+	}
+
+	if got := u.LayerURI().String(); got != "layup://example/layers/go" {
+		t.Fatalf("unexpected layer URI: %q", got)
+	}
+
+	if got := u.ModelURI().String(); got != "layup://example" {
+		t.Fatalf("unexpected model URI: %q", got)
+	}
+
+	for ref, want := range map[string]string{
+		"language":                "layup://example/layers/go/nodes/language",
+		"./language":              "layup://example/layers/go/nodes/language",
+		"../../github/nodes/a":    "layup://example/layers/github/nodes/a",
+		"../links/implementation": "layup://example/layers/go/links/implementation",
+		"/layers/buf":             "layup://example/layers/buf",
+		"github://picatz/layup":   "github://picatz/layup",
+		"layup://other/layers/x":  "layup://other/layers/x",
+	} {
+		got, err := u.Resolve(ref)
+		if err != nil {
+			t.Fatalf("failed to resolve %q: %v", ref, err)
+		}
+
+		if got.String() != want {
+			t.Fatalf("resolving %q: got %q, want %q", ref, got.String(), want)
+		}
+	}
+}
+
+func TestParseHCL_uri(t *testing.T) {
+	m, err := layupv1.ParseHCL(strings.NewReader(thisProject))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	to, err := layupv1.ParseURI(m.GetLayers()[2].GetLinks()[1].GetTo())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if to != layupv1.NewNodeURI("layup://example", "go", "runtime") {
+		t.Fatalf("unexpected link URI: %v", to)
+	}
+}